@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/venkytv/calendar-notifier/pkg/calendar/google"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/msgraph"
+	"github.com/venkytv/calendar-notifier/pkg/config"
+)
+
+const authUsage = `Usage:
+  calendar-notifier [-config file] auth google [--calendar name] [--device]
+  calendar-notifier [-config file] auth msgraph [--calendar name]
+  calendar-notifier [-config file] auth status
+
+  auth google    authorize access to a Google calendar and save its token
+  auth msgraph   sign in to a Microsoft Graph calendar with a device code and save its token
+  auth status    show the authentication state of every Google and Microsoft Graph calendar
+`
+
+// runAuth runs the auth subcommand and returns the exit code
+func runAuth(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, authUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		return 1
+	}
+
+	// Only problems are worth logging while talking to the user
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	switch args[0] {
+	case "google":
+		return runAuthGoogle(cfg, args[1:], logger)
+	case "msgraph":
+		return runAuthMicrosoft(cfg, args[1:], logger)
+	case "status":
+		return runAuthStatus(cfg, os.Stdout, logger)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown auth command %q\n\n%s", args[0], authUsage)
+		return 2
+	}
+}
+
+// runAuthGoogle authorizes one Google calendar, with the browser code flow or
+// the device flow, and saves its token
+func runAuthGoogle(cfg *config.Config, args []string, logger *slog.Logger) int {
+	flags := flag.NewFlagSet("auth google", flag.ContinueOnError)
+	name := flags.String("calendar", "", "Name of the Google calendar in the config (optional if there is only one)")
+	device := flags.Bool("device", false, "Use the device flow: enter a code on another device instead of pasting one back")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	calendarCfg, err := findCalendar(cfg, "google", "Google", *name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serviceAccount, err := google.IsServiceAccountKey(calendarCfg.CredentialsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if serviceAccount {
+		// Nothing to authorize, but the key and delegation can still be checked
+		if _, err := google.NewServiceAccountClient(ctx, calendarCfg.CredentialsFile, calendarCfg.Impersonate); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", calendarCfg.Name, err)
+			return 1
+		}
+		fmt.Printf("%s uses a service account key and needs no authorization; a token was obtained successfully.\n", calendarCfg.Name)
+		return 0
+	}
+
+	tokenFile := googleTokenFile(calendarCfg)
+	tm, err := google.NewTokenManager(calendarCfg.CredentialsFile, tokenFile, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *device {
+		_, err = tm.AuthorizeDevice(ctx, func(auth *oauth2.DeviceAuthResponse) {
+			fmt.Printf("On any device, open %s and enter the code:\n\n    %s\n\n", auth.VerificationURI, auth.UserCode)
+			if !auth.Expiry.IsZero() {
+				fmt.Printf("The code expires at %s. Waiting for authorization...\n", auth.Expiry.Local().Format(time.Kitchen))
+			}
+		})
+	} else {
+		fmt.Printf("Open this URL in a browser and grant read-only calendar access:\n\n%s\n\n", tm.GetAuthURL())
+		fmt.Print("Paste the authorization code, or the whole URL you were redirected to: ")
+
+		var code string
+		code, err = bufio.NewReader(os.Stdin).ReadString('\n')
+		if err == nil || (err == io.EOF && code != "") {
+			_, err = tm.ExchangeCode(ctx, code)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nAuthorization failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\nToken for %s saved to %s (mode 0600).\n", calendarCfg.Name, tokenFile)
+	if err := printTokenInfo(os.Stdout, tm); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runAuthMicrosoft signs in to one Microsoft Graph calendar with the device
+// code flow and saves its token
+func runAuthMicrosoft(cfg *config.Config, args []string, logger *slog.Logger) int {
+	flags := flag.NewFlagSet("auth msgraph", flag.ContinueOnError)
+	name := flags.String("calendar", "", "Name of the Microsoft Graph calendar in the config (optional if there is only one)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	calendarCfg, err := findCalendar(cfg, "msgraph", "Microsoft Graph", *name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	creds, err := msgraph.LoadCredentials(calendarCfg.CredentialsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if creds.UsesClientCredentials() {
+		// Nothing to sign in to, but the secret can still be checked
+		if _, err := msgraph.NewClientCredentialsClient(ctx, creds); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", calendarCfg.Name, err)
+			return 1
+		}
+		fmt.Printf("%s uses a client secret and needs no sign-in; a token was obtained successfully.\n", calendarCfg.Name)
+		return 0
+	}
+
+	tokenFile := graphTokenFile(calendarCfg)
+	tm := msgraph.NewTokenManager(creds, tokenFile, logger)
+
+	_, err = tm.AuthorizeDevice(ctx, func(auth *oauth2.DeviceAuthResponse) {
+		fmt.Printf("On any device, open %s and enter the code:\n\n    %s\n\n", auth.VerificationURI, auth.UserCode)
+		if !auth.Expiry.IsZero() {
+			fmt.Printf("The code expires at %s. Waiting for sign-in...\n", auth.Expiry.Local().Format(time.Kitchen))
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nSign-in failed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("\nToken for %s saved to %s (mode 0600).\n", calendarCfg.Name, tokenFile)
+	return 0
+}
+
+// runAuthStatus reports the authentication state of every Google and
+// Microsoft Graph calendar. It returns 1 if any of them needs authorizing.
+func runAuthStatus(cfg *config.Config, w io.Writer, logger *slog.Logger) int {
+	status := 0
+	found := false
+
+	for _, calendarCfg := range cfg.Calendars {
+		var ok bool
+		switch calendarCfg.Type {
+		case "google":
+			fmt.Fprintf(w, "%s:\n", calendarCfg.Name)
+			ok = printGoogleStatus(w, calendarCfg, logger)
+		case "msgraph":
+			fmt.Fprintf(w, "%s:\n", calendarCfg.Name)
+			ok = printGraphStatus(w, calendarCfg, logger)
+		default:
+			continue
+		}
+		found = true
+		if !ok {
+			status = 1
+		}
+	}
+
+	if !found {
+		fmt.Fprintln(w, "No Google or Microsoft Graph calendars are configured.")
+	}
+	return status
+}
+
+// printGoogleStatus describes how a Google calendar authenticates and returns
+// false if it needs authorizing
+func printGoogleStatus(w io.Writer, calendarCfg config.CalendarConfig, logger *slog.Logger) bool {
+	serviceAccount, err := google.IsServiceAccountKey(calendarCfg.CredentialsFile)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+	if serviceAccount {
+		fmt.Fprintf(w, "  service account key: %s\n", calendarCfg.CredentialsFile)
+		if calendarCfg.Impersonate != "" {
+			fmt.Fprintf(w, "  impersonating: %s\n", calendarCfg.Impersonate)
+		}
+		return true
+	}
+
+	tokenFile := googleTokenFile(calendarCfg)
+	fmt.Fprintf(w, "  token file: %s\n", tokenFile)
+
+	tm, err := google.NewTokenManager(calendarCfg.CredentialsFile, tokenFile, logger)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+	if !tm.IsTokenValid() {
+		fmt.Fprintf(w, "  not authorized: run 'calendar-notifier auth google --calendar %s'\n", calendarCfg.Name)
+		return false
+	}
+	if err := printTokenInfo(w, tm); err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// printGraphStatus describes how a Microsoft Graph calendar authenticates and
+// returns false if it needs a sign-in
+func printGraphStatus(w io.Writer, calendarCfg config.CalendarConfig, logger *slog.Logger) bool {
+	creds, err := msgraph.LoadCredentials(calendarCfg.CredentialsFile)
+	if err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+	if creds.UsesClientCredentials() {
+		fmt.Fprintf(w, "  client secret: application %s in tenant %s\n", creds.ClientID, creds.TenantID)
+		if calendarCfg.Impersonate != "" {
+			fmt.Fprintf(w, "  reading mailbox: %s\n", calendarCfg.Impersonate)
+		}
+		return true
+	}
+
+	tokenFile := graphTokenFile(calendarCfg)
+	fmt.Fprintf(w, "  token file: %s\n", tokenFile)
+
+	tm := msgraph.NewTokenManager(creds, tokenFile, logger)
+	if !tm.IsTokenValid() {
+		fmt.Fprintf(w, "  not signed in: run 'calendar-notifier auth msgraph --calendar %s'\n", calendarCfg.Name)
+		return false
+	}
+	token, err := tm.LoadToken()
+	if err != nil {
+		fmt.Fprintf(w, "  error: %v\n", err)
+		return false
+	}
+
+	if token.Expiry.After(time.Now()) {
+		fmt.Fprintf(w, "  access token: valid until %s\n", token.Expiry.Local().Format(time.RFC1123))
+	} else {
+		fmt.Fprintf(w, "  access token: expired at %s\n", token.Expiry.Local().Format(time.RFC1123))
+	}
+	if token.RefreshToken != "" {
+		fmt.Fprintln(w, "  refresh token: yes (access is renewed automatically)")
+	} else {
+		fmt.Fprintln(w, "  refresh token: no (sign in again once the access token expires)")
+	}
+	return true
+}
+
+// printTokenInfo describes the stored token of a token manager
+func printTokenInfo(w io.Writer, tm *google.TokenManager) error {
+	info, err := tm.GetTokenInfo()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Expiry.IsZero():
+		fmt.Fprintln(w, "  access token: does not expire")
+	case info.Expiry.After(time.Now()):
+		fmt.Fprintf(w, "  access token: valid until %s\n", info.Expiry.Local().Format(time.RFC1123))
+	default:
+		fmt.Fprintf(w, "  access token: expired at %s\n", info.Expiry.Local().Format(time.RFC1123))
+	}
+
+	if info.HasRefreshToken {
+		fmt.Fprintln(w, "  refresh token: yes (access is renewed automatically)")
+	} else {
+		fmt.Fprintln(w, "  refresh token: no (authorize again once the access token expires)")
+	}
+
+	if len(info.Scopes) > 0 {
+		fmt.Fprintf(w, "  scopes: %s\n", strings.Join(info.Scopes, " "))
+	} else {
+		fmt.Fprintln(w, "  scopes: unknown (token saved before scopes were recorded)")
+	}
+	return nil
+}
+
+// findCalendar returns the calendar of the given type with the given name, or
+// the only one if name is empty. label names the type in errors.
+func findCalendar(cfg *config.Config, calendarType, label, name string) (config.CalendarConfig, error) {
+	var matches []config.CalendarConfig
+	for _, calendarCfg := range cfg.Calendars {
+		if calendarCfg.Type == calendarType && (name == "" || calendarCfg.Name == name) {
+			matches = append(matches, calendarCfg)
+		}
+	}
+
+	switch {
+	case len(matches) == 1:
+		return matches[0], nil
+	case name != "":
+		return config.CalendarConfig{}, fmt.Errorf("no %s calendar named %q in the config", label, name)
+	case len(matches) == 0:
+		return config.CalendarConfig{}, fmt.Errorf("no %s calendars in the config", label)
+	default:
+		return config.CalendarConfig{}, fmt.Errorf("several %s calendars are configured; choose one with --calendar", label)
+	}
+}
+
+// googleTokenFile returns where the token of a Google calendar is stored
+func googleTokenFile(calendarCfg config.CalendarConfig) string {
+	if calendarCfg.TokenFile != "" {
+		return calendarCfg.TokenFile
+	}
+	return google.DefaultTokenFile(calendarCfg.CredentialsFile)
+}
+
+// graphTokenFile returns where the token of a Microsoft Graph calendar is stored
+func graphTokenFile(calendarCfg config.CalendarConfig) string {
+	if calendarCfg.TokenFile != "" {
+		return calendarCfg.TokenFile
+	}
+	return msgraph.DefaultTokenFile(calendarCfg.CredentialsFile)
+}
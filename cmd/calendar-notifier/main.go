@@ -5,15 +5,21 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/venkytv/calendar-notifier/internal/models"
 	"github.com/venkytv/calendar-notifier/pkg/calendar"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/caldav"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/file"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/google"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/ical"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/msgraph"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/providers"
 	"github.com/venkytv/calendar-notifier/pkg/config"
 	"github.com/venkytv/calendar-notifier/pkg/nats"
@@ -47,6 +53,11 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Subcommands follow the flags
+	if flag.Arg(0) == "auth" {
+		os.Exit(runAuth(flag.Args()[1:]))
+	}
+
 	// Initialize application
 	app, err := NewApp(*configPath, *debug, *dryRun)
 	if err != nil {
@@ -123,6 +134,12 @@ type App struct {
 	natsPublisher    *nats.Publisher
 	eventScheduler   *scheduler.EventScheduler
 	dryRun          bool
+
+	// Google Calendar push notifications, if configured
+	pushReceiver    *google.PushReceiver
+	googleProviders map[string]*google.Provider // By calendar name
+	pushMu          sync.Mutex
+	pushCalendars   map[string][]string // Calendar names by watched Google calendar ID
 }
 
 // NewApp creates a new application instance
@@ -146,6 +163,7 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 	factory := calendar.NewDefaultProviderFactory()
 	providers.InitializeBuiltinProviders(factory)
 	calendarManager := calendar.NewManagerWithCoordinator(factory, nil, logger)
+	googleProviders := make(map[string]*google.Provider)
 
 	// Configure calendar providers
 	for _, calendarCfg := range cfg.Calendars {
@@ -169,8 +187,12 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 
 			caldavConfig := &caldav.Config{
 				URL:      calendarCfg.URL,
-				Username: calendarCfg.Username,
-				Password: calendarCfg.Password,
+				TimeZone: calendarCfg.TimeZone,
+				Auth:     authConfig(calendarCfg),
+
+				ExpandRecurrences: calendarCfg.ExpandRecurrences,
+				Sync:              calendarCfg.Sync,
+				CacheFile:         calendarCfg.CacheFile,
 			}
 
 			if err := caldavProvider.InitializeWithConfig(caldavConfig); err != nil {
@@ -178,11 +200,52 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 			}
 
 		case "ical":
-			// iCal providers just need the URL
-			if err := provider.Initialize(ctx, calendarCfg.URL); err != nil {
+			// iCal providers need the URL and optionally a default time zone
+			icalProvider, ok := provider.(*ical.Provider)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast to iCal provider")
+			}
+
+			if calendarCfg.TimeZone != "" {
+				if err := icalProvider.SetTimeZone(calendarCfg.TimeZone); err != nil {
+					return nil, fmt.Errorf("failed to configure %s iCal provider: %w", calendarCfg.Name, err)
+				}
+			}
+
+			// Private feeds authenticate like CalDAV servers
+			if auth := authConfig(calendarCfg); !auth.IsZero() {
+				client, err := caldav.NewHTTPClient(auth, 30*time.Second)
+				if err != nil {
+					return nil, fmt.Errorf("failed to configure %s iCal authentication: %w", calendarCfg.Name, err)
+				}
+				icalProvider.SetHTTPClient(client)
+			}
+
+			if err := icalProvider.Initialize(ctx, calendarCfg.URL); err != nil {
 				return nil, fmt.Errorf("failed to initialize %s iCal provider: %w", calendarCfg.Name, err)
 			}
 
+		case "file":
+			// File providers read a local .ics file or directory
+			fileProvider, ok := provider.(*file.Provider)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast to file provider")
+			}
+
+			if calendarCfg.TimeZone != "" {
+				if err := fileProvider.SetTimeZone(calendarCfg.TimeZone); err != nil {
+					return nil, fmt.Errorf("failed to configure %s file provider: %w", calendarCfg.Name, err)
+				}
+			}
+
+			// Edits are picked up as soon as the watcher reports them
+			name := calendarCfg.Name
+			fileProvider.SetOnChange(func() { calendarManager.RefreshProvider(name) })
+
+			if err := fileProvider.Initialize(ctx, calendarCfg.Path); err != nil {
+				return nil, fmt.Errorf("failed to initialize %s file provider: %w", calendarCfg.Name, err)
+			}
+
 		case "google":
 			// Google Calendar providers need OAuth2 credentials
 			googleProvider, ok := provider.(*google.Provider)
@@ -195,19 +258,66 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 				googleProvider.SetTokenFile(calendarCfg.TokenFile)
 			}
 
+			// Service accounts may read a Workspace user's calendars
+			googleProvider.SetImpersonate(calendarCfg.Impersonate)
+
+			// All-day dates without a zone are interpreted in this zone
+			if calendarCfg.TimeZone != "" {
+				if err := googleProvider.SetTimeZone(calendarCfg.TimeZone); err != nil {
+					return nil, fmt.Errorf("failed to configure %s Google Calendar provider: %w", calendarCfg.Name, err)
+				}
+			}
+
 			// Initialize with credentials file
 			if err := googleProvider.Initialize(ctx, calendarCfg.CredentialsFile); err != nil {
-				return nil, fmt.Errorf("failed to initialize %s Google Calendar provider: %w\n\nFor initial setup, you may need to authenticate. See the error message above for instructions.", calendarCfg.Name, err)
+				return nil, fmt.Errorf("failed to initialize %s Google Calendar provider: %w\n\nFor initial setup, authorize access with: calendar-notifier -config %s auth google --calendar %q", calendarCfg.Name, err, configPath, calendarCfg.Name)
 			}
 
 			// Store calendar IDs for this provider (Google uses explicit calendar IDs)
 			googleProvider.SetCalendarIDs(calendarCfg.CalendarIDs)
+			googleProviders[calendarCfg.Name] = googleProvider
+
+		case "msgraph":
+			// Microsoft Graph providers need an app registration
+			graphProvider, ok := provider.(*msgraph.Provider)
+			if !ok {
+				return nil, fmt.Errorf("failed to cast to Microsoft Graph provider")
+			}
+
+			if calendarCfg.TokenFile != "" {
+				graphProvider.SetTokenFile(calendarCfg.TokenFile)
+			}
+
+			// Apps with a client secret read a given user's mailbox
+			graphProvider.SetUser(calendarCfg.Impersonate)
+
+			if calendarCfg.TimeZone != "" {
+				if err := graphProvider.SetTimeZone(calendarCfg.TimeZone); err != nil {
+					return nil, fmt.Errorf("failed to configure %s Microsoft Graph provider: %w", calendarCfg.Name, err)
+				}
+			}
+
+			if err := graphProvider.Initialize(ctx, calendarCfg.CredentialsFile); err != nil {
+				return nil, fmt.Errorf("failed to initialize %s Microsoft Graph provider: %w\n\nFor initial setup, sign in with: calendar-notifier -config %s auth msgraph --calendar %q", calendarCfg.Name, err, configPath, calendarCfg.Name)
+			}
+
+			// Without calendar IDs the default calendar is read
+			graphProvider.SetCalendarIDs(calendarCfg.CalendarIDs)
 
 		default:
 			return nil, fmt.Errorf("unsupported provider type: %s", calendarCfg.Type)
 		}
 
 		calendarManager.AddProvider(calendarCfg.Name, provider)
+		calendarManager.SetEventFilter(calendarCfg.Name, calendar.EventFilter{
+			NotifyCancelled:     calendarCfg.EventFilter.NotifyCancelled,
+			SkipTransparent:     calendarCfg.EventFilter.SkipTransparent,
+			SkipFocusTime:       calendarCfg.EventFilter.SkipFocusTime,
+			SkipWorkingLocation: calendarCfg.EventFilter.SkipWorkingLocation,
+		})
+		calendarManager.SetMaxStaleness(calendarCfg.Name, calendarCfg.MaxStaleness)
+		calendarManager.SetFetchTimeout(calendarCfg.Name, calendarCfg.FetchTimeout)
+		calendarManager.SetPollInterval(calendarCfg.Name, calendarCfg.PollInterval)
 
 		logger.Info("Configured calendar provider",
 			"name", calendarCfg.Name,
@@ -232,14 +342,23 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 		logger.Info("Running in dry-run mode - notifications will not be published")
 	}
 
+	// All-day reminder time was validated when the config was loaded
+	allDayTime, _ := cfg.Defaults.AllDay.TimeOfDay()
+
 	// Create scheduler configuration from app config
 	schedulerConfig := &scheduler.Config{
-		PollInterval:         5 * time.Minute, // Default - could be configurable
+		PollInterval:         5 * time.Minute, // Re-plan from the cache; calendars refresh on their own poll_interval
 		LookaheadWindow:      24 * time.Hour,
 		DefaultLeadTimes:     cfg.Defaults.NotificationIntervals,
 		FinalReminderMinutes: cfg.Defaults.FinalReminderMinutes,
+		NotifyCancellations:  cfg.Defaults.NotifyCancellations,
+		MissedReminderWindow: cfg.Defaults.MissedReminderWindow,
 		MaxConcurrentEvents:  1000,
 		TimerBufferSize:      100,
+		AllDay: scheduler.AllDayPolicy{
+			Mode:      scheduler.AllDayMode(cfg.Defaults.AllDay.Notify),
+			TimeOfDay: allDayTime,
+		},
 	}
 
 	// Create event scheduler
@@ -252,14 +371,63 @@ func NewApp(configPath string, debugMode, dryRun bool) (*App, error) {
 
 	eventScheduler := scheduler.NewEventScheduler(schedulerConfig, calendarManager, publisherInterface, logger)
 
-	return &App{
+	// A dry run must not record reminders as delivered
+	if cfg.StateFile != "" && !dryRun {
+		eventScheduler.SetStateStore(scheduler.NewFileStateStore(cfg.StateFile))
+	}
+
+	app := &App{
 		config:          cfg,
 		logger:          logger,
 		calendarManager: calendarManager,
 		natsPublisher:   natsPublisher,
 		eventScheduler:  eventScheduler,
 		dryRun:          dryRun,
-	}, nil
+		googleProviders: googleProviders,
+		pushCalendars:   make(map[string][]string),
+	}
+
+	// Changes pushed by Google are picked up by refreshing the calendar that
+	// watches the changed Google calendar right away; the scheduler re-plans
+	// if its events changed
+	if cfg.GooglePush.Enabled() && len(googleProviders) > 0 {
+		app.pushReceiver = google.NewPushReceiver(cfg.GooglePush.URL, cfg.GooglePush.TTL, app.refreshPushedCalendar, logger)
+	}
+
+	return app, nil
+}
+
+// refreshPushedCalendar refreshes the calendars watching the Google calendar
+// that pushed a change
+func (a *App) refreshPushedCalendar(calendarID string) {
+	a.pushMu.Lock()
+	names := a.pushCalendars[calendarID]
+	a.pushMu.Unlock()
+
+	if len(names) == 0 {
+		// The channel reported a change before Watch returned its calendars
+		a.logger.Debug("Change pushed for a calendar not yet mapped, refreshing all Google calendars", "calendar_id", calendarID)
+		names = slices.Collect(maps.Keys(a.googleProviders))
+	}
+	for _, name := range names {
+		a.calendarManager.RefreshProvider(name)
+	}
+}
+
+// authConfig returns the HTTP authentication settings of a CalDAV or iCal calendar
+func authConfig(cfg config.CalendarConfig) caldav.AuthConfig {
+	return caldav.AuthConfig{
+		Method:       cfg.Auth,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		PasswordFile: cfg.PasswordFile,
+		PasswordEnv:  cfg.PasswordEnv,
+		Token:        cfg.BearerToken,
+		TokenFile:    cfg.BearerTokenFile,
+		TokenEnv:     cfg.BearerTokenEnv,
+		ClientCert:   cfg.ClientCert,
+		ClientKey:    cfg.ClientKey,
+	}
 }
 
 // Start starts the application services
@@ -269,6 +437,25 @@ func (a *App) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to start event scheduler: %w", err)
 	}
 
+	// Receive Google Calendar push notifications
+	if a.pushReceiver != nil {
+		if err := a.pushReceiver.Start(a.config.GooglePush.Listen); err != nil {
+			return err
+		}
+		for _, name := range slices.Sorted(maps.Keys(a.googleProviders)) {
+			calendarIDs, err := a.googleProviders[name].Watch(ctx, a.pushReceiver)
+			if err != nil {
+				a.logger.Warn("Google Calendar push notifications unavailable, polling only", "calendar", name, "error", err)
+				continue
+			}
+			a.pushMu.Lock()
+			for _, calendarID := range calendarIDs {
+				a.pushCalendars[calendarID] = append(a.pushCalendars[calendarID], name)
+			}
+			a.pushMu.Unlock()
+		}
+	}
+
 	// Start cleanup routine for old events
 	go a.runCleanupRoutine(ctx)
 
@@ -290,6 +477,14 @@ func (a *App) Stop(ctx context.Context) error {
 		a.logger.Info("Event scheduler stopped successfully")
 	}
 
+	// Stop receiving push notifications
+	if a.pushReceiver != nil {
+		if err := a.pushReceiver.Close(ctx); err != nil {
+			a.logger.Error("Error stopping push notification receiver", "error", err)
+			shutdownErrors = append(shutdownErrors, err)
+		}
+	}
+
 	// Close NATS publisher (flush any pending messages)
 	if a.natsPublisher != nil && !a.dryRun {
 		a.logger.Info("Closing NATS publisher")
@@ -394,7 +589,8 @@ func (p *DryRunPublisher) PublishNotification(ctx context.Context, notification
 		"title", notification.Title,
 		"when", notification.When.Format(time.RFC3339),
 		"lead", notification.Lead,
-		"severity", notification.Severity)
+		"severity", notification.Severity,
+		"cancelled", notification.Cancelled)
 	return nil
 }
 
@@ -5,6 +5,8 @@ go 1.25.0
 require (
 	github.com/arran4/golang-ical v0.3.2
 	github.com/nats-io/nats.go v1.46.0
+	golang.org/x/oauth2 v0.32.0
+	google.golang.org/api v0.253.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -33,10 +35,8 @@ require (
 	go.opentelemetry.io/otel/trace v1.37.0 // indirect
 	golang.org/x/crypto v0.43.0 // indirect
 	golang.org/x/net v0.46.0 // indirect
-	golang.org/x/oauth2 v0.32.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 	golang.org/x/text v0.30.0 // indirect
-	google.golang.org/api v0.253.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251014184007-4626949a642f // indirect
 	google.golang.org/grpc v1.76.0 // indirect
 	google.golang.org/protobuf v1.36.10 // indirect
@@ -11,6 +11,7 @@ type Event struct {
 	Description    string    `json:"description,omitempty"`
 	StartTime      time.Time `json:"start_time"`
 	EndTime        time.Time `json:"end_time"`
+	AllDay         bool      `json:"all_day,omitempty"` // Date-only event; StartTime is local midnight
 	Alarms         []Alarm   `json:"alarms,omitempty"`
 	CalendarID     string    `json:"calendar_id"`
 	CalendarName   string    `json:"calendar_name"`
@@ -18,36 +19,143 @@ type Event struct {
 	CreatedAt      time.Time `json:"created_at"`
 	ModifiedAt     time.Time `json:"modified_at"`
 	ResponseStatus string    `json:"response_status,omitempty"` // accepted, declined, tentative, needsAction
+	Status         string    `json:"status,omitempty"`          // confirmed, tentative, cancelled
+	Transparency   string    `json:"transparency,omitempty"`    // opaque, transparent (shown as free)
+	EventType      string    `json:"event_type,omitempty"`      // default, outOfOffice, focusTime, workingLocation (Google)
+	JoinURL        string    `json:"join_url,omitempty"`        // Video conference link
+	HTMLLink       string    `json:"html_link,omitempty"`       // Event page in the calendar's web UI
+	Color          string    `json:"color,omitempty"`           // Hex color, e.g. #5484ed
+	Organizer      string    `json:"organizer,omitempty"`       // Organizer email address
 }
 
-// Alarm represents a notification trigger for an event
+// Event status values
+const (
+	EventStatusConfirmed = "confirmed"
+	EventStatusTentative = "tentative"
+	EventStatusCancelled = "cancelled"
+)
+
+// Event transparency values
+const (
+	TransparencyOpaque      = "opaque"
+	TransparencyTransparent = "transparent"
+)
+
+// Event type values. Only Google Calendar reports types; other events have none.
+const (
+	EventTypeDefault         = "default"
+	EventTypeOutOfOffice     = "outOfOffice"
+	EventTypeFocusTime       = "focusTime"
+	EventTypeWorkingLocation = "workingLocation"
+)
+
+// AlarmRelation identifies the point of an event that a relative alarm is measured from
+type AlarmRelation string
+
+const (
+	AlarmRelatedStart AlarmRelation = "start" // Default when empty
+	AlarmRelatedEnd   AlarmRelation = "end"
+)
+
+// Alarm represents a notification trigger for an event.
+// An alarm either fires at AbsoluteTime, or LeadTimeMinutes before the event
+// start (or end, if RelatedTo is AlarmRelatedEnd). A negative lead time fires after it.
 type Alarm struct {
-	LeadTimeMinutes int    `json:"lead_time_minutes"`
-	Severity        string `json:"severity,omitempty"`
-	Method          string `json:"method,omitempty"` // email, popup, etc.
+	LeadTimeMinutes int           `json:"lead_time_minutes"`
+	RelatedTo       AlarmRelation `json:"related_to,omitempty"`    // start (default) or end
+	AbsoluteTime    *time.Time    `json:"absolute_time,omitempty"` // Fixed trigger instant, overrides the lead time
+	Severity        string        `json:"severity,omitempty"`
+	Method          string        `json:"method,omitempty"` // email, popup, etc.
+}
+
+// IsAbsolute returns true if the alarm fires at a fixed instant
+func (a *Alarm) IsAbsolute() bool {
+	return a.AbsoluteTime != nil
+}
+
+// IsRelatedToEnd returns true if the alarm is measured from the event end
+func (a *Alarm) IsRelatedToEnd() bool {
+	return !a.IsAbsolute() && a.RelatedTo == AlarmRelatedEnd
+}
+
+// ReferenceTime returns the event time the alarm refers to: the end for
+// end-related alarms, otherwise the start
+func (a *Alarm) ReferenceTime(event *Event) time.Time {
+	if a.IsRelatedToEnd() {
+		return event.EndTime
+	}
+	return event.StartTime
+}
+
+// TriggerTime returns the instant at which the alarm fires for the given event
+func (a *Alarm) TriggerTime(event *Event) time.Time {
+	if a.IsAbsolute() {
+		return *a.AbsoluteTime
+	}
+	return a.ReferenceTime(event).Add(-time.Duration(a.LeadTimeMinutes) * time.Minute)
 }
 
 // Notification represents the message format sent to NATS
 // This matches the expected format for calendar-siren consumer
 type Notification struct {
-	Title    string    `json:"title"`
-	When     time.Time `json:"when"`
-	Lead     int       `json:"lead"`
-	Severity string    `json:"severity,omitempty"`
+	Title     string    `json:"title"`
+	When      time.Time `json:"when"`
+	Lead      int       `json:"lead"`
+	Severity  string    `json:"severity,omitempty"`
+	Related   string    `json:"related,omitempty"` // "end" when When is the event end rather than its start
+	AllDay    bool      `json:"all_day,omitempty"`
+	JoinURL   string    `json:"join_url,omitempty"`  // Lets the consumer offer a "join" action
+	Organizer string    `json:"organizer,omitempty"` // Organizer email address
+	Cancelled bool      `json:"cancelled,omitempty"` // The event was removed after a reminder for it went out
 }
 
-// NewNotification creates a Notification from an Event and Alarm
+// NewNotification creates a Notification from an Event and Alarm.
+// When is the time the alarm refers to and Lead the minutes between the trigger and it.
 func NewNotification(event *Event, alarm *Alarm) *Notification {
 	severity := alarm.Severity
 	if severity == "" {
 		severity = "normal"
 	}
 
+	when := alarm.ReferenceTime(event)
+	lead := alarm.LeadTimeMinutes
+	if alarm.IsAbsolute() {
+		lead = int(when.Sub(*alarm.AbsoluteTime).Minutes())
+	}
+
+	notification := &Notification{
+		Title:     event.Title,
+		When:      when,
+		Lead:      lead,
+		Severity:  severity,
+		AllDay:    event.AllDay,
+		JoinURL:   event.JoinURL,
+		Organizer: event.Organizer,
+	}
+	if alarm.IsRelatedToEnd() {
+		notification.Related = string(AlarmRelatedEnd)
+	}
+
+	return notification
+}
+
+// IsRelatedToEnd returns true if When is the event end rather than its start
+func (n *Notification) IsRelatedToEnd() bool {
+	return n.Related == string(AlarmRelatedEnd)
+}
+
+// NewCancellationNotification creates the Notification announcing that an event
+// was cancelled, deleted or moved out of the lookahead window. When is the
+// start of the event and Lead the minutes from now until then.
+func NewCancellationNotification(event *Event, now time.Time) *Notification {
 	return &Notification{
-		Title:    event.Title,
-		When:     event.StartTime,
-		Lead:     alarm.LeadTimeMinutes,
-		Severity: severity,
+		Title:     event.Title,
+		When:      event.StartTime,
+		Lead:      int(event.StartTime.Sub(now).Minutes()),
+		Severity:  "normal",
+		AllDay:    event.AllDay,
+		Organizer: event.Organizer,
+		Cancelled: true,
 	}
 }
 
@@ -61,17 +169,46 @@ func (e *Event) IsUpcoming(now time.Time) bool {
 	return e.StartTime.After(now)
 }
 
+// HasEnded returns true if the event is over at the given time
+func (e *Event) HasEnded(now time.Time) bool {
+	end := e.EndTime
+	if end.IsZero() {
+		end = e.StartTime
+	}
+	return !end.After(now)
+}
+
 // ShouldNotify determines if a notification should be sent for this event
 // based on the given alarm and current time
 func (e *Event) ShouldNotify(alarm *Alarm, now time.Time) bool {
-	if !e.IsUpcoming(now) {
+	if !alarm.ReferenceTime(e).After(now) {
 		return false
 	}
 
-	notificationTime := e.StartTime.Add(-time.Duration(alarm.LeadTimeMinutes) * time.Minute)
+	notificationTime := alarm.TriggerTime(e)
 	return now.After(notificationTime) || now.Equal(notificationTime)
 }
 
+// IsCancelled returns true if the event has been cancelled by its organizer
+func (e *Event) IsCancelled() bool {
+	return e.Status == EventStatusCancelled
+}
+
+// IsTransparent returns true if the event does not block time (shown as free)
+func (e *Event) IsTransparent() bool {
+	return e.Transparency == TransparencyTransparent
+}
+
+// IsFocusTime returns true if the event is a Google focus time block
+func (e *Event) IsFocusTime() bool {
+	return e.EventType == EventTypeFocusTime
+}
+
+// IsWorkingLocation returns true if the event only records where the user works
+func (e *Event) IsWorkingLocation() bool {
+	return e.EventType == EventTypeWorkingLocation
+}
+
 // IsAccepted returns true if the user has accepted the event invitation
 // Empty response status is treated as accepted for backward compatibility
 func (e *Event) IsAccepted() bool {
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -228,6 +229,137 @@ func TestNewNotification(t *testing.T) {
 	if notificationDefault.Severity != "normal" {
 		t.Errorf("Expected default severity 'normal', got %s", notificationDefault.Severity)
 	}
+	if notificationDefault.JoinURL != "" || notificationDefault.Organizer != "" {
+		t.Errorf("Expected no join URL or organizer, got %q and %q", notificationDefault.JoinURL, notificationDefault.Organizer)
+	}
+
+	// Join link and organizer are passed on for the consumer's "join" action
+	event.JoinURL = "https://meet.google.com/abc-defg-hij"
+	event.Organizer = "boss@example.com"
+	notificationJoin := NewNotification(event, alarm)
+	if notificationJoin.JoinURL != event.JoinURL {
+		t.Errorf("Expected join URL %s, got %s", event.JoinURL, notificationJoin.JoinURL)
+	}
+	if notificationJoin.Organizer != event.Organizer {
+		t.Errorf("Expected organizer %s, got %s", event.Organizer, notificationJoin.Organizer)
+	}
+}
+
+func TestNewCancellationNotification(t *testing.T) {
+	startTime := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC)
+	event := &Event{
+		Title:     "Test Meeting",
+		StartTime: startTime,
+		JoinURL:   "https://meet.google.com/abc-defg-hij",
+		Organizer: "boss@example.com",
+	}
+
+	notification := NewCancellationNotification(event, startTime.Add(-10*time.Minute))
+	if !notification.Cancelled || notification.Title != event.Title || !notification.When.Equal(startTime) {
+		t.Errorf("Unexpected cancellation %+v", notification)
+	}
+	if notification.Lead != 10 {
+		t.Errorf("Expected lead 10, got %d", notification.Lead)
+	}
+	if notification.JoinURL != "" || notification.Organizer != event.Organizer {
+		t.Errorf("Expected the organizer but no join URL, got %q and %q", notification.JoinURL, notification.Organizer)
+	}
+
+	data, err := json.Marshal(notification)
+	if err != nil {
+		t.Fatalf("Failed to marshal notification: %v", err)
+	}
+	if !strings.Contains(string(data), `"cancelled":true`) {
+		t.Errorf("Expected the cancelled flag in %s", data)
+	}
+}
+
+func TestAlarm_TriggerTime(t *testing.T) {
+	event := &Event{
+		StartTime: time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC),
+	}
+	absolute := time.Date(2025, 1, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		alarm    Alarm
+		expected time.Time
+		lead     int
+		when     time.Time
+		related  string
+	}{
+		{
+			name:     "before start",
+			alarm:    Alarm{LeadTimeMinutes: 15},
+			expected: time.Date(2025, 1, 1, 13, 45, 0, 0, time.UTC),
+			lead:     15,
+			when:     event.StartTime,
+		},
+		{
+			name:     "after start",
+			alarm:    Alarm{LeadTimeMinutes: -10, RelatedTo: AlarmRelatedStart},
+			expected: time.Date(2025, 1, 1, 14, 10, 0, 0, time.UTC),
+			lead:     -10,
+			when:     event.StartTime,
+		},
+		{
+			name:     "before end",
+			alarm:    Alarm{LeadTimeMinutes: 5, RelatedTo: AlarmRelatedEnd},
+			expected: time.Date(2025, 1, 1, 14, 55, 0, 0, time.UTC),
+			lead:     5,
+			when:     event.EndTime,
+			related:  "end",
+		},
+		{
+			name:     "absolute",
+			alarm:    Alarm{AbsoluteTime: &absolute, RelatedTo: AlarmRelatedEnd},
+			expected: absolute,
+			lead:     270,
+			when:     event.StartTime,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if trigger := tt.alarm.TriggerTime(event); !trigger.Equal(tt.expected) {
+				t.Errorf("TriggerTime() = %v, expected %v", trigger, tt.expected)
+			}
+
+			notification := NewNotification(event, &tt.alarm)
+			if notification.Lead != tt.lead {
+				t.Errorf("NewNotification() Lead = %d, expected %d", notification.Lead, tt.lead)
+			}
+			if !notification.When.Equal(tt.when) {
+				t.Errorf("NewNotification() When = %v, expected %v", notification.When, tt.when)
+			}
+			if notification.Related != tt.related {
+				t.Errorf("NewNotification() Related = %q, expected %q", notification.Related, tt.related)
+			}
+			if notification.IsRelatedToEnd() != tt.alarm.IsRelatedToEnd() {
+				t.Errorf("IsRelatedToEnd() = %t, expected %t", notification.IsRelatedToEnd(), tt.alarm.IsRelatedToEnd())
+			}
+		})
+	}
+}
+
+func TestEvent_ShouldNotifyEndRelated(t *testing.T) {
+	event := &Event{
+		StartTime: time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC),
+	}
+	alarm := &Alarm{LeadTimeMinutes: 5, RelatedTo: AlarmRelatedEnd}
+
+	// The event is in progress, but the end-related alarm is still pending
+	if !event.ShouldNotify(alarm, time.Date(2025, 1, 1, 14, 56, 0, 0, time.UTC)) {
+		t.Error("Expected to notify 4 minutes before the end")
+	}
+	if event.ShouldNotify(alarm, time.Date(2025, 1, 1, 14, 50, 0, 0, time.UTC)) {
+		t.Error("Expected no notification 10 minutes before the end")
+	}
+	if event.ShouldNotify(alarm, time.Date(2025, 1, 1, 15, 1, 0, 0, time.UTC)) {
+		t.Error("Expected no notification after the event ended")
+	}
 }
 
 func TestNotification_JSONSerialization(t *testing.T) {
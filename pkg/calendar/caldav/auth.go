@@ -0,0 +1,381 @@
+package caldav
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authentication methods
+const (
+	AuthBasic  = "basic"
+	AuthDigest = "digest"
+	AuthBearer = "bearer"
+	AuthNone   = "none"
+)
+
+// AuthConfig describes how HTTP requests to a calendar server authenticate.
+// Each secret can be given inline, read from a file or taken from an environment
+// variable; files are re-read on every request so rotated secrets are picked up.
+type AuthConfig struct {
+	// AuthBasic, AuthDigest, AuthBearer or AuthNone. Defaults to bearer when a
+	// token is configured, basic when a username or password is, and none otherwise.
+	Method string `yaml:"method"`
+
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	PasswordEnv  string `yaml:"password_env"`
+
+	Token     string `yaml:"token"` // OAuth2 bearer token
+	TokenFile string `yaml:"token_file"`
+	TokenEnv  string `yaml:"token_env"`
+
+	// PEM client certificate and key for TLS client authentication, usable with
+	// any method
+	ClientCert string `yaml:"client_cert"`
+	ClientKey  string `yaml:"client_key"`
+}
+
+// IsZero reports whether no authentication is configured
+func (c AuthConfig) IsZero() bool {
+	return c == AuthConfig{}
+}
+
+// NewHTTPClient returns an HTTP client that authenticates its requests as
+// configured. It checks that the secrets can be read, so configuration mistakes
+// surface at startup rather than on the first poll.
+func NewHTTPClient(config AuthConfig, timeout time.Duration) (*http.Client, error) {
+	password := secret{value: config.Password, file: config.PasswordFile, env: config.PasswordEnv}
+	token := secret{value: config.Token, file: config.TokenFile, env: config.TokenEnv}
+
+	method := config.Method
+	if method == "" {
+		switch {
+		case token.isSet():
+			method = AuthBearer
+		case config.Username != "" || password.isSet():
+			method = AuthBasic
+		default:
+			method = AuthNone
+		}
+	}
+
+	switch method {
+	case AuthBasic, AuthDigest:
+		if config.Username == "" {
+			return nil, fmt.Errorf("%s authentication requires a username", method)
+		}
+		if err := password.validate("password"); err != nil {
+			return nil, err
+		}
+	case AuthBearer:
+		if err := token.validate("token"); err != nil {
+			return nil, err
+		}
+	case AuthNone:
+	default:
+		return nil, fmt.Errorf("invalid authentication method %q: must be %s, %s, %s or %s",
+			method, AuthBasic, AuthDigest, AuthBearer, AuthNone)
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		base.TLSClientConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &authTransport{
+			base:     base,
+			method:   method,
+			username: config.Username,
+			password: password,
+			token:    token,
+		},
+	}, nil
+}
+
+// secret is a credential given inline, in a file or in an environment variable
+type secret struct {
+	value, file, env string
+}
+
+func (s secret) isSet() bool {
+	return s.value != "" || s.file != "" || s.env != ""
+}
+
+// validate checks that exactly one source is configured and that it yields a value
+func (s secret) validate(name string) error {
+	sources := 0
+	for _, source := range []string{s.value, s.file, s.env} {
+		if source != "" {
+			sources++
+		}
+	}
+	switch {
+	case sources == 0:
+		return fmt.Errorf("%s is required", name)
+	case sources > 1:
+		return fmt.Errorf("only one of %s, %s_file and %s_env may be set", name, name, name)
+	}
+
+	_, err := s.get()
+	return err
+}
+
+// get returns the current value of the secret. Surrounding whitespace, such as
+// the trailing newline of a secret file, is dropped.
+func (s secret) get() (string, error) {
+	var value string
+	switch {
+	case s.file != "":
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %v", err)
+		}
+		value = string(data)
+	case s.env != "":
+		value = os.Getenv(s.env)
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", s.env)
+		}
+	default:
+		return s.value, nil
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", s.file)
+	}
+	return value, nil
+}
+
+// authTransport adds credentials to each request. For digest authentication it
+// answers the server's challenge and reuses it for later requests until the
+// server declares the nonce stale.
+type authTransport struct {
+	base     http.RoundTripper
+	method   string
+	username string
+	password secret
+	token    secret
+
+	mu        sync.Mutex
+	challenge *digestChallenge
+	count     int // Requests sent with the current nonce
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody != nil {
+		// Each attempt sends a fresh copy of the body
+		defer req.Body.Close()
+	}
+
+	authed, err := t.authorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(authed)
+	if err != nil || t.method != AuthDigest || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// Answer a new or stale digest challenge once, if the body can be replayed
+	challenge := parseDigestChallenge(resp.Header.Values("WWW-Authenticate"))
+	if challenge == nil || (req.Body != nil && req.GetBody == nil) {
+		return resp, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	t.mu.Lock()
+	t.challenge = challenge
+	t.count = 0
+	t.mu.Unlock()
+
+	authed, err = t.authorize(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(authed)
+}
+
+// authorize returns a copy of req carrying the credentials
+func (t *authTransport) authorize(req *http.Request) (*http.Request, error) {
+	authed := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %v", err)
+		}
+		authed.Body = body
+	}
+
+	switch t.method {
+	case AuthBasic:
+		password, err := t.password.get()
+		if err != nil {
+			return nil, err
+		}
+		authed.SetBasicAuth(t.username, password)
+	case AuthBearer:
+		token, err := t.token.get()
+		if err != nil {
+			return nil, err
+		}
+		authed.Header.Set("Authorization", "Bearer "+token)
+	case AuthDigest:
+		t.mu.Lock()
+		challenge := t.challenge
+		t.count++
+		count := t.count
+		t.mu.Unlock()
+
+		// Without a challenge yet the request goes out unauthenticated and the
+		// server's 401 supplies one
+		if challenge != nil {
+			password, err := t.password.get()
+			if err != nil {
+				return nil, err
+			}
+			authed.Header.Set("Authorization", challenge.authorization(req.Method, req.URL.RequestURI(), t.username, password, count))
+		}
+	}
+
+	return authed, nil
+}
+
+// digestChallenge holds the parameters of a WWW-Authenticate: Digest header
+// (RFC 7616)
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm string
+	qopAuth                         bool // Server offers qop=auth
+}
+
+// parseDigestChallenge returns the first digest challenge among the headers
+func parseDigestChallenge(headers []string) *digestChallenge {
+	for _, header := range headers {
+		scheme, params, _ := strings.Cut(strings.TrimSpace(header), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+
+		c := &digestChallenge{algorithm: "MD5"}
+		for key, value := range parseAuthParams(params) {
+			switch key {
+			case "realm":
+				c.realm = value
+			case "nonce":
+				c.nonce = value
+			case "opaque":
+				c.opaque = value
+			case "algorithm":
+				c.algorithm = value
+			case "qop":
+				for _, qop := range strings.Split(value, ",") {
+					if strings.TrimSpace(qop) == "auth" {
+						c.qopAuth = true
+					}
+				}
+			}
+		}
+		if c.nonce != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+// parseAuthParams splits comma-separated key=value pairs whose values may be
+// quoted strings containing commas
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimLeft(s, ", ") {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			value = strings.ReplaceAll(rest[1:min(end, len(rest))], `\`, "")
+			s = rest[min(end+1, len(rest)):]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+	}
+	return params
+}
+
+// authorization computes the Authorization header answering the challenge
+func (c *digestChallenge) authorization(method, uri, username, password string, count int) string {
+	algorithm := strings.ToUpper(c.algorithm)
+	sess := strings.HasSuffix(algorithm, "-SESS")
+
+	var h func() hash.Hash
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "SHA-256":
+		h = sha256.New
+	default:
+		h = md5.New
+	}
+	digest := func(s string) string {
+		sum := h()
+		sum.Write([]byte(s))
+		return hex.EncodeToString(sum.Sum(nil))
+	}
+
+	cnonceBytes := make([]byte, 8)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := fmt.Sprintf("%08x", count)
+
+	ha1 := digest(username + ":" + c.realm + ":" + password)
+	if sess {
+		ha1 = digest(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := digest(method + ":" + uri)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s`,
+		username, c.realm, c.nonce, uri, c.algorithm)
+	if c.qopAuth {
+		response := digest(ha1 + ":" + c.nonce + ":" + nc + ":" + cnonce + ":auth:" + ha2)
+		fmt.Fprintf(&b, `, qop=auth, nc=%s, cnonce="%s", response="%s"`, nc, cnonce, response)
+	} else {
+		fmt.Fprintf(&b, `, response="%s"`, digest(ha1+":"+c.nonce+":"+ha2))
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, c.opaque)
+	}
+	return b.String()
+}
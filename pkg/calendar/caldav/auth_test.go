@@ -0,0 +1,231 @@
+package caldav
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	os.WriteFile(passwordFile, []byte("secret\n"), 0600)
+	t.Setenv("CALDAV_TEST_TOKEN", "token")
+
+	tests := []struct {
+		name      string
+		config    AuthConfig
+		method    string
+		expectErr bool
+	}{
+		{name: "no authentication", config: AuthConfig{}, method: AuthNone},
+		{name: "basic by default", config: AuthConfig{Username: "user", Password: "pass"}, method: AuthBasic},
+		{name: "password file", config: AuthConfig{Username: "user", PasswordFile: passwordFile}, method: AuthBasic},
+		{name: "bearer from token", config: AuthConfig{TokenEnv: "CALDAV_TEST_TOKEN"}, method: AuthBearer},
+		{name: "digest", config: AuthConfig{Method: AuthDigest, Username: "user", Password: "pass"}, method: AuthDigest},
+		{name: "missing username", config: AuthConfig{Password: "pass"}, expectErr: true},
+		{name: "missing password", config: AuthConfig{Username: "user"}, expectErr: true},
+		{name: "two password sources", config: AuthConfig{Username: "user", Password: "pass", PasswordFile: passwordFile}, expectErr: true},
+		{name: "unreadable password file", config: AuthConfig{Username: "user", PasswordFile: filepath.Join(dir, "missing")}, expectErr: true},
+		{name: "unset token variable", config: AuthConfig{TokenEnv: "CALDAV_TEST_UNSET"}, expectErr: true},
+		{name: "bearer without token", config: AuthConfig{Method: AuthBearer}, expectErr: true},
+		{name: "unknown method", config: AuthConfig{Method: "ntlm"}, expectErr: true},
+		{name: "certificate without key", config: AuthConfig{ClientCert: "client.pem"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient(tt.config, time.Second)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("NewHTTPClient() expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+			}
+			if method := client.Transport.(*authTransport).method; method != tt.method {
+				t.Errorf("NewHTTPClient() method = %s, expected %s", method, tt.method)
+			}
+		})
+	}
+}
+
+func TestAuthTransportRereadsTokenFile(t *testing.T) {
+	var authorization atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("first\n"), 0600)
+
+	client, err := NewHTTPClient(AuthConfig{TokenFile: tokenFile}, time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+	}
+
+	for _, token := range []string{"first", "second"} {
+		os.WriteFile(tokenFile, []byte(token), 0600)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if got := authorization.Load(); got != "Bearer "+token {
+			t.Errorf("Expected Authorization %q, got %q", "Bearer "+token, got)
+		}
+	}
+}
+
+func TestAuthTransportDigest(t *testing.T) {
+	const realm, nonce = "caldav", "abc123"
+	var challenges, accepted atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Digest ") {
+			challenges.Add(1)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest realm="%s", nonce="%s", qop="auth,auth-int", opaque="xyz"`, realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := parseAuthParams(strings.TrimPrefix(header, "Digest "))
+		md5hex := func(s string) string {
+			sum := md5.Sum([]byte(s))
+			return hex.EncodeToString(sum[:])
+		}
+		ha1 := md5hex("user:" + realm + ":secret")
+		ha2 := md5hex(r.Method + ":" + params["uri"])
+		expected := md5hex(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+		if params["response"] != expected || params["opaque"] != "xyz" || params["uri"] != r.URL.RequestURI() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// The body must survive the challenge round trip
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "<propfind/>" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		accepted.Add(1)
+		w.WriteHeader(http.StatusMultiStatus)
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(AuthConfig{Method: AuthDigest, Username: "user", Password: "secret"}, time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("PROPFIND", server.URL+"/dav/?x=1", strings.NewReader("<propfind/>"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMultiStatus {
+			t.Fatalf("Expected 207 after answering the challenge, got %d", resp.StatusCode)
+		}
+	}
+
+	// The challenge is reused for the second request
+	if challenges.Load() != 1 || accepted.Load() != 2 {
+		t.Errorf("Expected 1 challenge and 2 accepted requests, got %d and %d", challenges.Load(), accepted.Load())
+	}
+}
+
+func TestParseAuthParams(t *testing.T) {
+	params := parseAuthParams(`realm="a, b", nonce="n\"1", qop=auth, stale=TRUE`)
+	expected := map[string]string{"realm": "a, b", "nonce": `n"1`, "qop": "auth", "stale": "TRUE"}
+	for key, value := range expected {
+		if params[key] != value {
+			t.Errorf("parseAuthParams()[%q] = %q, expected %q", key, params[key], value)
+		}
+	}
+}
+
+func TestNewHTTPClientPresentsClientCertificate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "calendar-notifier" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := NewHTTPClient(AuthConfig{ClientCert: certFile, ClientKey: keyFile}, time.Second)
+	if err != nil {
+		t.Fatalf("NewHTTPClient() unexpected error: %v", err)
+	}
+
+	// Trust the test server's self-signed certificate
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client.Transport.(*authTransport).base.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the client certificate to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+// writeTestCertificate writes a self-signed client certificate and its key
+func writeTestCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "calendar-notifier"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
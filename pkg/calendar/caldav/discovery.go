@@ -0,0 +1,218 @@
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	calendarPkg "github.com/venkytv/calendar-notifier/pkg/calendar"
+	"github.com/venkytv/calendar-notifier/pkg/retry"
+)
+
+// discoveryTTL is how long discovered calendars are reused before the
+// calendar home is listed again
+const discoveryTTL = time.Hour
+
+// errNotCalDAV means the configured URL is not served by a CalDAV server
+var errNotCalDAV = errors.New("no CalDAV service found")
+
+const propfindPrincipalBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:current-user-principal/>
+  </d:prop>
+</d:propfind>`
+
+const propfindHomeSetBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <c:calendar-home-set/>
+  </d:prop>
+</d:propfind>`
+
+const propfindCalendarBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:ic="http://apple.com/ns/ical/">
+  <d:prop>
+    <d:resourcetype/>
+    <d:displayname/>
+    <d:current-user-principal/>
+    <c:calendar-description/>
+    <c:supported-calendar-component-set/>
+    <ic:calendar-color/>
+  </d:prop>
+</d:propfind>`
+
+// discoverCalendars finds the calendar collections of the configured account.
+// The configured URL may itself be a calendar collection; otherwise the current
+// user principal is located from it or from /.well-known/caldav (RFC 6764), and
+// the collections in its calendar-home-set are listed (RFC 4791 section 6.2.1).
+func (p *SimpleProvider) discoverCalendars(ctx context.Context) ([]*calendarPkg.Calendar, error) {
+	var principal string
+
+	ms, err := p.davRequest(ctx, "PROPFIND", p.url, "0", propfindCalendarBody)
+	switch {
+	case err == nil:
+		for _, resp := range ms.Responses {
+			prop := resp.prop()
+			if prop == nil {
+				continue
+			}
+			if prop.ResourceType.Calendar != nil {
+				p.logger.Debug("Configured CalDAV URL is a calendar collection", "url", p.url)
+				return []*calendarPkg.Calendar{calendarFromProp(ms.resolve(resp.Href), prop)}, nil
+			}
+			if prop.CurrentUserPrincipal.Href != "" {
+				principal = ms.resolve(prop.CurrentUserPrincipal.Href)
+			}
+		}
+	case !isNotCalDAV(err):
+		return nil, err
+	}
+
+	if principal == "" {
+		principal, err = p.findPrincipal(ctx, wellKnownURL(p.url))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	home, err := p.findCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.listCalendars(ctx, home)
+}
+
+// findPrincipal returns the current-user-principal URL reported at target
+func (p *SimpleProvider) findPrincipal(ctx context.Context, target string) (string, error) {
+	ms, err := p.davRequest(ctx, "PROPFIND", target, "0", propfindPrincipalBody)
+	if err != nil {
+		if isNotCalDAV(err) {
+			return "", fmt.Errorf("%w at %s: %v", errNotCalDAV, target, err)
+		}
+		return "", err
+	}
+
+	for _, resp := range ms.Responses {
+		if prop := resp.prop(); prop != nil && prop.CurrentUserPrincipal.Href != "" {
+			return ms.resolve(prop.CurrentUserPrincipal.Href), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s does not report a current-user-principal", errNotCalDAV, target)
+}
+
+// findCalendarHomeSet returns the collection holding the principal's calendars
+func (p *SimpleProvider) findCalendarHomeSet(ctx context.Context, principal string) (string, error) {
+	ms, err := p.davRequest(ctx, "PROPFIND", principal, "0", propfindHomeSetBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to query principal %s: %v", principal, err)
+	}
+
+	for _, resp := range ms.Responses {
+		if prop := resp.prop(); prop != nil && prop.CalendarHomeSet.Href != "" {
+			return ms.resolve(prop.CalendarHomeSet.Href), nil
+		}
+	}
+	return "", fmt.Errorf("principal %s has no calendar-home-set", principal)
+}
+
+// listCalendars returns the calendar collections in the calendar home that can
+// hold events
+func (p *SimpleProvider) listCalendars(ctx context.Context, home string) ([]*calendarPkg.Calendar, error) {
+	ms, err := p.davRequest(ctx, "PROPFIND", home, "1", propfindCalendarBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar home %s: %v", home, err)
+	}
+
+	var calendars []*calendarPkg.Calendar
+	for _, resp := range ms.Responses {
+		prop := resp.prop()
+		if prop == nil || prop.ResourceType.Calendar == nil {
+			continue
+		}
+
+		calendar := calendarFromProp(ms.resolve(resp.Href), prop)
+		if !supportsEvents(calendar.Components) {
+			p.logger.Debug("Skipping calendar without events",
+				"calendar", calendar.Name,
+				"components", calendar.Components)
+			continue
+		}
+		calendars = append(calendars, calendar)
+	}
+
+	return calendars, nil
+}
+
+// calendarFromProp builds calendar metadata from a collection's properties
+func calendarFromProp(href string, prop *davProp) *calendarPkg.Calendar {
+	name := strings.TrimSpace(prop.DisplayName)
+	if name == "" {
+		if u, err := url.Parse(href); err == nil {
+			name = path.Base(strings.TrimSuffix(u.Path, "/"))
+		}
+	}
+
+	calendar := &calendarPkg.Calendar{
+		ID:          href,
+		Name:        name,
+		Description: strings.TrimSpace(prop.CalendarDescription),
+		Color:       normalizeColor(prop.CalendarColor),
+	}
+	for _, comp := range prop.SupportedComponents.Components {
+		calendar.Components = append(calendar.Components, strings.ToUpper(comp.Name))
+	}
+	return calendar
+}
+
+// supportsEvents reports whether a collection accepts VEVENTs. Collections that
+// do not advertise their components accept all of them.
+func supportsEvents(components []string) bool {
+	if len(components) == 0 {
+		return true
+	}
+	for _, comp := range components {
+		if comp == "VEVENT" {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeColor drops the alpha channel Apple clients append, e.g. "#FF2968FF"
+func normalizeColor(color string) string {
+	color = strings.TrimSpace(color)
+	if len(color) == 9 && strings.HasPrefix(color, "#") {
+		return color[:7]
+	}
+	return color
+}
+
+// wellKnownURL returns the RFC 6764 bootstrap URL on the host of rawURL
+func wellKnownURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/.well-known/caldav"}).String()
+}
+
+// isNotCalDAV reports whether a failed WebDAV request means the server does not
+// speak CalDAV at that URL, as opposed to an authentication or server failure
+func isNotCalDAV(err error) bool {
+	var httpErr *retry.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return httpErr.StatusCode < http.StatusInternalServerError
+}
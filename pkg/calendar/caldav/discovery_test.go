@@ -0,0 +1,208 @@
+package caldav
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newDiscoveryTestServer(t *testing.T) *fakeCalDAVServer {
+	return newFakeCalDAVServer(t,
+		&fakeCalendar{
+			path:       fakeHomePath + "personal/",
+			name:       "Personal",
+			color:      "#FF2968FF",
+			components: []string{"VEVENT", "VTODO"},
+			objects: map[string]string{
+				"dentist.ics": `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test//EN
+BEGIN:VEVENT
+UID:dentist@example.com
+DTSTART:20251001T100000Z
+DTEND:20251001T110000Z
+SUMMARY:Dentist
+END:VEVENT
+END:VCALENDAR`,
+			},
+		},
+		&fakeCalendar{
+			path:       fakeHomePath + "tasks/",
+			name:       "Tasks",
+			components: []string{"VTODO"},
+		},
+		&fakeCalendar{
+			path:  fakeHomePath + "work/",
+			name:  "Work",
+			color: "#0082C9",
+			objects: map[string]string{
+				"standup.ics": `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test//EN
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTART:20251001T140000Z
+DTEND:20251001T143000Z
+SUMMARY:Standup
+END:VEVENT
+END:VCALENDAR`,
+			},
+		},
+	)
+}
+
+func TestSimpleProvider_DiscoverCalendarsViaWellKnown(t *testing.T) {
+	server := newDiscoveryTestServer(t)
+
+	provider := NewSimpleProvider()
+	if err := provider.InitializeWithConfig(&Config{URL: server.URL, Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+
+	if len(calendars) != 2 {
+		t.Fatalf("Expected 2 event calendars, got %d", len(calendars))
+	}
+
+	personal := calendars[0]
+	if personal.ID != server.URL+fakeHomePath+"personal/" {
+		t.Errorf("Expected absolute calendar URL, got %s", personal.ID)
+	}
+	if personal.Name != "Personal" || personal.Color != "#FF2968" {
+		t.Errorf("Expected Personal with color #FF2968, got %s with %s", personal.Name, personal.Color)
+	}
+	if !reflect.DeepEqual(personal.Components, []string{"VEVENT", "VTODO"}) {
+		t.Errorf("Expected VEVENT and VTODO components, got %v", personal.Components)
+	}
+	if calendars[1].Name != "Work" || calendars[1].Components != nil {
+		t.Errorf("Expected Work calendar without declared components, got %+v", calendars[1])
+	}
+
+	// The well-known redirect is followed without losing the PROPFIND method
+	log := server.requestLog()
+	expected := []string{
+		"PROPFIND /",
+		"PROPFIND /.well-known/caldav",
+		"PROPFIND /dav/",
+		"PROPFIND " + fakePrincipalPath,
+		"PROPFIND " + fakeHomePath,
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, log)
+	}
+
+	// A second call is served from the cache
+	if _, err := provider.GetCalendars(context.Background()); err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(server.requestLog()) != len(expected) {
+		t.Errorf("Expected cached calendars, got %d requests", len(server.requestLog()))
+	}
+}
+
+func TestSimpleProvider_GetEventsFromAllDiscoveredCalendars(t *testing.T) {
+	server := newDiscoveryTestServer(t)
+
+	provider := NewSimpleProvider()
+	if err := provider.InitializeWithConfig(&Config{URL: server.URL + "/dav/", Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	events, err := provider.GetEvents(context.Background(), nil, from, to)
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Title != "Dentist" || events[0].CalendarID != server.URL+fakeHomePath+"personal/" {
+		t.Errorf("Expected Dentist from the personal calendar, got %s from %s", events[0].Title, events[0].CalendarID)
+	}
+	if events[1].Title != "Standup" || events[1].CalendarName != "Work" {
+		t.Errorf("Expected Standup from Work, got %s from %s", events[1].Title, events[1].CalendarName)
+	}
+}
+
+func TestSimpleProvider_ConfiguredCalendarCollection(t *testing.T) {
+	server := newDiscoveryTestServer(t)
+
+	provider := NewSimpleProvider()
+	calendarURL := server.URL + fakeHomePath + "work/"
+	if err := provider.InitializeWithConfig(&Config{URL: calendarURL, Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].ID != calendarURL || calendars[0].Name != "Work" {
+		t.Errorf("Expected only the configured Work calendar, got %+v", calendars)
+	}
+}
+
+func TestSimpleProvider_DiscoveryFallsBackToPlainFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nEND:VCALENDAR"))
+	}))
+	defer server.Close()
+
+	provider := NewSimpleProvider()
+	if err := provider.InitializeWithConfig(&Config{URL: server.URL, Username: "user", Password: "pass"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].ID != server.URL || !provider.isPlainFeed(server.URL) {
+		t.Errorf("Expected the URL as a single plain feed, got %+v", calendars)
+	}
+}
+
+func TestSimpleProvider_DiscoveryAuthFailure(t *testing.T) {
+	server := newDiscoveryTestServer(t)
+
+	provider := NewSimpleProvider()
+	if err := provider.InitializeWithConfig(&Config{URL: server.URL, Username: "user", Password: "wrong"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	if _, err := provider.GetCalendars(context.Background()); err == nil {
+		t.Error("Expected discovery to fail with bad credentials")
+	}
+}
+
+func TestWellKnownURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "https://cloud.example.com", expected: "https://cloud.example.com/.well-known/caldav"},
+		{input: "https://cloud.example.com/remote.php/dav/", expected: "https://cloud.example.com/.well-known/caldav"},
+		{input: "http://localhost:5232/user/", expected: "http://localhost:5232/.well-known/caldav"},
+	}
+
+	for _, tt := range tests {
+		if result := wellKnownURL(tt.input); result != tt.expected {
+			t.Errorf("wellKnownURL(%q) = %q, expected %q", tt.input, result, tt.expected)
+		}
+	}
+}
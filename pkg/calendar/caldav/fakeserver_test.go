@@ -0,0 +1,384 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// fakeCalendar is a calendar collection served by fakeCalDAVServer
+type fakeCalendar struct {
+	path       string
+	name       string
+	color      string
+	components []string
+	objects    map[string]string // Object name to iCalendar data
+	noSync     bool              // Reject sync-collection like servers without RFC 6578
+	syncLimit  int               // Most changes per sync-collection reply; the rest follow a 507 (RFC 6578 section 3.6)
+
+	// Sync state: the collection version is its sync token and getctag. Each
+	// object remembers the version it last changed at, deleted ones a tombstone.
+	version  int
+	changed  map[string]int
+	deleted  map[string]int
+	minToken int // Oldest sync token still accepted
+}
+
+// fakeCalDAVServer is a minimal CalDAV server laid out like Nextcloud:
+// /.well-known/caldav redirects to /dav/, which names the principal, whose
+// calendar home holds the calendars
+type fakeCalDAVServer struct {
+	*httptest.Server
+	t         *testing.T
+	calendars []*fakeCalendar
+
+	mu       sync.Mutex
+	requests []string // "METHOD /path" of every request
+	queries  []fakeQuery
+	fetched  []string // Object paths returned by calendar-multiget
+}
+
+// fakeQuery records the filter of a calendar-query REPORT and what it returned
+type fakeQuery struct {
+	start, end time.Time
+	expand     bool
+	served     int // Calendar objects returned
+}
+
+var (
+	timeRangePattern = regexp.MustCompile(`time-range start="([0-9TZ]+)" end="([0-9TZ]+)"`)
+	expandPattern    = regexp.MustCompile(`<c:expand `)
+	syncTokenPattern = regexp.MustCompile(`<d:sync-token>([^<]*)</d:sync-token>`)
+	hrefPattern      = regexp.MustCompile(`<d:href>([^<]*)</d:href>`)
+)
+
+const (
+	fakePrincipalPath = "/dav/principals/user/"
+	fakeHomePath      = "/dav/calendars/user/"
+)
+
+func newFakeCalDAVServer(t *testing.T, calendars ...*fakeCalendar) *fakeCalDAVServer {
+	t.Helper()
+	for _, cal := range calendars {
+		cal.version = 1
+		cal.changed = make(map[string]int)
+		cal.deleted = make(map[string]int)
+		for name := range cal.objects {
+			cal.changed[name] = cal.version
+		}
+	}
+	s := &fakeCalDAVServer{t: t, calendars: calendars}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requestLog returns the requests received so far
+func (s *fakeCalDAVServer) requestLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// queryLog returns the calendar-query REPORTs received so far
+func (s *fakeCalDAVServer) queryLog() []fakeQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeQuery(nil), s.queries...)
+}
+
+// fetchLog returns the object paths downloaded with calendar-multiget so far
+func (s *fakeCalDAVServer) fetchLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.fetched...)
+}
+
+// putObject creates or replaces an object, advancing the collection version
+func (s *fakeCalDAVServer) putObject(calendarPath, name, data string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cal := s.calendar(calendarPath)
+	if cal.objects == nil {
+		cal.objects = make(map[string]string)
+	}
+	cal.version++
+	cal.objects[name] = data
+	cal.changed[name] = cal.version
+	delete(cal.deleted, name)
+}
+
+// deleteObject removes an object, leaving a tombstone for sync-collection
+func (s *fakeCalDAVServer) deleteObject(calendarPath, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cal := s.calendar(calendarPath)
+	cal.version++
+	delete(cal.objects, name)
+	delete(cal.changed, name)
+	cal.deleted[name] = cal.version
+}
+
+// expireSyncTokens makes the server reject every sync token handed out so far
+func (s *fakeCalDAVServer) expireSyncTokens(calendarPath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cal := s.calendar(calendarPath)
+	cal.version++
+	cal.minToken = cal.version
+}
+
+func (s *fakeCalDAVServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	s.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/.well-known/caldav":
+		http.Redirect(w, r, "/dav/", http.StatusMovedPermanently)
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/":
+		s.writeMultistatus(w, fmt.Sprintf(`<d:response><d:href>/dav/</d:href><d:propstat><d:prop>
+<d:current-user-principal><d:href>%s</d:href></d:current-user-principal>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakePrincipalPath))
+	case r.Method == "PROPFIND" && r.URL.Path == fakePrincipalPath:
+		s.writeMultistatus(w, fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop>
+<c:calendar-home-set><d:href>%s</d:href></c:calendar-home-set>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakePrincipalPath, fakeHomePath))
+	case r.Method == "PROPFIND" && r.URL.Path == fakeHomePath:
+		responses := fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop>
+<d:resourcetype><d:collection/></d:resourcetype>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, fakeHomePath)
+		if r.Header.Get("Depth") == "1" {
+			for _, cal := range s.calendars {
+				responses += cal.propResponse()
+			}
+		}
+		s.writeMultistatus(w, responses)
+	default:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		cal := s.calendar(r.URL.Path)
+		switch {
+		case cal == nil:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == "PROPFIND":
+			responses := cal.propResponse()
+			if r.Header.Get("Depth") == "1" {
+				responses += cal.etagResponses()
+			}
+			s.writeMultistatus(w, responses)
+		case r.Method == "REPORT" && strings.Contains(string(body), "sync-collection"):
+			s.handleSyncCollection(w, cal, string(body))
+		case r.Method == "REPORT" && strings.Contains(string(body), "calendar-multiget"):
+			var responses strings.Builder
+			for _, m := range hrefPattern.FindAllStringSubmatch(string(body), -1) {
+				name := strings.TrimPrefix(m[1], cal.path)
+				if _, ok := cal.objects[name]; !ok {
+					fmt.Fprintf(&responses, `<d:response><d:href>%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, m[1])
+					continue
+				}
+				s.fetched = append(s.fetched, m[1])
+				responses.WriteString(cal.objectResponse(name))
+			}
+			s.writeMultistatus(w, responses.String())
+		case r.Method == "REPORT":
+			query := parseFakeQuery(string(body))
+			responses, served := cal.reportResponses(query)
+			query.served = served
+			s.queries = append(s.queries, query)
+			s.writeMultistatus(w, responses)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleSyncCollection answers a sync-collection REPORT with the objects changed
+// and deleted since the request's token, or all objects for an empty token
+func (s *fakeCalDAVServer) handleSyncCollection(w http.ResponseWriter, cal *fakeCalendar, body string) {
+	if cal.noSync {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// The token of a truncated reply is "<version>+<changes already sent>"
+	since, skip := 0, 0
+	if m := syncTokenPattern.FindStringSubmatch(body); m != nil && m[1] != "" {
+		version, sent, _ := strings.Cut(m[1], "+")
+		token, err := strconv.Atoi(version)
+		if sent != "" && err == nil {
+			skip, err = strconv.Atoi(sent)
+		}
+		if err != nil || (sent == "" && (token < cal.minToken || token > cal.version)) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><d:error xmlns:d="DAV:"><d:valid-sync-token/></d:error>`)
+			return
+		}
+		since = token
+	}
+
+	var changes []string
+	for _, name := range sortedNames(cal.changed) {
+		if cal.changed[name] > since {
+			changes = append(changes, fmt.Sprintf(`<d:response><d:href>%s%s</d:href><d:propstat><d:prop>
+<d:getetag>%s</d:getetag>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, cal.path, name, cal.etag(name)))
+		}
+	}
+	if since > 0 {
+		for _, name := range sortedNames(cal.deleted) {
+			if cal.deleted[name] > since {
+				changes = append(changes, fmt.Sprintf(`<d:response><d:href>%s%s</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`, cal.path, name))
+			}
+		}
+	}
+
+	changes = changes[min(skip, len(changes)):]
+	token := strconv.Itoa(cal.version)
+	if cal.syncLimit > 0 && len(changes) > cal.syncLimit {
+		changes = append(changes[:cal.syncLimit],
+			fmt.Sprintf(`<d:response><d:href>%s</d:href><d:status>HTTP/1.1 507 Insufficient Storage</d:status></d:response>`, cal.path))
+		token = fmt.Sprintf("%d+%d", since, skip+cal.syncLimit)
+	}
+	s.writeMultistatus(w, strings.Join(changes, "")+fmt.Sprintf(`<d:sync-token>%s</d:sync-token>`, token))
+}
+
+func (s *fakeCalDAVServer) calendar(path string) *fakeCalendar {
+	for _, cal := range s.calendars {
+		if cal.path == path {
+			return cal
+		}
+	}
+	return nil
+}
+
+func (s *fakeCalDAVServer) writeMultistatus(w http.ResponseWriter, responses string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:ic="http://apple.com/ns/ical/" xmlns:cs="http://calendarserver.org/ns/">%s</d:multistatus>`, responses)
+}
+
+func (c *fakeCalendar) propResponse() string {
+	var comps strings.Builder
+	for _, comp := range c.components {
+		fmt.Fprintf(&comps, `<c:comp name="%s"/>`, comp)
+	}
+	return fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop>
+<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>
+<d:displayname>%s</d:displayname>
+<ic:calendar-color>%s</ic:calendar-color>
+<c:supported-calendar-component-set>%s</c:supported-calendar-component-set>
+<cs:getctag>%d</cs:getctag>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, c.path, c.name, c.color, comps.String(), c.version)
+}
+
+// etag returns the entity tag of an object, which changes with every write
+func (c *fakeCalendar) etag(name string) string {
+	return fmt.Sprintf(`"%s-%d"`, name, c.changed[name])
+}
+
+// etagResponses lists the objects with their ETags, as a depth 1 PROPFIND does
+func (c *fakeCalendar) etagResponses() string {
+	var b strings.Builder
+	for _, name := range sortedNames(c.changed) {
+		fmt.Fprintf(&b, `<d:response><d:href>%s%s</d:href><d:propstat><d:prop>
+<d:resourcetype/>
+<d:getetag>%s</d:getetag>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, c.path, name, c.etag(name))
+	}
+	return b.String()
+}
+
+// objectResponse returns an object with its ETag and calendar data
+func (c *fakeCalendar) objectResponse(name string) string {
+	var data bytes.Buffer
+	xml.EscapeText(&data, []byte(c.objects[name]))
+	return fmt.Sprintf(`<d:response><d:href>%s%s</d:href><d:propstat><d:prop>
+<d:getetag>%s</d:getetag>
+<c:calendar-data>%s</c:calendar-data>
+</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, c.path, name, c.etag(name), data.String())
+}
+
+func sortedNames(versions map[string]int) []string {
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseFakeQuery(body string) fakeQuery {
+	var query fakeQuery
+	if m := timeRangePattern.FindStringSubmatch(body); m != nil {
+		query.start, _ = time.Parse(caldavTimeFormat, m[1])
+		query.end, _ = time.Parse(caldavTimeFormat, m[2])
+	}
+	query.expand = expandPattern.MatchString(body)
+	return query
+}
+
+// matches reports whether an object has an event overlapping the query's time
+// range. Like a real server, recurring events are always considered.
+func (q fakeQuery) matches(data string) bool {
+	if q.start.IsZero() {
+		return true
+	}
+	cal, err := ics.ParseCalendar(strings.NewReader(data))
+	if err != nil {
+		return true
+	}
+	for _, event := range cal.Events() {
+		if event.GetProperty(ics.ComponentPropertyRrule) != nil {
+			return true
+		}
+		start, err := event.GetStartAt()
+		if err != nil {
+			return true
+		}
+		end, err := event.GetEndAt()
+		if err != nil {
+			end = start
+		}
+		if start.Before(q.end) && end.After(q.start) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakeCalendar) reportResponses(query fakeQuery) (string, int) {
+	names := make([]string, 0, len(c.objects))
+	for name := range c.objects {
+		if query.matches(c.objects[name]) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(c.objectResponse(name))
+	}
+	return b.String(), len(names)
+}
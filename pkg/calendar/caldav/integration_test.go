@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -270,9 +271,12 @@ SUMMARY:Concurrent Access Test
 END:VEVENT
 END:VCALENDAR`
 
-	requestCount := 0
+	var requestCount int32
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestCount++
+		// Only count feed downloads, not the one-off discovery requests
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&requestCount, 1)
+		}
 		// Add some processing delay to make concurrency issues more likely
 		time.Sleep(10 * time.Millisecond)
 
@@ -334,8 +338,8 @@ END:VCALENDAR`
 	}
 
 	// Verify that all requests were actually made (requestCount should be numRequests)
-	if requestCount != numRequests {
-		t.Errorf("Expected %d requests to be made, but got %d", numRequests, requestCount)
+	if count := atomic.LoadInt32(&requestCount); count != numRequests {
+		t.Errorf("Expected %d requests to be made, but got %d", numRequests, count)
 	}
 }
 
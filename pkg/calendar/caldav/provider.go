@@ -2,10 +2,12 @@ package caldav
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/venkytv/calendar-notifier/internal/models"
@@ -19,17 +21,49 @@ type Config struct {
 	URL      string `yaml:"url"`
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
+	TimeZone string `yaml:"timezone"` // Zone for floating times, defaults to local
+
+	// Authentication other than a Basic password; Username and Password above
+	// fill in Auth.Username and Auth.Password when those are empty
+	Auth AuthConfig `yaml:"auth"`
+
+	// How collections are fetched: SyncQuery (default) or SyncIncremental
+	Sync string `yaml:"sync"`
+
+	// File keeping the local copy of each collection across restarts (incremental sync only)
+	CacheFile string `yaml:"cache_file"`
+
+	// Ask the server to expand recurring events into instances (RFC 4791 section 9.6.5).
+	// Expanded instances are in UTC, so all-day events lose their date-only form.
+	// Requires SyncQuery.
+	ExpandRecurrences bool `yaml:"expand_recurrences"`
 }
 
-// SimpleProvider is a basic CalDAV provider that fetches iCal data via HTTP
+// SimpleProvider is a CalDAV provider. The configured URL may be a server or
+// account URL, from which every calendar is discovered, a single calendar
+// collection, or a plain iCalendar feed fetched with GET.
 type SimpleProvider struct {
 	name     string
 	url      string
 	username string
-	password string
+	location *time.Location
+	sync     string
+	expand   bool
 	client   *http.Client
 	logger   *slog.Logger
 	retryer  *retry.Retryer
+
+	// Discovered calendars, refreshed after discoveryTTL
+	mu           sync.Mutex
+	calendars    []*calendarPkg.Calendar
+	discoveredAt time.Time
+	plainFeed    bool // The URL serves iCalendar data directly rather than CalDAV
+
+	// Local copies of collections for incremental sync
+	syncMu      sync.Mutex
+	collections map[string]*collectionState
+	removed     []string // IDs of events deleted on the server, for TakeRemovedEvents
+	cacheFile   string
 }
 
 // NewSimpleProvider creates a new simple CalDAV provider
@@ -66,8 +100,9 @@ func NewSimpleProvider() *SimpleProvider {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		logger:  logger,
-		retryer: retry.NewRetryer(retryConfig, logger),
+		logger:      logger,
+		retryer:     retry.NewRetryer(retryConfig, logger),
+		collections: make(map[string]*collectionState),
 	}
 }
 
@@ -123,36 +158,112 @@ func (p *SimpleProvider) InitializeWithConfig(config *Config) error {
 	if config.URL == "" {
 		return fmt.Errorf("CalDAV URL is required")
 	}
-	if config.Username == "" {
-		return fmt.Errorf("CalDAV username is required")
+
+	auth := config.Auth
+	if auth.Username == "" {
+		auth.Username = config.Username
 	}
-	if config.Password == "" {
-		return fmt.Errorf("CalDAV password is required")
+	if auth.Password == "" {
+		auth.Password = config.Password
+	}
+	client, err := NewHTTPClient(auth, p.client.Timeout)
+	if err != nil {
+		return fmt.Errorf("invalid CalDAV authentication: %v", err)
+	}
+
+	if config.TimeZone != "" {
+		loc, err := time.LoadLocation(config.TimeZone)
+		if err != nil {
+			return fmt.Errorf("invalid CalDAV time zone %q: %v", config.TimeZone, err)
+		}
+		p.location = loc
 	}
 
 	p.url = config.URL
-	p.username = config.Username
-	p.password = config.Password
+	p.username = auth.Username
+	switch config.Sync {
+	case "":
+		p.sync = SyncQuery
+	case SyncIncremental, SyncQuery:
+		p.sync = config.Sync
+	default:
+		return fmt.Errorf("invalid CalDAV sync mode %q: must be %s or %s", config.Sync, SyncIncremental, SyncQuery)
+	}
+	if config.ExpandRecurrences && p.sync != SyncQuery {
+		return fmt.Errorf("CalDAV expand_recurrences requires sync mode %s", SyncQuery)
+	}
+
+	p.client = client
+	p.expand = config.ExpandRecurrences
+	p.cacheFile = config.CacheFile
+
+	if err := p.loadCache(); err != nil {
+		// Start from an empty copy; the first sync downloads everything again
+		p.logger.Warn("Ignoring CalDAV cache", "path", p.cacheFile, "error", err)
+	}
 
 	return nil
 }
 
-// GetEvents retrieves events from CalDAV server by fetching iCal data
+// GetEvents retrieves events from the given calendar collections, or from every
+// discovered calendar if none are given
 func (p *SimpleProvider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
 	if p.url == "" {
 		return nil, fmt.Errorf("CalDAV provider not initialized")
 	}
 
-	// For simplicity, we'll fetch the main calendar URL
-	// In a real implementation, we'd iterate through calendar IDs
+	calendars, err := p.GetCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(calendarIDs) == 0 {
+		for _, calendar := range calendars {
+			calendarIDs = append(calendarIDs, calendar.ID)
+		}
+	}
+
+	var allEvents []*models.Event
+	for _, calendarID := range calendarIDs {
+		calendarName := "CalDAV Calendar"
+		for _, calendar := range calendars {
+			if calendar.ID == calendarID {
+				calendarName = calendar.Name
+			}
+		}
+
+		var events []*models.Event
+		switch {
+		case p.isPlainFeed(calendarID):
+			events, err = p.getFeedEvents(ctx, from, to)
+		case p.sync == SyncQuery:
+			events, err = p.getCollectionEvents(ctx, calendarID, calendarName, from, to)
+		default:
+			events, err = p.getSyncedEvents(ctx, calendarID, calendarName, from, to)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.logger.Debug("Fetched events from CalDAV calendar",
+			"calendar", calendarName,
+			"url", calendarID,
+			"event_count", len(events))
+
+		allEvents = append(allEvents, events...)
+	}
+
+	return allEvents, nil
+}
+
+// getFeedEvents fetches and parses the configured URL as a plain iCalendar feed
+func (p *SimpleProvider) getFeedEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
 	icalData, err := p.fetchICalData(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch iCal data: %v", err)
 	}
 
-	// Parse iCal data using shared parser
 	// Pass username as userEmail to identify the authenticated user in attendee lists
-	events, err := ical.ParseICalData(icalData, p.url, "CalDAV Calendar", from, to, p.username, p.logger)
+	events, err := ical.ParseICalDataInLocation(icalData, p.url, "CalDAV Calendar", from, to, p.username, p.location, p.logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse iCal data: %v", err)
 	}
@@ -168,8 +279,6 @@ func (p *SimpleProvider) fetchICalData(ctx context.Context) (string, error) {
 			return nil, fmt.Errorf("failed to create request: %v", err)
 		}
 
-		// Add basic authentication
-		req.SetBasicAuth(p.username, p.password)
 		req.Header.Set("Accept", "text/calendar")
 		req.Header.Set("User-Agent", "calendar-notifier/1.0")
 
@@ -217,32 +326,76 @@ func (p *SimpleProvider) fetchICalData(ctx context.Context) (string, error) {
 	return result.(string), nil
 }
 
-// GetCalendars returns a basic calendar list (simplified for this provider)
+// GetCalendars discovers the calendars of the configured account. Results are
+// cached for discoveryTTL; if rediscovery fails the previous list is kept.
 func (p *SimpleProvider) GetCalendars(ctx context.Context) ([]*calendarPkg.Calendar, error) {
 	if p.url == "" {
 		return nil, fmt.Errorf("CalDAV provider not initialized")
 	}
 
-	// Return a single calendar representing this CalDAV endpoint
-	calendar := &calendarPkg.Calendar{
-		ID:          p.url,
-		Name:        "CalDAV Calendar",
-		Description: fmt.Sprintf("Calendar from %s", p.url),
-		Primary:     true,
-		AccessRole:  "owner",
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.calendars != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.calendars, nil
 	}
 
-	return []*calendarPkg.Calendar{calendar}, nil
+	calendars, err := p.discoverCalendars(ctx)
+	plainFeed := false
+	switch {
+	case errors.Is(err, errNotCalDAV):
+		// Not a CalDAV server: treat the URL as a single iCalendar feed
+		p.logger.Info("No CalDAV service found, fetching URL as an iCalendar feed",
+			"url", p.url,
+			"reason", err)
+		calendars = []*calendarPkg.Calendar{{
+			ID:          p.url,
+			Name:        "CalDAV Calendar",
+			Description: fmt.Sprintf("Calendar from %s", p.url),
+			Primary:     true,
+			AccessRole:  "owner",
+		}}
+		plainFeed = true
+	case err != nil:
+		if p.calendars != nil {
+			p.logger.Warn("CalDAV discovery failed, using previously discovered calendars",
+				"url", p.url,
+				"error", err)
+			return p.calendars, nil
+		}
+		return nil, fmt.Errorf("CalDAV discovery failed: %v", err)
+	default:
+		p.logger.Info("Discovered CalDAV calendars",
+			"url", p.url,
+			"calendar_count", len(calendars))
+	}
+
+	p.calendars = calendars
+	p.plainFeed = plainFeed
+	p.discoveredAt = time.Now()
+
+	return calendars, nil
 }
 
-// IsHealthy performs a health check by attempting to fetch calendar data
+// isPlainFeed reports whether calendarID is the configured URL serving plain
+// iCalendar data
+func (p *SimpleProvider) isPlainFeed(calendarID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.plainFeed && calendarID == p.url
+}
+
+// IsHealthy checks that the server answers CalDAV requests, or serves the
+// feed if the URL is not a CalDAV server
 func (p *SimpleProvider) IsHealthy(ctx context.Context) error {
 	if p.url == "" {
 		return fmt.Errorf("CalDAV provider not initialized")
 	}
 
-	// Try to fetch data as a health check
-	_, err := p.fetchICalData(ctx)
+	_, err := p.davRequest(ctx, "PROPFIND", p.url, "0", propfindPrincipalBody)
+	if err != nil && isNotCalDAV(err) {
+		_, err = p.fetchICalData(ctx)
+	}
 	if err != nil {
 		return fmt.Errorf("CalDAV health check failed: %v", err)
 	}
@@ -77,8 +77,9 @@ func TestSimpleProvider_InitializeWithConfig(t *testing.T) {
 	if provider.username != config.Username {
 		t.Errorf("Expected username %s, got %s", config.Username, provider.username)
 	}
-	if provider.password != config.Password {
-		t.Errorf("Expected password %s, got %s", config.Password, provider.password)
+	transport, ok := provider.client.Transport.(*authTransport)
+	if !ok || transport.method != AuthBasic || transport.password.value != config.Password {
+		t.Errorf("Expected basic authentication with password %s, got %+v", config.Password, provider.client.Transport)
 	}
 }
 
@@ -103,9 +104,15 @@ func TestSimpleProvider_GetCalendars(t *testing.T) {
 		t.Error("Expected error when not initialized")
 	}
 
-	// Test with initialization
+	// Test with a server that does not speak CalDAV
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nEND:VCALENDAR"))
+	}))
+	defer server.Close()
+
 	config := &Config{
-		URL:      "https://example.com/caldav",
+		URL:      server.URL,
 		Username: "user",
 		Password: "pass",
 	}
@@ -116,10 +123,10 @@ func TestSimpleProvider_GetCalendars(t *testing.T) {
 
 	calendars, err := provider.GetCalendars(context.Background())
 	if err != nil {
-		t.Errorf("Unexpected error: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
 	}
 	if len(calendars) != 1 {
-		t.Errorf("Expected 1 calendar, got %d", len(calendars))
+		t.Fatalf("Expected 1 calendar, got %d", len(calendars))
 	}
 	if calendars[0].ID != config.URL {
 		t.Errorf("Expected calendar ID %s, got %s", config.URL, calendars[0].ID)
@@ -164,6 +171,12 @@ END:VCALENDAR`
 			t.Errorf("Expected credentials 'testuser:testpass', got %s", credentials)
 		}
 
+		// Discovery requests are rejected as by a plain web server
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
 		// Verify Accept header
 		accept := r.Header.Get("Accept")
 		if accept != "text/calendar" {
@@ -0,0 +1,81 @@
+package caldav
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/ical"
+)
+
+// caldavTimeFormat is the UTC date-time form used in time-range and expand elements
+const caldavTimeFormat = "20060102T150405Z"
+
+// calendarQuery builds a calendar-query REPORT body that selects the events
+// overlapping [from, to) (RFC 4791 section 9.9). With expand the server returns
+// each instance of a recurring event in that range as its own component.
+func calendarQuery(from, to time.Time, expand bool) string {
+	start := from.UTC().Format(caldavTimeFormat)
+	end := to.UTC().Format(caldavTimeFormat)
+
+	calendarData := "<c:calendar-data/>"
+	if expand {
+		calendarData = fmt.Sprintf(`<c:calendar-data>
+      <c:expand start="%s" end="%s"/>
+    </c:calendar-data>`, start, end)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <d:getetag/>
+    %s
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT">
+        <c:time-range start="%s" end="%s"/>
+      </c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`, calendarData, start, end)
+}
+
+// getCollectionEvents fetches the events of a calendar collection that overlap
+// [from, to) with a calendar-query REPORT, so the server does the filtering
+func (p *SimpleProvider) getCollectionEvents(ctx context.Context, calendarURL, calendarName string, from, to time.Time) ([]*models.Event, error) {
+	ms, err := p.davRequest(ctx, "REPORT", calendarURL, "1", calendarQuery(from, to, p.expand))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calendar %s: %v", calendarName, err)
+	}
+
+	var events []*models.Event
+	for _, resp := range ms.Responses {
+		prop := resp.prop()
+		if prop == nil || prop.CalendarData == "" {
+			continue
+		}
+
+		// Each calendar object resource is a complete VCALENDAR; recurring events
+		// the server did not expand are still expanded client-side
+		objectEvents, err := ical.ParseICalDataInLocation(prop.CalendarData, calendarURL, calendarName, from, to, p.username, p.location, p.logger)
+		if err != nil {
+			p.logger.Warn("Failed to parse CalDAV calendar object",
+				"calendar", calendarName,
+				"href", ms.resolve(resp.Href),
+				"error", err)
+			continue
+		}
+		events = append(events, objectEvents...)
+	}
+
+	p.logger.Debug("Queried CalDAV calendar",
+		"calendar", calendarName,
+		"objects", len(ms.Responses),
+		"from", from.Format(time.RFC3339),
+		"to", to.Format(time.RFC3339),
+		"expand", p.expand)
+
+	return events, nil
+}
@@ -0,0 +1,129 @@
+package caldav
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarQuery(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	from := time.Date(2025, 10, 1, 2, 0, 0, 0, loc)
+	to := time.Date(2025, 10, 2, 2, 0, 0, 0, loc)
+
+	body := calendarQuery(from, to, false)
+	if !strings.Contains(body, `<c:time-range start="20251001T000000Z" end="20251002T000000Z"/>`) {
+		t.Errorf("Expected a UTC time-range filter, got:\n%s", body)
+	}
+	if strings.Contains(body, "<c:expand") {
+		t.Errorf("Expected no expand element, got:\n%s", body)
+	}
+
+	body = calendarQuery(from, to, true)
+	if !strings.Contains(body, `<c:expand start="20251001T000000Z" end="20251002T000000Z"/>`) {
+		t.Errorf("Expected an expand element, got:\n%s", body)
+	}
+}
+
+func newQueryTestServer(t *testing.T) *fakeCalDAVServer {
+	event := func(uid, start, end, extra string) string {
+		return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//Test//EN\nBEGIN:VEVENT\nUID:" + uid +
+			"\nDTSTART:" + start + "\nDTEND:" + end + "\nSUMMARY:" + uid + "\n" + extra + "END:VEVENT\nEND:VCALENDAR"
+	}
+
+	return newFakeCalDAVServer(t, &fakeCalendar{
+		path: fakeHomePath + "work/",
+		name: "Work",
+		objects: map[string]string{
+			"archived.ics": event("Archived", "20200301T100000Z", "20200301T110000Z", ""),
+			"review.ics":   event("Review", "20251001T100000Z", "20251001T110000Z", ""),
+			"offsite.ics":  event("Offsite", "20300601T090000Z", "20300601T170000Z", ""),
+			"weekly.ics":   event("Weekly Sync", "20250101T150000Z", "20250101T153000Z", "RRULE:FREQ=WEEKLY\n"),
+		},
+	})
+}
+
+func TestSimpleProvider_GetEventsQueriesTimeRange(t *testing.T) {
+	server := newQueryTestServer(t)
+
+	provider := NewSimpleProvider()
+	calendarURL := server.URL + fakeHomePath + "work/"
+	if err := provider.InitializeWithConfig(&Config{URL: calendarURL, Username: "user", Password: "secret", Sync: SyncQuery}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	events, err := provider.GetEvents(context.Background(), []string{calendarURL}, from, to)
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	titles := map[string]bool{}
+	for _, event := range events {
+		titles[event.Title] = true
+	}
+	if len(events) != 2 || !titles["Review"] || !titles["Weekly Sync"] {
+		t.Errorf("Expected Review and one Weekly Sync occurrence, got %v", titles)
+	}
+
+	queries := server.queryLog()
+	if len(queries) != 1 {
+		t.Fatalf("Expected 1 calendar-query REPORT, got %d", len(queries))
+	}
+	query := queries[0]
+	if !query.start.Equal(from) || !query.end.Equal(to) {
+		t.Errorf("Expected time-range %v to %v, got %v to %v", from, to, query.start, query.end)
+	}
+	if query.served != 2 {
+		t.Errorf("Expected the server to return 2 of 4 objects, got %d", query.served)
+	}
+	if query.expand {
+		t.Error("Expected no server-side expansion by default")
+	}
+}
+
+func TestSimpleProvider_DefaultsToQuerySync(t *testing.T) {
+	server := newQueryTestServer(t)
+
+	provider := NewSimpleProvider()
+	calendarURL := server.URL + fakeHomePath + "work/"
+	if err := provider.InitializeWithConfig(&Config{URL: calendarURL, Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	if _, err := provider.GetEvents(context.Background(), []string{calendarURL}, from, to); err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	if queries := server.queryLog(); len(queries) != 1 || queries[0].served != 2 {
+		t.Errorf("Expected the default to be a time-range calendar-query, got %+v", queries)
+	}
+}
+
+func TestSimpleProvider_GetEventsRequestsExpansion(t *testing.T) {
+	server := newQueryTestServer(t)
+
+	provider := NewSimpleProvider()
+	calendarURL := server.URL + fakeHomePath + "work/"
+	config := &Config{URL: calendarURL, Username: "user", Password: "secret", ExpandRecurrences: true}
+	if err := provider.InitializeWithConfig(config); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	if _, err := provider.GetEvents(context.Background(), nil, from, to); err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	queries := server.queryLog()
+	if len(queries) != 1 || !queries[0].expand {
+		t.Errorf("Expected a calendar-query REPORT requesting expansion, got %+v", queries)
+	}
+}
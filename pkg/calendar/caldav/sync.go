@@ -0,0 +1,490 @@
+package caldav
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/venkytv/calendar-notifier/internal/models"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/ical"
+	"github.com/venkytv/calendar-notifier/pkg/retry"
+)
+
+// Sync modes for calendar collections
+const (
+	// SyncIncremental keeps a local copy of each collection and only downloads
+	// objects that changed, using sync-collection (RFC 6578) or, if the server
+	// lacks it, getctag and ETag comparison
+	SyncIncremental = "incremental"
+
+	// SyncQuery sends a time-range calendar-query REPORT on every poll
+	SyncQuery = "query"
+)
+
+// multigetBatchSize bounds the number of hrefs in one calendar-multiget REPORT
+const multigetBatchSize = 50
+
+// collectionState is the local copy of one calendar collection
+type collectionState struct {
+	SyncToken string                   `json:"sync_token,omitempty"`
+	CTag      string                   `json:"ctag,omitempty"`
+	NoSync    bool                     `json:"no_sync,omitempty"` // Server rejected sync-collection
+	Objects   map[string]*cachedObject `json:"objects"`           // Keyed by absolute href
+}
+
+// cachedObject is a calendar object resource and the ETag it was fetched at.
+// A sync replaces the object when its ETag changes, so Data is parsed once.
+type cachedObject struct {
+	ETag string `json:"etag"`
+	Data string `json:"data"`
+
+	parsed   bool          // Data has been parsed
+	calendar *ics.Calendar // Parsed Data; nil if it failed to parse
+}
+
+const syncCollectionBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:sync-collection xmlns:d="DAV:">
+  <d:sync-token>%s</d:sync-token>
+  <d:sync-level>1</d:sync-level>
+  <d:prop>
+    <d:getetag/>
+  </d:prop>
+</d:sync-collection>`
+
+const propfindCTagBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/">
+  <d:prop>
+    <cs:getctag/>
+  </d:prop>
+</d:propfind>`
+
+const propfindETagBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getetag/>
+  </d:prop>
+</d:propfind>`
+
+const calendarMultigetBody = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <d:getetag/>
+    <c:calendar-data/>
+  </d:prop>
+%s</c:calendar-multiget>`
+
+// getSyncedEvents brings the local copy of a collection up to date and returns
+// its events overlapping [from, to). Events of objects deleted on the server
+// are recorded for TakeRemovedEvents.
+func (p *SimpleProvider) getSyncedEvents(ctx context.Context, calendarURL, calendarName string, from, to time.Time) ([]*models.Event, error) {
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	state := p.collections[calendarURL]
+	if state == nil {
+		state = &collectionState{Objects: make(map[string]*cachedObject)}
+		p.collections[calendarURL] = state
+	}
+
+	changed, removed, err := p.syncCollection(ctx, calendarURL, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync calendar %s: %v", calendarName, err)
+	}
+
+	if changed || len(removed) > 0 {
+		p.saveCache()
+	}
+
+	for _, href := range sortedHrefs(removed) {
+		for _, event := range p.objectEvents(removed[href], href, calendarURL, calendarName, from, to) {
+			p.removed = append(p.removed, event.ID)
+		}
+	}
+
+	var events []*models.Event
+	for _, href := range sortedHrefs(state.Objects) {
+		events = append(events, p.objectEvents(state.Objects[href], href, calendarURL, calendarName, from, to)...)
+	}
+
+	return events, nil
+}
+
+// objectEvents returns the events of one calendar object resource within
+// [from, to), parsing it on first use only. Parse failures are logged instead
+// of failing so one bad object does not hide the rest of the calendar.
+func (p *SimpleProvider) objectEvents(object *cachedObject, href, calendarURL, calendarName string, from, to time.Time) []*models.Event {
+	if !object.parsed {
+		object.parsed = true
+		calendar, err := ics.ParseCalendar(strings.NewReader(object.Data))
+		if err != nil {
+			p.logger.Warn("Failed to parse CalDAV calendar object",
+				"calendar", calendarName,
+				"href", href,
+				"error", err)
+		} else {
+			object.calendar = calendar
+		}
+	}
+	if object.calendar == nil {
+		return nil
+	}
+	return ical.CalendarEvents(object.calendar, calendarURL, calendarName, from, to, p.username, p.location, p.logger)
+}
+
+// syncCollection updates state from the server. It reports whether the state
+// changed and returns the objects deleted on the server.
+func (p *SimpleProvider) syncCollection(ctx context.Context, calendarURL string, state *collectionState) (bool, map[string]*cachedObject, error) {
+	if !state.NoSync {
+		changed, removed, err := p.syncWithToken(ctx, calendarURL, state)
+		if !errors.Is(err, errSyncUnsupported) {
+			return changed, removed, err
+		}
+		p.logger.Info("Server does not support sync-collection, comparing ETags instead",
+			"url", calendarURL,
+			"reason", err)
+		state.NoSync = true
+		state.SyncToken = ""
+	}
+
+	return p.syncWithETags(ctx, calendarURL, state)
+}
+
+// errSyncUnsupported means the server rejected the sync-collection REPORT
+var errSyncUnsupported = errors.New("sync-collection not supported")
+
+// syncWithToken applies the changes since the stored sync token. An empty token
+// lists the whole collection; a token the server no longer accepts is dropped
+// and the collection listed again. A truncated reply is followed by further
+// requests with the token it returned until the server has sent everything.
+func (p *SimpleProvider) syncWithToken(ctx context.Context, calendarURL string, state *collectionState) (bool, map[string]*cachedObject, error) {
+	startToken := state.SyncToken
+	fullListing := state.SyncToken == ""
+	listed := make(map[string]bool) // Objects of a full listing, over all its replies
+	removed := make(map[string]*cachedObject)
+	changed := false
+
+	// A failed reply undoes the earlier ones, so the next sync starts over
+	fail := func(err error) (bool, map[string]*cachedObject, error) {
+		restoreObjects(state, removed)
+		state.SyncToken = startToken
+		return false, nil, err
+	}
+
+	for {
+		ms, err := p.davRequest(ctx, "REPORT", calendarURL, "1", fmt.Sprintf(syncCollectionBody, xmlEscape(state.SyncToken)))
+		if err != nil && isClientError(err) && state.SyncToken == startToken && startToken != "" {
+			// RFC 6578 section 3.2: an invalid token fails the valid-sync-token precondition
+			p.logger.Info("CalDAV sync token rejected, resynchronizing", "url", calendarURL, "error", err)
+			state.SyncToken = ""
+			fullListing = true
+			ms, err = p.davRequest(ctx, "REPORT", calendarURL, "1", fmt.Sprintf(syncCollectionBody, ""))
+		}
+		if err != nil {
+			if isClientError(err) {
+				err = fmt.Errorf("%w: %v", errSyncUnsupported, err)
+			}
+			return fail(err)
+		}
+		if ms.SyncToken == "" {
+			return fail(fmt.Errorf("%w: reply has no sync-token", errSyncUnsupported))
+		}
+
+		truncated := false
+		listing := make(map[string]string)
+		for _, resp := range ms.Responses {
+			href := ms.resolve(resp.Href)
+			if isCollectionHref(href, calendarURL) {
+				truncated = truncated || resp.truncated()
+				continue
+			}
+			if resp.notFound() {
+				if object, ok := state.Objects[href]; ok {
+					removed[href] = object
+					delete(state.Objects, href)
+				}
+				delete(listed, href)
+				continue
+			}
+			if prop := resp.prop(); prop != nil {
+				listing[href] = prop.ETag
+				listed[href] = true
+			}
+		}
+
+		stored, err := p.fetchChanged(ctx, calendarURL, state, listing)
+		if err != nil {
+			return fail(err)
+		}
+		changed = changed || stored > 0
+
+		if truncated && ms.SyncToken == state.SyncToken {
+			return fail(fmt.Errorf("truncated sync-collection reply did not advance the sync token"))
+		}
+
+		// Only advance the token once the changes it covers are stored
+		changed = changed || state.SyncToken != ms.SyncToken
+		state.SyncToken = ms.SyncToken
+		if !truncated {
+			break
+		}
+		p.logger.Debug("CalDAV sync-collection reply truncated, continuing", "url", calendarURL)
+	}
+
+	// Objects missing from a complete listing were deleted on the server
+	if fullListing {
+		for href, object := range state.Objects {
+			if !listed[href] {
+				removed[href] = object
+				delete(state.Objects, href)
+			}
+		}
+	}
+
+	p.logger.Debug("Synchronized CalDAV calendar",
+		"url", calendarURL,
+		"full", fullListing,
+		"changed", changed,
+		"removed", len(removed))
+
+	return changed, removed, nil
+}
+
+// syncWithETags skips the collection if its getctag is unchanged, otherwise
+// lists the ETags of all objects and downloads the ones that differ
+func (p *SimpleProvider) syncWithETags(ctx context.Context, calendarURL string, state *collectionState) (bool, map[string]*cachedObject, error) {
+	ctag := ""
+	if ms, err := p.davRequest(ctx, "PROPFIND", calendarURL, "0", propfindCTagBody); err == nil {
+		for _, resp := range ms.Responses {
+			if prop := resp.prop(); prop != nil && prop.CTag != "" {
+				ctag = prop.CTag
+			}
+		}
+	} else if !isClientError(err) {
+		return false, nil, err
+	}
+
+	if ctag != "" && ctag == state.CTag {
+		p.logger.Debug("CalDAV calendar unchanged", "url", calendarURL, "ctag", ctag)
+		return false, nil, nil
+	}
+
+	ms, err := p.davRequest(ctx, "PROPFIND", calendarURL, "1", propfindETagBody)
+	if err != nil {
+		return false, nil, err
+	}
+
+	listing := make(map[string]string)
+	for _, resp := range ms.Responses {
+		href := ms.resolve(resp.Href)
+		prop := resp.prop()
+		if prop == nil || prop.ResourceType.Collection != nil || isCollectionHref(href, calendarURL) {
+			continue
+		}
+		listing[href] = prop.ETag
+	}
+
+	removed := make(map[string]*cachedObject)
+	for href, object := range state.Objects {
+		if _, ok := listing[href]; !ok {
+			removed[href] = object
+			delete(state.Objects, href)
+		}
+	}
+
+	changed, err := p.fetchChanged(ctx, calendarURL, state, listing)
+	if err != nil {
+		restoreObjects(state, removed)
+		return false, nil, err
+	}
+	ctagChanged := state.CTag != ctag
+	state.CTag = ctag
+
+	p.logger.Debug("Compared CalDAV calendar ETags",
+		"url", calendarURL,
+		"objects", len(listing),
+		"changed", changed,
+		"removed", len(removed))
+
+	return changed > 0 || len(removed) > 0 || ctagChanged, removed, nil
+}
+
+// fetchChanged downloads the objects of listing whose ETag differs from the
+// cached copy and returns how many were stored
+func (p *SimpleProvider) fetchChanged(ctx context.Context, calendarURL string, state *collectionState, listing map[string]string) (int, error) {
+	var hrefs []string
+	for href, etag := range listing {
+		if cached, ok := state.Objects[href]; !ok || etag == "" || cached.ETag != etag {
+			hrefs = append(hrefs, href)
+		}
+	}
+	sort.Strings(hrefs)
+
+	stored := 0
+	for start := 0; start < len(hrefs); start += multigetBatchSize {
+		end := start + multigetBatchSize
+		if end > len(hrefs) {
+			end = len(hrefs)
+		}
+
+		var body strings.Builder
+		for _, href := range hrefs[start:end] {
+			fmt.Fprintf(&body, "  <d:href>%s</d:href>\n", xmlEscape(hrefPath(href)))
+		}
+
+		ms, err := p.davRequest(ctx, "REPORT", calendarURL, "1", fmt.Sprintf(calendarMultigetBody, body.String()))
+		if err != nil {
+			return stored, fmt.Errorf("calendar-multiget failed: %v", err)
+		}
+
+		for _, resp := range ms.Responses {
+			href := ms.resolve(resp.Href)
+			prop := resp.prop()
+			if prop == nil || prop.CalendarData == "" {
+				continue
+			}
+			etag := prop.ETag
+			if etag == "" {
+				etag = listing[href]
+			}
+			state.Objects[href] = &cachedObject{ETag: etag, Data: prop.CalendarData}
+			stored++
+		}
+	}
+
+	return stored, nil
+}
+
+// TakeRemovedEvents returns the IDs of events whose objects were deleted on
+// the server since the previous call
+func (p *SimpleProvider) TakeRemovedEvents() []string {
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	removed := p.removed
+	p.removed = nil
+	return removed
+}
+
+// loadCache reads the collection copies saved by saveCache. A missing file is
+// not an error.
+func (p *SimpleProvider) loadCache() error {
+	if p.cacheFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(p.cacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CalDAV cache: %v", err)
+	}
+
+	var collections map[string]*collectionState
+	if err := json.Unmarshal(data, &collections); err != nil {
+		return fmt.Errorf("failed to parse CalDAV cache %s: %v", p.cacheFile, err)
+	}
+	for _, state := range collections {
+		if state.Objects == nil {
+			state.Objects = make(map[string]*cachedObject)
+		}
+	}
+
+	p.collections = collections
+	return nil
+}
+
+// saveCache writes the collection copies so a restart resumes from the stored
+// sync tokens. Failures are logged; the next successful sync retries.
+func (p *SimpleProvider) saveCache() {
+	if p.cacheFile == "" {
+		return
+	}
+
+	data, err := json.Marshal(p.collections)
+	if err != nil {
+		p.logger.Warn("Failed to encode CalDAV cache", "error", err)
+		return
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated cache
+	tmp, err := os.CreateTemp(filepath.Dir(p.cacheFile), filepath.Base(p.cacheFile)+".tmp*")
+	if err != nil {
+		p.logger.Warn("Failed to write CalDAV cache", "path", p.cacheFile, "error", err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p.cacheFile)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		p.logger.Warn("Failed to write CalDAV cache", "path", p.cacheFile, "error", err)
+	}
+}
+
+// isClientError reports whether a WebDAV request failed with a 4xx status other
+// than an authentication failure, i.e. the server refused what was asked
+func isClientError(err error) bool {
+	var httpErr *retry.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return httpErr.StatusCode >= 400 && httpErr.StatusCode < 500
+}
+
+// isCollectionHref reports whether href is the collection itself rather than one
+// of its objects
+func isCollectionHref(href, calendarURL string) bool {
+	return strings.TrimSuffix(href, "/") == strings.TrimSuffix(calendarURL, "/")
+}
+
+// hrefPath returns the escaped path of an absolute href, as multiget expects
+func hrefPath(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.EscapedPath()
+}
+
+// restoreObjects puts back objects removed from state during a sync that failed,
+// so the next attempt reports their deletion again
+func restoreObjects(state *collectionState, removed map[string]*cachedObject) {
+	for href, object := range removed {
+		state.Objects[href] = object
+	}
+}
+
+func sortedHrefs(objects map[string]*cachedObject) []string {
+	hrefs := make([]string, 0, len(objects))
+	for href := range objects {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+	return hrefs
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,259 @@
+package caldav
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+const syncCalendarPath = fakeHomePath + "work/"
+
+func syncEvent(uid, start string) string {
+	return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//Test//EN\nBEGIN:VEVENT\nUID:" + uid +
+		"\nDTSTART:" + start + "\nDURATION:PT1H\nSUMMARY:" + uid + "\nEND:VEVENT\nEND:VCALENDAR"
+}
+
+func newSyncTestServer(t *testing.T, noSync bool) *fakeCalDAVServer {
+	return newFakeCalDAVServer(t, &fakeCalendar{
+		path:   syncCalendarPath,
+		name:   "Work",
+		noSync: noSync,
+		objects: map[string]string{
+			"planning.ics": syncEvent("Planning", "20251001T090000Z"),
+			"review.ics":   syncEvent("Review", "20251001T140000Z"),
+		},
+	})
+}
+
+func newSyncTestProvider(t *testing.T, server *fakeCalDAVServer, cacheFile string) *SimpleProvider {
+	t.Helper()
+	provider := NewSimpleProvider()
+	config := &Config{URL: server.URL + syncCalendarPath, Username: "user", Password: "secret", Sync: SyncIncremental, CacheFile: cacheFile}
+	if err := provider.InitializeWithConfig(config); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	return provider
+}
+
+// syncEvents polls the test calendar for October 1st 2025 and returns the titles
+func syncEvents(t *testing.T, provider *SimpleProvider, server *fakeCalDAVServer) ([]string, []*models.Event) {
+	t.Helper()
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC)
+
+	events, err := provider.GetEvents(context.Background(), []string{server.URL + syncCalendarPath}, from, to)
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	var titles []string
+	for _, event := range events {
+		titles = append(titles, event.Title)
+	}
+	sort.Strings(titles)
+	return titles, events
+}
+
+func TestSimpleProvider_IncrementalSync(t *testing.T) {
+	server := newSyncTestServer(t, false)
+	provider := newSyncTestProvider(t, server, "")
+
+	titles, events := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review"}) {
+		t.Fatalf("Expected Planning and Review, got %v", titles)
+	}
+	if fetched := server.fetchLog(); len(fetched) != 2 {
+		t.Errorf("Expected both objects fetched on the first sync, got %v", fetched)
+	}
+
+	var planningID string
+	for _, event := range events {
+		if event.Title == "Planning" {
+			planningID = event.ID
+		}
+	}
+
+	server.deleteObject(syncCalendarPath, "planning.ics")
+	server.putObject(syncCalendarPath, "retro.ics", syncEvent("Retro", "20251001T160000Z"))
+
+	titles, _ = syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Retro", "Review"}) {
+		t.Errorf("Expected Retro and Review after the changes, got %v", titles)
+	}
+
+	// Only the new object is downloaded; the unchanged one comes from the copy
+	fetched := server.fetchLog()
+	if !reflect.DeepEqual(fetched[2:], []string{syncCalendarPath + "retro.ics"}) {
+		t.Errorf("Expected only retro.ics fetched, got %v", fetched[2:])
+	}
+
+	if removed := provider.TakeRemovedEvents(); !reflect.DeepEqual(removed, []string{planningID}) {
+		t.Errorf("TakeRemovedEvents() = %v, expected [%s]", removed, planningID)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("TakeRemovedEvents() should drain the list, got %v", removed)
+	}
+
+	// Nothing changed: one sync-collection REPORT and no downloads
+	requests := len(server.requestLog())
+	syncEvents(t, provider, server)
+	if len(server.fetchLog()) != len(fetched) {
+		t.Errorf("Expected no downloads without changes, got %v", server.fetchLog())
+	}
+	if n := len(server.requestLog()) - requests; n != 1 {
+		t.Errorf("Expected a single request without changes, got %d", n)
+	}
+}
+
+func TestSimpleProvider_SyncParsesChangedObjectsOnly(t *testing.T) {
+	server := newSyncTestServer(t, false)
+	provider := newSyncTestProvider(t, server, "")
+	calendarURL := server.URL + syncCalendarPath
+
+	parsed := func(name string) *ics.Calendar {
+		object := provider.collections[calendarURL].Objects[calendarURL+name]
+		if object == nil {
+			t.Fatalf("Expected %s in the local copy", name)
+		}
+		return object.calendar
+	}
+
+	syncEvents(t, provider, server)
+	planning, review := parsed("planning.ics"), parsed("review.ics")
+	if planning == nil || review == nil {
+		t.Fatal("Expected both objects to be parsed")
+	}
+
+	server.putObject(syncCalendarPath, "review.ics", syncEvent("Review moved", "20251001T150000Z"))
+	titles, _ := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review moved"}) {
+		t.Fatalf("Expected Planning and the updated Review, got %v", titles)
+	}
+
+	if parsed("planning.ics") != planning {
+		t.Error("Expected the unchanged object to keep its parsed calendar")
+	}
+	if parsed("review.ics") == review {
+		t.Error("Expected the changed object to be parsed again")
+	}
+}
+
+func TestSimpleProvider_SyncFallsBackToETags(t *testing.T) {
+	server := newSyncTestServer(t, true)
+	provider := newSyncTestProvider(t, server, "")
+
+	titles, events := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review"}) {
+		t.Fatalf("Expected Planning and Review, got %v", titles)
+	}
+
+	// An unchanged getctag skips the listing, and sync-collection is not retried
+	requests := len(server.requestLog())
+	syncEvents(t, provider, server)
+	if log := server.requestLog()[requests:]; !reflect.DeepEqual(log, []string{"PROPFIND " + syncCalendarPath}) {
+		t.Errorf("Expected only a getctag PROPFIND, got %v", log)
+	}
+
+	server.putObject(syncCalendarPath, "review.ics", syncEvent("Review moved", "20251001T150000Z"))
+	server.deleteObject(syncCalendarPath, "planning.ics")
+
+	titles, _ = syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Review moved"}) {
+		t.Errorf("Expected the updated Review only, got %v", titles)
+	}
+	if fetched := server.fetchLog(); !reflect.DeepEqual(fetched[2:], []string{syncCalendarPath + "review.ics"}) {
+		t.Errorf("Expected only review.ics fetched again, got %v", fetched[2:])
+	}
+
+	removed := provider.TakeRemovedEvents()
+	if len(removed) != 1 || removed[0] != events[0].ID {
+		t.Errorf("TakeRemovedEvents() = %v, expected [%s]", removed, events[0].ID)
+	}
+}
+
+func TestSimpleProvider_SyncTokenRejected(t *testing.T) {
+	server := newSyncTestServer(t, false)
+	provider := newSyncTestProvider(t, server, "")
+
+	syncEvents(t, provider, server)
+
+	server.expireSyncTokens(syncCalendarPath)
+	server.putObject(syncCalendarPath, "review.ics", syncEvent("Review moved", "20251001T150000Z"))
+
+	titles, _ := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review moved"}) {
+		t.Errorf("Expected Planning and the updated Review, got %v", titles)
+	}
+
+	// The full listing only downloads objects whose ETag changed
+	if fetched := server.fetchLog(); !reflect.DeepEqual(fetched[2:], []string{syncCalendarPath + "review.ics"}) {
+		t.Errorf("Expected only review.ics fetched again, got %v", fetched[2:])
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected no removed events after a resync, got %v", removed)
+	}
+}
+
+func TestSimpleProvider_SyncTruncatedReply(t *testing.T) {
+	server := newSyncTestServer(t, false)
+	server.calendar(syncCalendarPath).syncLimit = 1
+	provider := newSyncTestProvider(t, server, "")
+
+	// The first listing comes in two replies, the first cut short with a 507
+	titles, _ := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review"}) {
+		t.Fatalf("Expected Planning and Review, got %v", titles)
+	}
+	reports := 0
+	for _, request := range server.requestLog() {
+		if strings.HasPrefix(request, "REPORT ") {
+			reports++
+		}
+	}
+	if reports != 4 {
+		t.Errorf("Expected two sync-collection REPORTs and a multiget per reply, got %v", server.requestLog())
+	}
+
+	// A truncated full listing after the token expired keeps the objects the
+	// first reply left out
+	server.expireSyncTokens(syncCalendarPath)
+	titles, _ = syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Planning", "Review"}) {
+		t.Errorf("Expected Planning and Review after the resync, got %v", titles)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected no removed events after a truncated resync, got %v", removed)
+	}
+	if token := provider.collections[server.URL+syncCalendarPath].SyncToken; token != "2" {
+		t.Errorf("Expected the sync token of the last reply to be stored, got %q", token)
+	}
+}
+
+func TestSimpleProvider_SyncCacheFile(t *testing.T) {
+	server := newSyncTestServer(t, false)
+	cacheFile := filepath.Join(t.TempDir(), "caldav-cache.json")
+
+	syncEvents(t, newSyncTestProvider(t, server, cacheFile), server)
+
+	server.deleteObject(syncCalendarPath, "planning.ics")
+
+	// A new provider resumes from the stored sync token
+	provider := newSyncTestProvider(t, server, cacheFile)
+	titles, _ := syncEvents(t, provider, server)
+	if !reflect.DeepEqual(titles, []string{"Review"}) {
+		t.Errorf("Expected Review only, got %v", titles)
+	}
+	if fetched := server.fetchLog(); len(fetched) != 2 {
+		t.Errorf("Expected no downloads after restarting, got %v", fetched)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 1 {
+		t.Errorf("Expected the deleted Planning event reported after restarting, got %v", removed)
+	}
+}
@@ -0,0 +1,186 @@
+package caldav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/venkytv/calendar-notifier/pkg/retry"
+)
+
+// maxRedirects bounds the redirects followed for a single WebDAV request
+const maxRedirects = 5
+
+// multistatus is a WebDAV 207 Multi-Status response body (RFC 4918 section 14.16)
+type multistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"DAV: response"`
+	SyncToken string        `xml:"DAV: sync-token"` // Only in sync-collection replies
+
+	base *url.URL // URL the response came from, for resolving hrefs
+}
+
+// davResponse describes one resource in a multistatus body
+type davResponse struct {
+	Href     string        `xml:"DAV: href"`
+	Status   string        `xml:"DAV: status"`
+	Propstat []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+// davProp holds every property this package asks for; unrequested ones stay empty
+type davProp struct {
+	ResourceType         davResourceType `xml:"DAV: resourcetype"`
+	DisplayName          string          `xml:"DAV: displayname"`
+	CurrentUserPrincipal davHref         `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      davHref         `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	CalendarDescription  string          `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+	SupportedComponents  davComponentSet `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	CalendarColor        string          `xml:"http://apple.com/ns/ical/ calendar-color"`
+	ETag                 string          `xml:"DAV: getetag"`
+	CTag                 string          `xml:"http://calendarserver.org/ns/ getctag"`
+	CalendarData         string          `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"DAV: collection"`
+	Calendar   *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+type davComponentSet struct {
+	Components []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+}
+
+// prop returns the properties the server reported with a 200 status, or nil
+func (r *davResponse) prop() *davProp {
+	for i := range r.Propstat {
+		if statusOK(r.Propstat[i].Status) {
+			return &r.Propstat[i].Prop
+		}
+	}
+	return nil
+}
+
+// notFound reports whether the response marks a deleted or missing resource
+func (r *davResponse) notFound() bool {
+	fields := strings.Fields(r.Status)
+	return len(fields) >= 2 && fields[1] == "404"
+}
+
+// truncated reports whether the response marks a reply the server cut short,
+// as a 507 on the request URI of a sync-collection REPORT (RFC 6578 section 3.6)
+func (r *davResponse) truncated() bool {
+	fields := strings.Fields(r.Status)
+	return len(fields) >= 2 && fields[1] == "507"
+}
+
+// resolve turns an href from the response into an absolute URL
+func (m *multistatus) resolve(href string) string {
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || m.base == nil {
+		return href
+	}
+	return m.base.ResolveReference(ref).String()
+}
+
+// statusOK reports whether a WebDAV status line such as "HTTP/1.1 200 OK" is a 2xx
+func statusOK(status string) bool {
+	fields := strings.Fields(status)
+	return len(fields) >= 2 && strings.HasPrefix(fields[1], "2")
+}
+
+// davRequest sends a WebDAV request with an XML body and decodes the multistatus
+// reply. Redirects are followed with the original method, which net/http would
+// otherwise turn into a GET.
+func (p *SimpleProvider) davRequest(ctx context.Context, method, target, depth, body string) (*multistatus, error) {
+	operation := func() (interface{}, error) {
+		current := target
+		for redirects := 0; ; redirects++ {
+			req, err := http.NewRequestWithContext(ctx, method, current, strings.NewReader(body))
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+			req.Header.Set("Accept", "application/xml, text/xml")
+			req.Header.Set("Depth", depth)
+			req.Header.Set("User-Agent", "calendar-notifier/1.0")
+
+			p.logger.Debug("Sending CalDAV request", "method", method, "url", current, "depth", depth)
+
+			resp, err := p.noRedirectClient().Do(req)
+			if err != nil {
+				return nil, fmt.Errorf("HTTP request failed: %v", err)
+			}
+
+			if isRedirect(resp.StatusCode) && resp.Header.Get("Location") != "" {
+				resp.Body.Close()
+				if redirects == maxRedirects {
+					return nil, fmt.Errorf("too many redirects for %s", target)
+				}
+				next, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+				if err != nil {
+					return nil, fmt.Errorf("invalid redirect location %q: %v", resp.Header.Get("Location"), err)
+				}
+				current = next.String()
+				continue
+			}
+
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusMultiStatus {
+				return nil, retry.NewHTTPError(resp.StatusCode, resp.Status, current)
+			}
+
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read response body: %v", err)
+			}
+
+			var ms multistatus
+			if err := xml.Unmarshal(data, &ms); err != nil {
+				return nil, fmt.Errorf("invalid multistatus response from %s: %v", current, err)
+			}
+			ms.base = resp.Request.URL
+			return &ms, nil
+		}
+	}
+
+	result, err := p.retryer.DoWithResult(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*multistatus), nil
+}
+
+// noRedirectClient returns a copy of the HTTP client that hands redirects back
+// to the caller
+func (p *SimpleProvider) noRedirectClient() *http.Client {
+	client := *p.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
@@ -297,19 +297,9 @@ func (c *EventCoordinator) mergeEvents(events []*models.Event) *models.Event {
 	}
 
 	// Use the first event as the base (highest priority due to prior sorting)
-	merged := &models.Event{
-		ID:           events[0].ID,
-		Title:        events[0].Title,
-		Description:  events[0].Description,
-		StartTime:    events[0].StartTime,
-		EndTime:      events[0].EndTime,
-		CalendarID:   events[0].CalendarID,
-		CalendarName: events[0].CalendarName,
-		Location:     events[0].Location,
-		CreatedAt:    events[0].CreatedAt,
-		ModifiedAt:   events[0].ModifiedAt,
-		Alarms:       make([]models.Alarm, 0),
-	}
+	base := *events[0]
+	merged := &base
+	merged.Alarms = make([]models.Alarm, 0)
 
 	// Determine merge strategy
 	strategy := c.config.MergeStrategies["default"]
@@ -329,7 +319,10 @@ func (c *EventCoordinator) mergeEvents(events []*models.Event) *models.Event {
 		alarmMap := make(map[string]models.Alarm)
 		for _, event := range events {
 			for _, alarm := range event.Alarms {
-				key := fmt.Sprintf("%d-%s-%s", alarm.LeadTimeMinutes, alarm.Method, alarm.Severity)
+				key := fmt.Sprintf("%d-%t-%s-%s", alarm.LeadTimeMinutes, alarm.IsRelatedToEnd(), alarm.Method, alarm.Severity)
+				if alarm.IsAbsolute() {
+					key = fmt.Sprintf("%s-%s-%s", alarm.AbsoluteTime.UTC().Format(time.RFC3339), alarm.Method, alarm.Severity)
+				}
 				if _, exists := alarmMap[key]; !exists {
 					alarmMap[key] = alarm
 				}
@@ -357,6 +350,12 @@ func (c *EventCoordinator) mergeEvents(events []*models.Event) *models.Event {
 		if merged.Location == "" && event.Location != "" {
 			merged.Location = event.Location
 		}
+		if merged.JoinURL == "" && event.JoinURL != "" {
+			merged.JoinURL = event.JoinURL
+		}
+		if merged.Organizer == "" && event.Organizer != "" {
+			merged.Organizer = event.Organizer
+		}
 		// Use the latest modification time
 		if event.ModifiedAt.After(merged.ModifiedAt) {
 			merged.ModifiedAt = event.ModifiedAt
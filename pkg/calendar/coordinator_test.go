@@ -124,6 +124,90 @@ func TestDeduplicateEventsByTitle(t *testing.T) {
 	}
 }
 
+func TestMergeEventsKeepsStatus(t *testing.T) {
+	coordinator := NewEventCoordinator(nil, slog.Default())
+
+	now := time.Now()
+	events := []*models.Event{
+		{
+			ID:             "caldav-event1",
+			Title:          "Team Meeting",
+			StartTime:      now.Add(1 * time.Hour),
+			EndTime:        now.Add(2 * time.Hour),
+			CalendarName:   "caldav",
+			ResponseStatus: "tentative",
+			Status:         models.EventStatusTentative,
+			Transparency:   models.TransparencyTransparent,
+		},
+		{
+			ID:           "google-event1",
+			Title:        "Team Meeting",
+			StartTime:    now.Add(1 * time.Hour),
+			EndTime:      now.Add(2 * time.Hour),
+			CalendarName: "google",
+		},
+	}
+
+	merged := coordinator.mergeEvents(events)
+	if merged.Status != models.EventStatusTentative || merged.Transparency != models.TransparencyTransparent {
+		t.Errorf("Expected status and transparency of the base event, got %q and %q", merged.Status, merged.Transparency)
+	}
+	if merged.ResponseStatus != "tentative" {
+		t.Errorf("Expected the response status of the base event, got %q", merged.ResponseStatus)
+	}
+}
+
+func TestMergeEventsKeepsAllDay(t *testing.T) {
+	coordinator := NewEventCoordinator(nil, slog.Default())
+
+	day := time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local)
+	events := []*models.Event{
+		{ID: "caldav-holiday", Title: "Company Holiday", StartTime: day, EndTime: day.AddDate(0, 0, 1), CalendarName: "caldav", AllDay: true},
+		{ID: "google-holiday", Title: "Company Holiday", StartTime: day, EndTime: day.AddDate(0, 0, 1), CalendarName: "google", AllDay: true},
+	}
+
+	if merged := coordinator.mergeEvents(events); !merged.AllDay {
+		t.Error("Expected the merged event to stay an all-day event")
+	}
+}
+
+func TestMergeEventsKeepsGoogleDetails(t *testing.T) {
+	coordinator := NewEventCoordinator(nil, slog.Default())
+
+	now := time.Now()
+	events := []*models.Event{
+		{
+			ID:           "google-event1",
+			Title:        "Team Meeting",
+			StartTime:    now.Add(1 * time.Hour),
+			EndTime:      now.Add(2 * time.Hour),
+			CalendarName: "google",
+			EventType:    models.EventTypeOutOfOffice,
+			Color:        "#5484ed",
+		},
+		{
+			ID:           "caldav-event1",
+			Title:        "Team Meeting",
+			StartTime:    now.Add(1 * time.Hour),
+			EndTime:      now.Add(2 * time.Hour),
+			CalendarName: "caldav",
+			JoinURL:      "https://meet.google.com/abc-defg-hij",
+			Organizer:    "boss@example.com",
+		},
+	}
+
+	merged := coordinator.mergeEvents(events)
+	if merged.EventType != models.EventTypeOutOfOffice || merged.Color != "#5484ed" {
+		t.Errorf("Expected event type and color of the base event, got %q and %q", merged.EventType, merged.Color)
+	}
+	if merged.JoinURL != events[1].JoinURL || merged.Organizer != events[1].Organizer {
+		t.Errorf("Expected join URL and organizer from the duplicate, got %q and %q", merged.JoinURL, merged.Organizer)
+	}
+	if events[0].JoinURL != "" {
+		t.Error("Expected the source event to be left unchanged")
+	}
+}
+
 func TestDeduplicateEventsWithMergeAlarmsStrategy(t *testing.T) {
 	config := &CoordinatorConfig{
 		DeduplicationEnabled: true,
@@ -0,0 +1,420 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+	calendarPkg "github.com/venkytv/calendar-notifier/pkg/calendar"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/ical"
+)
+
+// changeSettleDelay is how long the watcher waits for a burst of file changes,
+// such as a sync tool rewriting a vdir store, to settle before calling onChange
+const changeSettleDelay = 200 * time.Millisecond
+
+// Provider reads calendars from local .ics files. The path may be a single
+// file, a directory of .ics files, or a vdir store such as those written by
+// vdirsyncer and khal, where each subdirectory is one calendar.
+type Provider struct {
+	name     string
+	path     string
+	location *time.Location
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	calendars []*fileCalendar
+	files     map[string]*parsedFile // Keyed by file path
+	loaded    bool
+
+	// Set by the watcher when anything below path changes. Without a watcher
+	// every poll rescans, re-parsing only files whose size or mtime changed.
+	watcher watcher
+	changed atomic.Bool
+
+	// Called once reported changes have settled, so the calendar is read
+	// again right away rather than at its next poll
+	onChange    func()
+	notifyMu    sync.Mutex
+	notifyTimer *time.Timer
+}
+
+// fileCalendar is one calendar and the files holding its events
+type fileCalendar struct {
+	calendar *calendarPkg.Calendar
+	files    []string
+}
+
+// parsedFile is a parsed .ics file and the metadata it was read at
+type parsedFile struct {
+	modTime  time.Time
+	size     int64
+	calendar *ics.Calendar
+}
+
+// NewProvider creates a new file provider
+func NewProvider() *Provider {
+	return &Provider{
+		name:   "File",
+		logger: slog.Default(),
+		files:  make(map[string]*parsedFile),
+	}
+}
+
+// Name returns the provider name
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Type returns the provider type identifier
+func (p *Provider) Type() string {
+	return "file"
+}
+
+// SetLogger sets the logger for this provider
+func (p *Provider) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		p.logger = logger
+	}
+}
+
+// SetTimeZone sets the IANA time zone used for floating times and all-day dates
+// (defaults to the local time zone)
+func (p *Provider) SetTimeZone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid file calendar time zone %q: %v", name, err)
+	}
+	p.location = loc
+	return nil
+}
+
+// SetOnChange sets a function called when the watcher reports changed files,
+// typically to refresh the provider early (call before Initialize). Without a
+// watcher changes are only noticed at the next poll.
+func (p *Provider) SetOnChange(onChange func()) {
+	p.onChange = onChange
+}
+
+// Initialize sets up the provider to read the .ics file or directory at path
+// and starts watching it for changes
+func (p *Provider) Initialize(ctx context.Context, path string) error {
+	if path == "" {
+		return fmt.Errorf("calendar file path is required")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("calendar path %s is not accessible: %v", path, err)
+	}
+
+	p.path = path
+
+	w, err := newWatcher(p.filesChanged, p.logger)
+	if err != nil {
+		p.logger.Info("File watching unavailable, checking modification times on every poll",
+			"path", path,
+			"reason", err)
+	} else {
+		p.watcher = w
+	}
+
+	p.logger.Info("Initialized file calendar provider", "path", path, "watching", p.watcher != nil)
+	return nil
+}
+
+// filesChanged is called by the watcher. It marks the files for a rescan and
+// calls onChange once no further change arrived for changeSettleDelay.
+func (p *Provider) filesChanged() {
+	p.changed.Store(true)
+	if p.onChange == nil {
+		return
+	}
+
+	p.notifyMu.Lock()
+	defer p.notifyMu.Unlock()
+	if p.notifyTimer == nil {
+		p.notifyTimer = time.AfterFunc(changeSettleDelay, p.onChange)
+	} else {
+		p.notifyTimer.Reset(changeSettleDelay)
+	}
+}
+
+// GetEvents returns the events of the given calendars, or of every calendar if
+// none are given, within the time range
+func (p *Provider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
+	if p.path == "" {
+		return nil, fmt.Errorf("file provider not initialized")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+
+	var events []*models.Event
+	for _, cal := range p.calendars {
+		if len(calendarIDs) > 0 && !contains(calendarIDs, cal.calendar.ID) {
+			continue
+		}
+		for _, path := range cal.files {
+			parsed, ok := p.files[path]
+			if !ok {
+				continue
+			}
+			events = append(events, ical.CalendarEvents(parsed.calendar, cal.calendar.ID, cal.calendar.Name, from, to, "", p.location, p.logger)...)
+		}
+	}
+
+	return events, nil
+}
+
+// GetCalendars returns the calendars found at the configured path
+func (p *Provider) GetCalendars(ctx context.Context) ([]*calendarPkg.Calendar, error) {
+	if p.path == "" {
+		return nil, fmt.Errorf("file provider not initialized")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+
+	calendars := make([]*calendarPkg.Calendar, 0, len(p.calendars))
+	for _, cal := range p.calendars {
+		calendars = append(calendars, cal.calendar)
+	}
+	return calendars, nil
+}
+
+// refresh rescans the path unless the watcher has reported no changes since
+// the previous scan
+func (p *Provider) refresh() error {
+	// Clear the flag before scanning so changes made during the scan are picked
+	// up by the next poll
+	if p.loaded && p.watcher != nil && !p.changed.Swap(false) {
+		return nil
+	}
+
+	calendars, dirs, err := scanPath(p.path)
+	if err != nil {
+		return err
+	}
+
+	if p.watcher != nil {
+		// Directories created since the last scan need their own watch
+		for _, dir := range dirs {
+			if err := p.watcher.add(dir); err != nil {
+				p.logger.Warn("Failed to watch calendar directory", "path", dir, "error", err)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	reparsed := 0
+	for _, cal := range calendars {
+		for _, path := range cal.files {
+			seen[path] = true
+
+			info, err := os.Stat(path)
+			if err != nil {
+				// Removed between the scan and now
+				delete(p.files, path)
+				continue
+			}
+			if cached, ok := p.files[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+				continue
+			}
+
+			parsed, err := parseFile(path, info)
+			if err != nil {
+				p.logger.Warn("Failed to parse calendar file", "path", path, "error", err)
+				delete(p.files, path)
+				continue
+			}
+			p.files[path] = parsed
+			reparsed++
+		}
+	}
+	for path := range p.files {
+		if !seen[path] {
+			delete(p.files, path)
+		}
+	}
+
+	p.calendars = calendars
+	p.loaded = true
+
+	p.logger.Debug("Scanned calendar files",
+		"path", p.path,
+		"calendars", len(calendars),
+		"files", len(p.files),
+		"reparsed", reparsed)
+
+	return nil
+}
+
+// scanPath finds the calendars at path and the directories to watch. A file is
+// one calendar; a directory is one calendar if it holds .ics files, and each
+// subdirectory holding .ics files is another.
+func scanPath(path string) ([]*fileCalendar, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read calendar path: %v", err)
+	}
+
+	if !info.IsDir() {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		cal := &fileCalendar{
+			calendar: newCalendar(path, name, true),
+			files:    []string{path},
+		}
+		// Editors and sync tools replace files by renaming, so the directory
+		// is watched rather than the file
+		return []*fileCalendar{cal}, []string{filepath.Dir(path)}, nil
+	}
+
+	dirs := []string{path}
+	var calendars []*fileCalendar
+
+	if cal, err := scanCollection(path); err != nil {
+		return nil, nil, err
+	} else if cal != nil {
+		calendars = append(calendars, cal)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read calendar directory: %v", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(path, entry.Name())
+		dirs = append(dirs, dir)
+
+		cal, err := scanCollection(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cal != nil {
+			calendars = append(calendars, cal)
+		}
+	}
+
+	if len(calendars) > 0 {
+		calendars[0].calendar.Primary = true
+	}
+	return calendars, dirs, nil
+}
+
+// scanCollection returns the calendar held by the .ics files directly in dir,
+// or nil if there are none. vdir metadata files name and color the calendar.
+func scanCollection(dir string) (*fileCalendar, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar directory: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.EqualFold(filepath.Ext(name), ".ics") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	sort.Strings(files)
+
+	name := readMetadata(dir, "displayname")
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	calendar := newCalendar(dir, name, false)
+	calendar.Color = readMetadata(dir, "color")
+
+	return &fileCalendar{calendar: calendar, files: files}, nil
+}
+
+func newCalendar(id, name string, primary bool) *calendarPkg.Calendar {
+	return &calendarPkg.Calendar{
+		ID:          id,
+		Name:        name,
+		Description: fmt.Sprintf("Calendar from %s", id),
+		Primary:     primary,
+		AccessRole:  "reader",
+	}
+}
+
+// readMetadata returns the contents of a vdir metadata file, or "" if absent
+func readMetadata(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func parseFile(path string, info os.FileInfo) (*parsedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	calendar, err := ics.ParseCalendar(f)
+	if err != nil {
+		return nil, err
+	}
+	return &parsedFile{modTime: info.ModTime(), size: info.Size(), calendar: calendar}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IsHealthy checks that the configured path can still be read
+func (p *Provider) IsHealthy(ctx context.Context) error {
+	if p.path == "" {
+		return fmt.Errorf("file provider not initialized")
+	}
+	if _, err := os.Stat(p.path); err != nil {
+		return fmt.Errorf("file calendar health check failed: %v", err)
+	}
+	return nil
+}
+
+// Close stops watching the calendar files
+func (p *Provider) Close() error {
+	p.notifyMu.Lock()
+	if p.notifyTimer != nil {
+		p.notifyTimer.Stop()
+	}
+	p.notifyMu.Unlock()
+
+	if p.watcher != nil {
+		return p.watcher.close()
+	}
+	return nil
+}
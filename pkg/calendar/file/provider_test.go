@@ -0,0 +1,171 @@
+package file
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func icsEvent(uid, start string) string {
+	return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//Test//EN\nBEGIN:VEVENT\nUID:" + uid +
+		"\nDTSTART:" + start + "\nDURATION:PT1H\nSUMMARY:" + uid + "\nEND:VEVENT\nEND:VCALENDAR\n"
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func newTestProvider(t *testing.T, path string) *Provider {
+	t.Helper()
+	provider := NewProvider()
+	if err := provider.Initialize(context.Background(), path); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	t.Cleanup(func() { provider.Close() })
+	return provider
+}
+
+func eventTitles(t *testing.T, provider *Provider, calendarIDs ...string) []string {
+	t.Helper()
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	events, err := provider.GetEvents(context.Background(), calendarIDs, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	var titles []string
+	for _, event := range events {
+		titles = append(titles, event.Title)
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestProvider_Initialize(t *testing.T) {
+	provider := NewProvider()
+	if err := provider.Initialize(context.Background(), ""); err == nil {
+		t.Error("Expected error for empty path")
+	}
+	if err := provider.Initialize(context.Background(), filepath.Join(t.TempDir(), "missing.ics")); err == nil {
+		t.Error("Expected error for missing path")
+	}
+	if _, err := provider.GetEvents(context.Background(), nil, time.Now(), time.Now()); err == nil {
+		t.Error("Expected error when not initialized")
+	}
+}
+
+func TestProvider_SingleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "personal.ics")
+	writeFile(t, path, icsEvent("Dentist", "20251001T100000Z"))
+
+	provider := newTestProvider(t, path)
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(calendars) != 1 || calendars[0].ID != path || calendars[0].Name != "personal" || !calendars[0].Primary {
+		t.Errorf("Expected the file as the primary calendar 'personal', got %+v", calendars)
+	}
+
+	if titles := eventTitles(t, provider); len(titles) != 1 || titles[0] != "Dentist" {
+		t.Errorf("Expected Dentist, got %v", titles)
+	}
+}
+
+func TestProvider_VdirStore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "work", "standup.ics"), icsEvent("Standup", "20251001T090000Z"))
+	writeFile(t, filepath.Join(root, "work", "review.ics"), icsEvent("Review", "20251001T140000Z"))
+	writeFile(t, filepath.Join(root, "work", "displayname"), "Work\n")
+	writeFile(t, filepath.Join(root, "work", "color"), "#0082C9\n")
+	writeFile(t, filepath.Join(root, "home", "dinner.ics"), icsEvent("Dinner", "20251001T190000Z"))
+	writeFile(t, filepath.Join(root, "home", ".dinner.ics.tmp"), "partial")
+	writeFile(t, filepath.Join(root, "empty", "notes.txt"), "not a calendar")
+
+	provider := newTestProvider(t, root)
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(calendars) != 2 {
+		t.Fatalf("Expected the home and work collections, got %+v", calendars)
+	}
+	work := calendars[1]
+	if work.ID != filepath.Join(root, "work") || work.Name != "Work" || work.Color != "#0082C9" {
+		t.Errorf("Expected Work named and colored from vdir metadata, got %+v", work)
+	}
+	if calendars[0].Name != "home" {
+		t.Errorf("Expected home named after its directory, got %s", calendars[0].Name)
+	}
+
+	titles := eventTitles(t, provider)
+	expected := []string{"Dinner", "Review", "Standup"}
+	if len(titles) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, titles)
+	}
+	for i := range expected {
+		if titles[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, titles)
+		}
+	}
+
+	if titles := eventTitles(t, provider, work.ID); len(titles) != 2 {
+		t.Errorf("Expected only the Work events, got %v", titles)
+	}
+}
+
+func TestProvider_RereadsChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "standup.ics"), icsEvent("Standup", "20251001T090000Z"))
+	writeFile(t, filepath.Join(root, "broken.ics"), "BEGIN:VCALENDAR\nnot ical")
+
+	provider := newTestProvider(t, root)
+
+	if titles := eventTitles(t, provider); len(titles) != 1 || titles[0] != "Standup" {
+		t.Fatalf("Expected Standup with the broken file skipped, got %v", titles)
+	}
+
+	writeFile(t, filepath.Join(root, "standup.ics"), icsEvent("Standup moved", "20251001T100000Z"))
+	writeFile(t, filepath.Join(root, "retro.ics"), icsEvent("Retro", "20251001T160000Z"))
+	os.Remove(filepath.Join(root, "broken.ics"))
+
+	// With a watcher the change is picked up once it has been reported
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		titles := eventTitles(t, provider)
+		if len(titles) == 2 && titles[0] == "Retro" && titles[1] == "Standup moved" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Retro and the moved Standup, got %v", titles)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProvider_IsHealthy(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "calendars")
+	writeFile(t, filepath.Join(dir, "a.ics"), icsEvent("A", "20251001T090000Z"))
+
+	provider := newTestProvider(t, dir)
+	if err := provider.IsHealthy(context.Background()); err != nil {
+		t.Errorf("IsHealthy() unexpected error: %v", err)
+	}
+
+	os.RemoveAll(dir)
+	if err := provider.IsHealthy(context.Background()); err == nil {
+		t.Error("Expected error once the path is gone")
+	}
+}
@@ -0,0 +1,10 @@
+package file
+
+// watcher reports changes below the directories added to it
+type watcher interface {
+	// add starts watching a directory; adding it again is harmless
+	add(dir string) error
+
+	// close stops watching
+	close() error
+}
@@ -0,0 +1,102 @@
+package file
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask selects the events that can change a calendar: files written,
+// created, removed or renamed, and the directory itself going away
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_MODIFY |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher calls onChange whenever inotify reports an event in a watched
+// directory. Events are not inspected: the provider rescans and only re-parses
+// files whose size or modification time changed.
+type inotifyWatcher struct {
+	file     *os.File
+	fd       int
+	onChange func()
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+// newWatcher starts an inotify watcher
+func newWatcher(onChange func(), logger *slog.Logger) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %v", err)
+	}
+
+	w := &inotifyWatcher{
+		// A non-blocking descriptor is served by the runtime poller, so close
+		// interrupts the pending read
+		file:     os.NewFile(uintptr(fd), "inotify"),
+		fd:       fd,
+		onChange: onChange,
+		logger:   logger,
+		watched:  make(map[string]bool),
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *inotifyWatcher) add(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.watched[dir] {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask); err != nil {
+		return fmt.Errorf("failed to watch %s: %v", dir, err)
+	}
+	w.watched[dir] = true
+	return nil
+}
+
+func (w *inotifyWatcher) run() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			// The descriptor was closed
+			return
+		}
+		if n < syscall.SizeofInotifyEvent {
+			continue
+		}
+
+		// A removed directory loses its watch. The event only names the watch
+		// descriptor, so all directories are forgotten and the next rescan adds
+		// back those that exist, including one recreated under the same name.
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				w.forget()
+			}
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+		}
+
+		w.logger.Debug("Calendar files changed", "bytes", n)
+		w.onChange()
+	}
+}
+
+// forget makes add register every directory again
+func (w *inotifyWatcher) forget() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.watched = make(map[string]bool)
+}
+
+func (w *inotifyWatcher) close() error {
+	return w.file.Close()
+}
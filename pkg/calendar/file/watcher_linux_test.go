@@ -0,0 +1,102 @@
+package file
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInotifyWatcher(t *testing.T) {
+	dir := t.TempDir()
+	changes := make(chan struct{}, 16)
+
+	w, err := newWatcher(func() { changes <- struct{}{} }, slog.Default())
+	if err != nil {
+		t.Fatalf("newWatcher() unexpected error: %v", err)
+	}
+	defer w.close()
+
+	if err := w.add(dir); err != nil {
+		t.Fatalf("add() unexpected error: %v", err)
+	}
+	if err := w.add(dir); err != nil {
+		t.Errorf("add() of a watched directory unexpected error: %v", err)
+	}
+
+	// Sync tools write a temporary file and rename it into place
+	tmp := filepath.Join(dir, ".event.ics.tmp")
+	os.WriteFile(tmp, []byte("BEGIN:VCALENDAR\nEND:VCALENDAR\n"), 0644)
+	os.Rename(tmp, filepath.Join(dir, "event.ics"))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a change notification")
+	}
+
+	if err := w.close(); err != nil {
+		t.Errorf("close() unexpected error: %v", err)
+	}
+}
+
+func TestProvider_SkipsRescanWithoutChanges(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "standup.ics")
+	writeFile(t, path, icsEvent("Standup", "20251001T090000Z"))
+
+	provider := newTestProvider(t, root)
+	if provider.watcher == nil {
+		t.Skip("inotify unavailable")
+	}
+	eventTitles(t, provider)
+
+	// Without a reported change the cached calendar is used as-is
+	provider.files[path].calendar = nil
+	provider.changed.Store(false)
+	if err := provider.refresh(); err != nil {
+		t.Fatalf("refresh() unexpected error: %v", err)
+	}
+	if provider.files[path].calendar != nil {
+		t.Error("Expected no rescan without a change notification")
+	}
+}
+
+func TestProvider_NotifiesChanges(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "standup.ics")
+	writeFile(t, path, icsEvent("Standup", "20251001T090000Z"))
+
+	changes := make(chan struct{}, 16)
+	provider := NewProvider()
+	provider.SetOnChange(func() { changes <- struct{}{} })
+	if err := provider.Initialize(context.Background(), root); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+	defer provider.Close()
+	if provider.watcher == nil {
+		t.Skip("inotify unavailable")
+	}
+	eventTitles(t, provider)
+
+	// A burst of writes is reported once it has settled
+	for _, title := range []string{"Standup moved", "Daily Standup"} {
+		writeFile(t, path, icsEvent(title, "20251001T100000Z"))
+	}
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the change to be reported")
+	}
+	select {
+	case <-changes:
+		t.Error("Expected a burst of changes to be reported once")
+	case <-time.After(2 * changeSettleDelay):
+	}
+
+	if titles := eventTitles(t, provider); len(titles) != 1 || titles[0] != "Daily Standup" {
+		t.Errorf("Expected the rewritten event, got %v", titles)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package file
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// newWatcher reports that change notification is unavailable on this platform,
+// so the provider falls back to checking modification times
+func newWatcher(onChange func(), logger *slog.Logger) (watcher, error) {
+	return nil, errors.New("inotify is only available on Linux")
+}
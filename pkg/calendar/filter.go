@@ -0,0 +1,45 @@
+package calendar
+
+import (
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// EventFilter decides which cancelled, free, focus time and working location
+// events of a calendar still get notifications. The zero value drops cancelled
+// events only, as the providers always did; skipping the others is opt-in.
+type EventFilter struct {
+	NotifyCancelled     bool // Keep events whose status is cancelled
+	SkipTransparent     bool // Drop events marked transparent or free
+	SkipFocusTime       bool // Drop Google focus time blocks
+	SkipWorkingLocation bool // Drop Google working location entries
+}
+
+// Allows returns true if notifications should be sent for the event. Focus
+// time and working location entries are decided by their own setting alone,
+// as Google marks working locations free.
+func (f EventFilter) Allows(event *models.Event) bool {
+	if event.IsCancelled() && !f.NotifyCancelled {
+		return false
+	}
+	if event.IsFocusTime() {
+		return !f.SkipFocusTime
+	}
+	if event.IsWorkingLocation() {
+		return !f.SkipWorkingLocation
+	}
+	if event.IsTransparent() && f.SkipTransparent {
+		return false
+	}
+	return true
+}
+
+// Apply returns the events allowed by the filter
+func (f EventFilter) Apply(events []*models.Event) []*models.Event {
+	filtered := make([]*models.Event, 0, len(events))
+	for _, event := range events {
+		if f.Allows(event) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+func TestEventFilterAllows(t *testing.T) {
+	busy := &models.Event{ID: "busy", Status: models.EventStatusConfirmed, Transparency: models.TransparencyOpaque}
+	cancelled := &models.Event{ID: "cancelled", Status: models.EventStatusCancelled}
+	free := &models.Event{ID: "free", Transparency: models.TransparencyTransparent}
+	unknown := &models.Event{ID: "unknown"}
+	focus := &models.Event{ID: "focus", EventType: models.EventTypeFocusTime}
+	office := &models.Event{ID: "office", EventType: models.EventTypeWorkingLocation, Transparency: models.TransparencyTransparent}
+	away := &models.Event{ID: "away", EventType: models.EventTypeOutOfOffice}
+
+	tests := []struct {
+		name     string
+		filter   EventFilter
+		expected map[*models.Event]bool
+	}{
+		{
+			name:     "default drops cancelled only",
+			filter:   EventFilter{},
+			expected: map[*models.Event]bool{busy: true, cancelled: false, free: true, unknown: true, focus: true, office: true, away: true},
+		},
+		{
+			name:     "notify cancelled",
+			filter:   EventFilter{NotifyCancelled: true},
+			expected: map[*models.Event]bool{busy: true, cancelled: true, free: true, unknown: true},
+		},
+		{
+			name:     "skip focus time",
+			filter:   EventFilter{SkipFocusTime: true},
+			expected: map[*models.Event]bool{busy: true, focus: false, office: true},
+		},
+		{
+			name:     "skip working location",
+			filter:   EventFilter{SkipWorkingLocation: true},
+			expected: map[*models.Event]bool{office: false, focus: true, free: true},
+		},
+		{
+			name:     "skip free keeps working location although marked free",
+			filter:   EventFilter{SkipTransparent: true},
+			expected: map[*models.Event]bool{office: true, free: false, busy: true},
+		},
+		{
+			name:     "skip everything",
+			filter:   EventFilter{SkipTransparent: true, SkipFocusTime: true, SkipWorkingLocation: true},
+			expected: map[*models.Event]bool{busy: true, cancelled: false, free: false, unknown: true, focus: false, office: false, away: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for event, expected := range tt.expected {
+				if result := tt.filter.Allows(event); result != expected {
+					t.Errorf("Allows(%s) = %v, expected %v", event.ID, result, expected)
+				}
+			}
+		})
+	}
+}
@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
+	googleoauth "golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
 )
 
 const (
 	// OAuth2 scopes required for read-only calendar access
 	calendarReadOnlyScope = calendar.CalendarReadonlyScope
+
+	// serviceAccountType is the "type" of a service account key file
+	serviceAccountType = "service_account"
 )
 
 // OAuth2Config holds the OAuth2 configuration
@@ -33,6 +40,59 @@ type CredentialsFile struct {
 	Web       OAuth2Config `json:"web"`
 }
 
+// DefaultTokenFile returns where the token of an OAuth2 client is stored when
+// no token file is configured: next to the credentials, with a .token suffix
+func DefaultTokenFile(credentialsPath string) string {
+	return credentialsPath + ".token"
+}
+
+// IsServiceAccountKey reports whether the credentials file at path holds a
+// service account key rather than OAuth2 client credentials
+func IsServiceAccountKey(credentialsPath string) (bool, error) {
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var creds struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return false, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+
+	return creds.Type == serviceAccountType, nil
+}
+
+// NewServiceAccountClient returns an HTTP client authenticated as the service
+// account whose JSON key is at credentialsPath. Tokens are obtained and renewed
+// from the key alone, so no browser flow or token file is involved. If subject
+// is set the service account acts as that user, which requires domain-wide
+// delegation of the calendar scope in the Workspace admin console.
+func NewServiceAccountClient(ctx context.Context, credentialsPath, subject string) (*http.Client, error) {
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	config, err := googleoauth.JWTConfigFromJSON(data, calendarReadOnlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	config.Subject = subject
+
+	// Fail early on a bad key or a missing delegation grant
+	tokenSource := config.TokenSource(ctx)
+	if _, err := tokenSource.Token(); err != nil {
+		if subject != "" {
+			return nil, fmt.Errorf("failed to get token for %s as %s (is domain-wide delegation granted?): %w", config.Email, subject, err)
+		}
+		return nil, fmt.Errorf("failed to get token for %s: %w", config.Email, err)
+	}
+
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
 // TokenManager handles OAuth2 token management including refresh
 type TokenManager struct {
 	config    *oauth2.Config
@@ -93,8 +153,9 @@ func loadOAuth2Config(credentialsPath string) (*oauth2.Config, error) {
 		RedirectURL:  redirectURI,
 		Scopes:       []string{calendarReadOnlyScope},
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  oauthConfig.AuthURI,
-			TokenURL: oauthConfig.TokenURI,
+			AuthURL:       oauthConfig.AuthURI,
+			TokenURL:      oauthConfig.TokenURI,
+			DeviceAuthURL: googleoauth.Endpoint.DeviceAuthURL,
 		},
 	}, nil
 }
@@ -108,8 +169,11 @@ func (tm *TokenManager) GetAuthURL() string {
 		oauth2.SetAuthURLParam("prompt", "consent"))
 }
 
-// ExchangeCode exchanges an authorization code for a token and saves it
+// ExchangeCode exchanges an authorization code for a token and saves it.
+// The code may also be given as the whole URL the browser was redirected to.
 func (tm *TokenManager) ExchangeCode(ctx context.Context, code string) (*oauth2.Token, error) {
+	code = authCodeFromInput(code)
+
 	token, err := tm.config.Exchange(ctx, code)
 	if err != nil {
 		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
@@ -123,29 +187,109 @@ func (tm *TokenManager) ExchangeCode(ctx context.Context, code string) (*oauth2.
 	return token, nil
 }
 
+// authCodeFromInput returns the code parameter of a pasted redirect URL, or
+// the input itself if it is not one
+func authCodeFromInput(input string) string {
+	input = strings.TrimSpace(input)
+	if u, err := url.Parse(input); err == nil && u.Scheme != "" {
+		if code := u.Query().Get("code"); code != "" {
+			return code
+		}
+	}
+	return input
+}
+
+// AuthorizeDevice runs the OAuth2 device authorization flow for machines
+// without a browser: prompt is given the code the user enters at the
+// verification URL on another device, after which the server is polled until
+// access is granted or the code expires. The token is saved on success. The
+// OAuth2 client must be of type "TVs and Limited Input devices".
+func (tm *TokenManager) AuthorizeDevice(ctx context.Context, prompt func(*oauth2.DeviceAuthResponse)) (*oauth2.Token, error) {
+	auth, err := tm.config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	prompt(auth)
+
+	token, err := tm.config.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	if err := tm.SaveToken(token); err != nil {
+		return nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	tm.logger.Info("successfully obtained and saved OAuth2 token")
+	return token, nil
+}
+
+// storedToken is the token file format: the token and the scopes granted
+// with it, which the token endpoint reports but oauth2.Token does not keep
+type storedToken struct {
+	*oauth2.Token
+	Scope string `json:"scope,omitempty"`
+}
+
 // LoadToken loads a saved token from disk
 func (tm *TokenManager) LoadToken() (*oauth2.Token, error) {
+	stored, err := tm.loadStoredToken()
+	if err != nil {
+		return nil, err
+	}
+	return stored.Token, nil
+}
+
+func (tm *TokenManager) loadStoredToken() (*storedToken, error) {
 	data, err := os.ReadFile(tm.tokenFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read token file: %w", err)
 	}
 
-	var token oauth2.Token
-	if err := json.Unmarshal(data, &token); err != nil {
+	stored := &storedToken{Token: &oauth2.Token{}}
+	if err := json.Unmarshal(data, stored); err != nil {
 		return nil, fmt.Errorf("failed to parse token file: %w", err)
 	}
 
-	return &token, nil
+	return stored, nil
 }
 
-// SaveToken saves a token to disk
+// SaveToken saves a token to disk, readable only by the owner. The file is
+// replaced atomically so a crash cannot leave a truncated token behind.
 func (tm *TokenManager) SaveToken(token *oauth2.Token) error {
-	data, err := json.MarshalIndent(token, "", "  ")
+	stored := &storedToken{Token: token}
+	if scope, ok := token.Extra("scope").(string); ok {
+		stored.Scope = scope
+	} else if previous, err := tm.loadStoredToken(); err == nil {
+		// Refresh responses may leave out unchanged scopes
+		stored.Scope = previous.Scope
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
-	if err := os.WriteFile(tm.tokenFile, data, 0600); err != nil {
+	tmp, err := os.CreateTemp(filepath.Dir(tm.tokenFile), filepath.Base(tm.tokenFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// CreateTemp already uses 0600; this keeps it explicit
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), tm.tokenFile); err != nil {
 		return fmt.Errorf("failed to write token file: %w", err)
 	}
 
@@ -190,6 +334,27 @@ func (tm *TokenManager) IsTokenValid() bool {
 	return token.Valid() || token.RefreshToken != ""
 }
 
+// TokenInfo describes a stored token
+type TokenInfo struct {
+	Expiry          time.Time
+	HasRefreshToken bool
+	Scopes          []string // Empty if the token was saved without them
+}
+
+// GetTokenInfo returns the expiry, refresh token presence and granted scopes
+// of the stored token
+func (tm *TokenManager) GetTokenInfo() (*TokenInfo, error) {
+	stored, err := tm.loadStoredToken()
+	if err != nil {
+		return nil, err
+	}
+	return &TokenInfo{
+		Expiry:          stored.Expiry,
+		HasRefreshToken: stored.RefreshToken != "",
+		Scopes:          strings.Fields(stored.Scope),
+	}, nil
+}
+
 // GetTokenExpiry returns the expiry time of the stored token
 func (tm *TokenManager) GetTokenExpiry() (time.Time, error) {
 	token, err := tm.LoadToken()
@@ -0,0 +1,301 @@
+package google
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// writeServiceAccountKey writes a service account key whose tokens come from tokenURL
+func writeServiceAccountKey(t *testing.T, tokenURL string) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	data, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "notifier",
+		"private_key_id": "key1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "notifier@notifier.iam.gserviceaccount.com",
+		"client_id":      "1234",
+		"token_uri":      tokenURL,
+	})
+
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+// jwtClaims decodes the claims of the JWT assertion in a token request
+func jwtClaims(t *testing.T, r *http.Request) map[string]interface{} {
+	t.Helper()
+	if err := r.ParseForm(); err != nil {
+		t.Fatalf("Failed to parse token request: %v", err)
+	}
+	parts := strings.Split(r.PostForm.Get("assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT assertion, got %q", r.PostForm.Get("assertion"))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("Failed to decode JWT claims: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("Failed to parse JWT claims: %v", err)
+	}
+	return claims
+}
+
+func TestIsServiceAccountKey(t *testing.T) {
+	dir := t.TempDir()
+	oauthPath := filepath.Join(dir, "oauth.json")
+	os.WriteFile(oauthPath, []byte(`{"installed":{"client_id":"id","client_secret":"secret"}}`), 0600)
+
+	if ok, err := IsServiceAccountKey(oauthPath); err != nil || ok {
+		t.Errorf("IsServiceAccountKey() = %v, %v for OAuth2 client credentials, want false", ok, err)
+	}
+	if ok, err := IsServiceAccountKey(writeServiceAccountKey(t, "https://oauth2.googleapis.com/token")); err != nil || !ok {
+		t.Errorf("IsServiceAccountKey() = %v, %v for a service account key, want true", ok, err)
+	}
+	if _, err := IsServiceAccountKey(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestNewServiceAccountClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+	}{
+		{"Service account's own calendars", ""},
+		{"Domain-wide delegation", "alice@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims map[string]interface{}
+			tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.PostFormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+					t.Errorf("Unexpected grant type %q", r.PostFormValue("grant_type"))
+				}
+				claims = jwtClaims(t, r)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"service-token","token_type":"Bearer","expires_in":3600}`))
+			}))
+			defer tokenServer.Close()
+
+			var authorization string
+			apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+			}))
+			defer apiServer.Close()
+
+			client, err := NewServiceAccountClient(context.Background(), writeServiceAccountKey(t, tokenServer.URL), tt.subject)
+			if err != nil {
+				t.Fatalf("NewServiceAccountClient() unexpected error: %v", err)
+			}
+
+			if claims["iss"] != "notifier@notifier.iam.gserviceaccount.com" || claims["scope"] != calendarReadOnlyScope {
+				t.Errorf("Unexpected JWT claims %v", claims)
+			}
+			if sub, _ := claims["sub"].(string); sub != tt.subject {
+				t.Errorf("JWT subject = %q, want %q", sub, tt.subject)
+			}
+
+			resp, err := client.Get(apiServer.URL)
+			if err != nil {
+				t.Fatalf("Request unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if authorization != "Bearer service-token" {
+				t.Errorf("Authorization = %q, want the service account token", authorization)
+			}
+		})
+	}
+}
+
+func TestNewServiceAccountClient_DelegationDenied(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"unauthorized_client","error_description":"Client is unauthorized to retrieve access tokens using this method"}`))
+	}))
+	defer tokenServer.Close()
+
+	_, err := NewServiceAccountClient(context.Background(), writeServiceAccountKey(t, tokenServer.URL), "alice@example.com")
+	if err == nil || !strings.Contains(err.Error(), "domain-wide delegation") {
+		t.Errorf("Expected a delegation error, got %v", err)
+	}
+}
+
+func TestProvider_ImpersonateRequiresServiceAccount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oauth.json")
+	os.WriteFile(path, []byte(`{"installed":{"client_id":"id","client_secret":"secret"}}`), 0600)
+
+	provider := NewProvider()
+	provider.SetImpersonate("alice@example.com")
+	if err := provider.Initialize(context.Background(), path); err == nil || !strings.Contains(err.Error(), "service account") {
+		t.Errorf("Expected error impersonating with OAuth2 client credentials, got %v", err)
+	}
+}
+
+// newTestTokenManager returns a token manager for OAuth2 client credentials
+// whose endpoints are served by server
+func newTestTokenManager(t *testing.T, server *httptest.Server) *TokenManager {
+	t.Helper()
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials.json")
+	data, _ := json.Marshal(map[string]interface{}{
+		"installed": map[string]interface{}{
+			"client_id":     "client",
+			"client_secret": "secret",
+			"auth_uri":      server.URL + "/auth",
+			"token_uri":     server.URL + "/token",
+			"redirect_uris": []string{"http://localhost"},
+		},
+	})
+	if err := os.WriteFile(credentials, data, 0600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+
+	tm, err := NewTokenManager(credentials, DefaultTokenFile(credentials), nil)
+	if err != nil {
+		t.Fatalf("NewTokenManager() unexpected error: %v", err)
+	}
+	tm.config.Endpoint.DeviceAuthURL = server.URL + "/device"
+	return tm
+}
+
+func TestTokenManager_AuthorizeDevice(t *testing.T) {
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("scope") != calendarReadOnlyScope {
+			t.Errorf("Expected the read-only calendar scope, got %q", r.PostFormValue("scope"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"device_code":"device-123","user_code":"ABCD-EFGH","verification_url":"https://www.google.com/device","expires_in":60,"interval":1}`))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("device_code") != "device-123" {
+			t.Errorf("Expected the device code, got %q", r.PostFormValue("device_code"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		polls++
+		if polls == 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3599,"scope":"` + calendarReadOnlyScope + `"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tm := newTestTokenManager(t, server)
+
+	var prompted string
+	token, err := tm.AuthorizeDevice(context.Background(), func(auth *oauth2.DeviceAuthResponse) {
+		prompted = auth.VerificationURI + " " + auth.UserCode
+	})
+	if err != nil {
+		t.Fatalf("AuthorizeDevice() unexpected error: %v", err)
+	}
+	if prompted != "https://www.google.com/device ABCD-EFGH" {
+		t.Errorf("Expected the user to be prompted with the URL and code, got %q", prompted)
+	}
+	if token.RefreshToken != "refresh" || polls != 2 {
+		t.Errorf("Expected a token after polling past authorization_pending, got %+v after %d polls", token, polls)
+	}
+
+	info, err := os.Stat(tm.tokenFile)
+	if err != nil {
+		t.Fatalf("Expected the token file to be written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	tokenInfo, err := tm.GetTokenInfo()
+	if err != nil {
+		t.Fatalf("GetTokenInfo() unexpected error: %v", err)
+	}
+	if !tokenInfo.HasRefreshToken || len(tokenInfo.Scopes) != 1 || tokenInfo.Scopes[0] != calendarReadOnlyScope {
+		t.Errorf("Unexpected token info %+v", tokenInfo)
+	}
+	if tokenInfo.Expiry.Before(time.Now().Add(50 * time.Minute)) {
+		t.Errorf("Expected an expiry about an hour away, got %v", tokenInfo.Expiry)
+	}
+}
+
+func TestTokenManager_SaveTokenKeepsScopes(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	tm := newTestTokenManager(t, server)
+
+	// An existing token file with looser permissions is replaced
+	os.WriteFile(tm.tokenFile, []byte(`{}`), 0644)
+
+	granted := (&oauth2.Token{AccessToken: "first", RefreshToken: "refresh"}).WithExtra(map[string]interface{}{"scope": calendarReadOnlyScope})
+	if err := tm.SaveToken(granted); err != nil {
+		t.Fatalf("SaveToken() unexpected error: %v", err)
+	}
+
+	// Refresh responses may not repeat the scopes
+	if err := tm.SaveToken(&oauth2.Token{AccessToken: "second", RefreshToken: "refresh"}); err != nil {
+		t.Fatalf("SaveToken() unexpected error: %v", err)
+	}
+
+	token, err := tm.LoadToken()
+	if err != nil || token.AccessToken != "second" {
+		t.Fatalf("LoadToken() = %+v, %v, want the second token", token, err)
+	}
+	info, err := tm.GetTokenInfo()
+	if err != nil || len(info.Scopes) != 1 {
+		t.Errorf("Expected the granted scope to be kept, got %+v, %v", info, err)
+	}
+
+	if fi, _ := os.Stat(tm.tokenFile); fi.Mode().Perm() != 0600 {
+		t.Errorf("Token file mode = %v, want 0600", fi.Mode().Perm())
+	}
+}
+
+func TestAuthCodeFromInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"4/0AbCdEf\n", "4/0AbCdEf"},
+		{"http://localhost/?state=state-token&code=4/0AbCdEf&scope=" + calendarReadOnlyScope, "4/0AbCdEf"},
+		{"  4/0AbCdEf  ", "4/0AbCdEf"},
+	}
+
+	for _, tt := range tests {
+		if got := authCodeFromInput(tt.input); got != tt.expected {
+			t.Errorf("authCodeFromInput(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
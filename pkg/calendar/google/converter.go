@@ -9,16 +9,17 @@ import (
 	"github.com/venkytv/calendar-notifier/internal/models"
 )
 
-// convertEvent converts a Google Calendar event to our internal Event model
-func (p *Provider) convertEvent(item *calendar.Event, calendarID string) (*models.Event, error) {
+// convertEvent converts a Google Calendar event to our internal Event model.
+// defaults are the calendar's default reminders, or nil if unknown.
+func (p *Provider) convertEvent(item *calendar.Event, calendarID string, defaults []*calendar.EventReminder) (*models.Event, error) {
 	// Parse start time
-	startTime, err := parseEventTime(item.Start)
+	startTime, err := parseEventTime(item.Start, p.location)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse start time: %w", err)
 	}
 
 	// Parse end time
-	endTime, err := parseEventTime(item.End)
+	endTime, err := parseEventTime(item.End, p.location)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse end time: %w", err)
 	}
@@ -46,7 +47,7 @@ func (p *Provider) convertEvent(item *calendar.Event, calendarID string) (*model
 	}
 
 	// Convert reminders to alarms
-	alarms := p.convertReminders(item)
+	alarms := p.convertReminders(item, defaults)
 
 	// Extract response status from attendees
 	responseStatus := extractResponseStatus(item)
@@ -57,19 +58,65 @@ func (p *Provider) convertEvent(item *calendar.Event, calendarID string) (*model
 		Description:    item.Description,
 		StartTime:      startTime,
 		EndTime:        endTime,
+		AllDay:         item.Start.Date != "",
 		Alarms:         alarms,
 		CalendarID:     calendarID,
 		Location:       item.Location,
 		CreatedAt:      createdAt,
 		ModifiedAt:     modifiedAt,
 		ResponseStatus: responseStatus,
+		Status:         item.Status,
+		Transparency:   item.Transparency,
+		EventType:      item.EventType,
+		JoinURL:        extractJoinURL(item),
+		HTMLLink:       item.HtmlLink,
+		Color:          eventColors[item.ColorId],
+	}
+
+	if item.Organizer != nil {
+		event.Organizer = item.Organizer.Email
+	}
+
+	// Google omits transparency for busy events
+	if event.Transparency == "" {
+		event.Transparency = models.TransparencyOpaque
 	}
 
 	return event, nil
 }
 
-// parseEventTime parses Google Calendar event time (handles both dateTime and date fields)
-func parseEventTime(eventTime *calendar.EventDateTime) (time.Time, error) {
+// eventColors is Google Calendar's fixed event palette, keyed by colorId.
+// Events without a colorId take the calendar's color and get none here.
+var eventColors = map[string]string{
+	"1":  "#a4bdfc",
+	"2":  "#7ae7bf",
+	"3":  "#dbadff",
+	"4":  "#ff887c",
+	"5":  "#fbd75b",
+	"6":  "#ffb878",
+	"7":  "#46d6db",
+	"8":  "#e1e1e1",
+	"9":  "#5484ed",
+	"10": "#51b749",
+	"11": "#dc2127",
+}
+
+// extractJoinURL returns the video link of the event's conference, falling
+// back to the legacy Hangouts link
+func extractJoinURL(item *calendar.Event) string {
+	if item.ConferenceData != nil {
+		for _, entryPoint := range item.ConferenceData.EntryPoints {
+			if entryPoint.EntryPointType == "video" && entryPoint.Uri != "" {
+				return entryPoint.Uri
+			}
+		}
+	}
+	return item.HangoutLink
+}
+
+// parseEventTime parses Google Calendar event time (handles both dateTime and date fields).
+// Dates of all-day events are midnight in their time zone, or in loc if none is given.
+func parseEventTime(eventTime *calendar.EventDateTime, loc *time.Location) (time.Time, error) {
 	if eventTime == nil {
 		return time.Time{}, fmt.Errorf("event time is nil")
 	}
@@ -85,28 +132,33 @@ func parseEventTime(eventTime *calendar.EventDateTime) (time.Time, error) {
 
 	// Fall back to Date (for all-day events)
 	if eventTime.Date != "" {
-		// Parse as date only (YYYY-MM-DD format)
-		t, err := time.Parse("2006-01-02", eventTime.Date)
-		if err != nil {
-			return time.Time{}, fmt.Errorf("failed to parse date: %w", err)
+		if loc == nil {
+			loc = time.Local
 		}
 
 		// If timezone is specified, use it
 		if eventTime.TimeZone != "" {
-			loc, err := time.LoadLocation(eventTime.TimeZone)
-			if err == nil {
-				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+			if tzLoc, err := time.LoadLocation(eventTime.TimeZone); err == nil {
+				loc = tzLoc
 			}
 		}
 
+		// Parse as date only (YYYY-MM-DD format)
+		t, err := time.ParseInLocation("2006-01-02", eventTime.Date, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse date: %w", err)
+		}
+
 		return t, nil
 	}
 
 	return time.Time{}, fmt.Errorf("no datetime or date field found")
 }
 
-// convertReminders converts Google Calendar reminders to our Alarm model
-func (p *Provider) convertReminders(item *calendar.Event) []models.Alarm {
+// convertReminders converts Google Calendar reminders to our Alarm model. Events
+// using the default reminders get the calendar's defaults, or a 10 minute
+// popup if those are unknown (nil).
+func (p *Provider) convertReminders(item *calendar.Event, defaults []*calendar.EventReminder) []models.Alarm {
 	if item.Reminders == nil {
 		return nil
 	}
@@ -115,9 +167,14 @@ func (p *Provider) convertReminders(item *calendar.Event) []models.Alarm {
 
 	// Check if default reminders are enabled
 	if item.Reminders.UseDefault {
-		// For default reminders, we can't know the exact values without fetching
-		// the calendar settings. We'll use a sensible default.
-		p.logger.Debug("event uses default reminders",
+		if defaults != nil {
+			for _, reminder := range defaults {
+				alarms = append(alarms, convertReminderOverride(reminder))
+			}
+			return alarms
+		}
+
+		p.logger.Debug("event uses unknown default reminders",
 			"event_id", item.Id,
 			"title", item.Summary)
 
@@ -48,7 +48,7 @@ func TestParseEventTime(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseEventTime(tt.eventTime)
+			got, err := parseEventTime(tt.eventTime, time.UTC)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseEventTime() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -129,6 +129,7 @@ func TestConvertReminders(t *testing.T) {
 	tests := []struct {
 		name          string
 		event         *calendar.Event
+		defaults      []*calendar.EventReminder
 		expectedCount int
 	}{
 		{
@@ -151,6 +152,33 @@ func TestConvertReminders(t *testing.T) {
 			},
 			expectedCount: 1, // Should return default 10 min reminder
 		},
+		{
+			name: "Event with calendar default reminders",
+			event: &calendar.Event{
+				Id:      "test2b",
+				Summary: "Test Event",
+				Reminders: &calendar.EventReminders{
+					UseDefault: true,
+				},
+			},
+			defaults: []*calendar.EventReminder{
+				{Method: "popup", Minutes: 5},
+				{Method: "email", Minutes: 60},
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "Event on a calendar without default reminders",
+			event: &calendar.Event{
+				Id:      "test2c",
+				Summary: "Test Event",
+				Reminders: &calendar.EventReminders{
+					UseDefault: true,
+				},
+			},
+			defaults:      []*calendar.EventReminder{},
+			expectedCount: 0,
+		},
 		{
 			name: "Event with override reminders",
 			event: &calendar.Event{
@@ -170,7 +198,7 @@ func TestConvertReminders(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			alarms := provider.convertReminders(tt.event)
+			alarms := provider.convertReminders(tt.event, tt.defaults)
 
 			if len(alarms) != tt.expectedCount {
 				t.Errorf("convertReminders() count = %v, want %v", len(alarms), tt.expectedCount)
@@ -229,7 +257,7 @@ func TestConvertEvent(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			event, err := provider.convertEvent(tt.event, tt.calendarID)
+			event, err := provider.convertEvent(tt.event, tt.calendarID, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("convertEvent() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -249,3 +277,151 @@ func TestConvertEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertEventStatusAndTransparency(t *testing.T) {
+	provider := &Provider{logger: slog.Default()}
+
+	now := time.Now()
+	start := &calendar.EventDateTime{DateTime: now.Format(time.RFC3339)}
+	end := &calendar.EventDateTime{DateTime: now.Add(time.Hour).Format(time.RFC3339)}
+
+	tests := []struct {
+		name                 string
+		status               string
+		transparency         string
+		expectedStatus       string
+		expectedTransparency string
+	}{
+		{name: "busy confirmed", status: "confirmed", expectedStatus: "confirmed", expectedTransparency: "opaque"},
+		{name: "free", status: "confirmed", transparency: "transparent", expectedStatus: "confirmed", expectedTransparency: "transparent"},
+		{name: "cancelled", status: "cancelled", expectedStatus: "cancelled", expectedTransparency: "opaque"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := provider.convertEvent(&calendar.Event{
+				Id:           "status-test",
+				Summary:      "Status Test",
+				Start:        start,
+				End:          end,
+				Status:       tt.status,
+				Transparency: tt.transparency,
+			}, "primary", nil)
+			if err != nil {
+				t.Fatalf("convertEvent() unexpected error: %v", err)
+			}
+			if event.Status != tt.expectedStatus {
+				t.Errorf("convertEvent() Status = %v, want %v", event.Status, tt.expectedStatus)
+			}
+			if event.Transparency != tt.expectedTransparency {
+				t.Errorf("convertEvent() Transparency = %v, want %v", event.Transparency, tt.expectedTransparency)
+			}
+		})
+	}
+}
+
+func TestConvertAllDayEventUsesProviderLocation(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*3600)
+	provider := &Provider{logger: slog.Default(), location: loc}
+
+	event, err := provider.convertEvent(&calendar.Event{
+		Id:      "holiday",
+		Summary: "Public Holiday",
+		Start:   &calendar.EventDateTime{Date: "2025-12-25"},
+		End:     &calendar.EventDateTime{Date: "2025-12-26"},
+	}, "primary", nil)
+	if err != nil {
+		t.Fatalf("convertEvent() unexpected error: %v", err)
+	}
+
+	if !event.AllDay {
+		t.Error("convertEvent() AllDay = false, want true")
+	}
+	expected := time.Date(2025, 12, 25, 0, 0, 0, 0, loc)
+	if !event.StartTime.Equal(expected) {
+		t.Errorf("convertEvent() StartTime = %v, want %v", event.StartTime, expected)
+	}
+}
+
+func TestConvertEventTypeLinksAndColor(t *testing.T) {
+	provider := &Provider{logger: slog.Default()}
+
+	now := time.Now()
+	start := &calendar.EventDateTime{DateTime: now.Format(time.RFC3339)}
+	end := &calendar.EventDateTime{DateTime: now.Add(time.Hour).Format(time.RFC3339)}
+
+	tests := []struct {
+		name              string
+		item              *calendar.Event
+		expectedType      string
+		expectedJoinURL   string
+		expectedColor     string
+		expectedOrganizer string
+	}{
+		{
+			name:         "plain event",
+			item:         &calendar.Event{EventType: "default"},
+			expectedType: "default",
+		},
+		{
+			name: "conference video entry point",
+			item: &calendar.Event{
+				HangoutLink: "https://meet.google.com/old-link",
+				ConferenceData: &calendar.ConferenceData{
+					EntryPoints: []*calendar.EntryPoint{
+						{EntryPointType: "phone", Uri: "tel:+1-555-0100"},
+						{EntryPointType: "video", Uri: "https://meet.google.com/abc-defg-hij"},
+					},
+				},
+				Organizer: &calendar.EventOrganizer{Email: "boss@example.com"},
+			},
+			expectedJoinURL:   "https://meet.google.com/abc-defg-hij",
+			expectedOrganizer: "boss@example.com",
+		},
+		{
+			name:            "hangout link fallback",
+			item:            &calendar.Event{HangoutLink: "https://meet.google.com/old-link"},
+			expectedJoinURL: "https://meet.google.com/old-link",
+		},
+		{
+			name:          "focus time with color",
+			item:          &calendar.Event{EventType: "focusTime", ColorId: "9"},
+			expectedType:  "focusTime",
+			expectedColor: "#5484ed",
+		},
+		{
+			name:         "unknown color",
+			item:         &calendar.Event{EventType: "workingLocation", ColorId: "42"},
+			expectedType: "workingLocation",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.item.Id = "links-test"
+			tt.item.Start = start
+			tt.item.End = end
+			tt.item.HtmlLink = "https://www.google.com/calendar/event?eid=abc"
+
+			event, err := provider.convertEvent(tt.item, "primary", nil)
+			if err != nil {
+				t.Fatalf("convertEvent() unexpected error: %v", err)
+			}
+			if event.EventType != tt.expectedType {
+				t.Errorf("convertEvent() EventType = %v, want %v", event.EventType, tt.expectedType)
+			}
+			if event.JoinURL != tt.expectedJoinURL {
+				t.Errorf("convertEvent() JoinURL = %v, want %v", event.JoinURL, tt.expectedJoinURL)
+			}
+			if event.Color != tt.expectedColor {
+				t.Errorf("convertEvent() Color = %v, want %v", event.Color, tt.expectedColor)
+			}
+			if event.Organizer != tt.expectedOrganizer {
+				t.Errorf("convertEvent() Organizer = %v, want %v", event.Organizer, tt.expectedOrganizer)
+			}
+			if event.HTMLLink != tt.item.HtmlLink {
+				t.Errorf("convertEvent() HTMLLink = %v, want %v", event.HTMLLink, tt.item.HtmlLink)
+			}
+		})
+	}
+}
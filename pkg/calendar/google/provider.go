@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"google.golang.org/api/calendar/v3"
@@ -24,15 +25,34 @@ type Provider struct {
 	tokenManager *TokenManager
 	service      *calendar.Service
 	tokenFile    string
+	impersonate  string // Workspace user a service account acts as
 	calendarIDs  []string
+	location     *time.Location
 	logger       *slog.Logger
+
+	// Default reminders of each calendar from its CalendarList entry, applied to
+	// events that use them. Refreshed whenever the calendar list is fetched.
+	remindersMu      sync.Mutex
+	defaultReminders map[string][]*calendar.EventReminder
+
+	// Local copy of each calendar kept up to date with sync tokens
+	syncMu    sync.Mutex
+	calendars map[string]*calendarState
+	removed   []string // IDs of events deleted upstream, for TakeRemovedEvents
+
+	// Push notification channels opened by Watch
+	watchMu      sync.Mutex
+	stopWatching context.CancelFunc
+	watchers     sync.WaitGroup
 }
 
 // NewProvider creates a new Google Calendar provider
 func NewProvider() *Provider {
 	return &Provider{
-		name:   providerName,
-		logger: slog.Default(),
+		name:             providerName,
+		logger:           slog.Default(),
+		defaultReminders: make(map[string][]*calendar.EventReminder),
+		calendars:        make(map[string]*calendarState),
 	}
 }
 
@@ -54,13 +74,25 @@ func (p *Provider) SetLogger(logger *slog.Logger) {
 }
 
 // Initialize sets up the Google Calendar provider with OAuth2 credentials
-// credentialsPath should point to the OAuth2 credentials JSON file
+// credentialsPath should point to the OAuth2 credentials JSON file or to a
+// service account key
 func (p *Provider) Initialize(ctx context.Context, credentialsPath string) error {
 	p.logger.Info("initializing Google Calendar provider", "credentials", credentialsPath)
 
+	serviceAccount, err := IsServiceAccountKey(credentialsPath)
+	if err != nil {
+		return err
+	}
+	if serviceAccount {
+		return p.initializeServiceAccount(ctx, credentialsPath)
+	}
+	if p.impersonate != "" {
+		return fmt.Errorf("impersonating %s requires a service account key, not OAuth2 client credentials", p.impersonate)
+	}
+
 	// Token file path - store next to credentials with .token suffix
 	if p.tokenFile == "" {
-		p.tokenFile = credentialsPath + ".token"
+		p.tokenFile = DefaultTokenFile(credentialsPath)
 	}
 
 	// Create token manager
@@ -72,8 +104,7 @@ func (p *Provider) Initialize(ctx context.Context, credentialsPath string) error
 
 	// Check if we have a valid token
 	if !tm.IsTokenValid() {
-		authURL := tm.GetAuthURL()
-		return fmt.Errorf("authentication required: visit this URL to authorize:\n%s\n\nThen run with the authorization code", authURL)
+		return fmt.Errorf("authentication required: no valid token in %s", p.tokenFile)
 	}
 
 	// Get authenticated HTTP client
@@ -93,6 +124,25 @@ func (p *Provider) Initialize(ctx context.Context, credentialsPath string) error
 	return nil
 }
 
+// initializeServiceAccount sets up the provider to authenticate as a service
+// account, acting as the impersonated user if one is set
+func (p *Provider) initializeServiceAccount(ctx context.Context, credentialsPath string) error {
+	client, err := NewServiceAccountClient(ctx, credentialsPath, p.impersonate)
+	if err != nil {
+		return err
+	}
+
+	srv, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return fmt.Errorf("failed to create calendar service: %w", err)
+	}
+	p.service = srv
+
+	p.logger.Info("Google Calendar provider initialized with service account",
+		"impersonate", p.impersonate)
+	return nil
+}
+
 // Authenticate performs the initial OAuth2 authentication with the provided code
 func (p *Provider) Authenticate(ctx context.Context, authCode string) error {
 	if p.tokenManager == nil {
@@ -137,11 +187,6 @@ func (p *Provider) GetCalendars(ctx context.Context) ([]*pkgcalendar.Calendar, e
 
 	p.logger.Debug("fetching calendar list")
 
-	calendarList, err := p.service.CalendarList.List().Context(ctx).Do()
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch calendar list: %w", err)
-	}
-
 	// Build a map for quick lookup if we have configured IDs
 	var configuredIDs map[string]bool
 	if len(p.calendarIDs) > 0 {
@@ -152,20 +197,28 @@ func (p *Provider) GetCalendars(ctx context.Context) ([]*pkgcalendar.Calendar, e
 	}
 
 	var calendars []*pkgcalendar.Calendar
-	for _, item := range calendarList.Items {
-		// If calendar IDs are configured, only include those
-		if configuredIDs != nil && !configuredIDs[item.Id] {
-			continue
+	err := p.service.CalendarList.List().Context(ctx).Pages(ctx, func(page *calendar.CalendarList) error {
+		for _, item := range page.Items {
+			p.setDefaultReminders(item.Id, item.DefaultReminders)
+
+			// If calendar IDs are configured, only include those
+			if configuredIDs != nil && !configuredIDs[item.Id] {
+				continue
+			}
+
+			calendars = append(calendars, &pkgcalendar.Calendar{
+				ID:          item.Id,
+				Name:        item.Summary,
+				Description: item.Description,
+				TimeZone:    item.TimeZone,
+				Primary:     item.Primary,
+				AccessRole:  item.AccessRole,
+			})
 		}
-
-		calendars = append(calendars, &pkgcalendar.Calendar{
-			ID:          item.Id,
-			Name:        item.Summary,
-			Description: item.Description,
-			TimeZone:    item.TimeZone,
-			Primary:     item.Primary,
-			AccessRole:  item.AccessRole,
-		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar list: %w", err)
 	}
 
 	p.logger.Debug("fetched calendars", "count", len(calendars))
@@ -201,40 +254,39 @@ func (p *Provider) GetEvents(ctx context.Context, calendarIDs []string, from, to
 	return allEvents, nil
 }
 
-// getEventsFromCalendar fetches events from a single calendar
-func (p *Provider) getEventsFromCalendar(ctx context.Context, calendarID string, from, to time.Time) ([]*models.Event, error) {
-	call := p.service.Events.List(calendarID).
-		Context(ctx).
-		TimeMin(from.Format(time.RFC3339)).
-		TimeMax(to.Format(time.RFC3339)).
-		SingleEvents(true).
-		OrderBy("startTime")
+// setDefaultReminders records the default reminders of a calendar and returns them
+func (p *Provider) setDefaultReminders(calendarID string, reminders []*calendar.EventReminder) []*calendar.EventReminder {
+	p.remindersMu.Lock()
+	defer p.remindersMu.Unlock()
 
-	eventsResult, err := call.Do()
-	if err != nil {
-		return nil, err
+	if reminders == nil {
+		// Known to have none, as opposed to not fetched yet
+		reminders = []*calendar.EventReminder{}
 	}
+	p.defaultReminders[calendarID] = reminders
+	return reminders
+}
 
-	var events []*models.Event
-	for _, item := range eventsResult.Items {
-		// Skip cancelled events
-		if item.Status == "cancelled" {
-			continue
-		}
-
-		event, err := p.convertEvent(item, calendarID)
-		if err != nil {
-			p.logger.Warn("failed to convert event",
-				"event_id", item.Id,
-				"calendar_id", calendarID,
-				"error", err)
-			continue
-		}
+// calendarDefaultReminders returns the default reminders of a calendar, fetching
+// its CalendarList entry if the calendar list has not been read yet. It returns
+// nil if they cannot be determined.
+func (p *Provider) calendarDefaultReminders(ctx context.Context, calendarID string) []*calendar.EventReminder {
+	p.remindersMu.Lock()
+	reminders, ok := p.defaultReminders[calendarID]
+	p.remindersMu.Unlock()
+	if ok {
+		return reminders
+	}
 
-		events = append(events, event)
+	entry, err := p.service.CalendarList.Get(calendarID).Context(ctx).Do()
+	if err != nil {
+		p.logger.Warn("failed to fetch default reminders, assuming 10 minutes",
+			"calendar_id", calendarID,
+			"error", err)
+		return nil
 	}
 
-	return events, nil
+	return p.setDefaultReminders(calendarID, entry.DefaultReminders)
 }
 
 // IsHealthy performs a health check on the provider
@@ -255,6 +307,7 @@ func (p *Provider) IsHealthy(ctx context.Context) error {
 // Close cleans up any resources used by the provider
 func (p *Provider) Close() error {
 	p.logger.Debug("closing Google Calendar provider")
+	p.stopWatch()
 	p.service = nil
 	p.tokenManager = nil
 	return nil
@@ -265,6 +318,23 @@ func (p *Provider) SetTokenFile(tokenFile string) {
 	p.tokenFile = tokenFile
 }
 
+// SetImpersonate sets the Workspace user a service account acts as through
+// domain-wide delegation (must be called before Initialize)
+func (p *Provider) SetImpersonate(user string) {
+	p.impersonate = user
+}
+
+// SetTimeZone sets the IANA time zone for all-day events without an explicit
+// time zone (defaults to the local time zone)
+func (p *Provider) SetTimeZone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	p.location = loc
+	return nil
+}
+
 // SetCalendarIDs sets the calendar IDs to monitor
 func (p *Provider) SetCalendarIDs(calendarIDs []string) {
 	p.calendarIDs = calendarIDs
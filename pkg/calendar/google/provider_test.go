@@ -0,0 +1,194 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
+)
+
+// newTestProvider returns a provider whose service talks to handler
+func newTestProvider(t *testing.T, handler http.Handler) *Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	srv, err := calendar.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create calendar service: %v", err)
+	}
+
+	provider := NewProvider()
+	provider.service = srv
+	return provider
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("Failed to encode response: %v", err)
+	}
+}
+
+func testEvent(id string, useDefault bool) *calendar.Event {
+	return &calendar.Event{
+		Id:        id,
+		Summary:   id,
+		Start:     &calendar.EventDateTime{DateTime: "2025-10-01T09:00:00Z"},
+		End:       &calendar.EventDateTime{DateTime: "2025-10-01T10:00:00Z"},
+		Reminders: &calendar.EventReminders{UseDefault: useDefault},
+	}
+}
+
+func TestProvider_GetEventsPaginates(t *testing.T) {
+	pages := map[string]*calendar.Events{
+		"": {
+			Items:         []*calendar.Event{testEvent("first", true), testEvent("second", false)},
+			NextPageToken: "page2",
+		},
+		"page2": {
+			Items:         []*calendar.Event{testEvent("third", true)},
+			NextPageToken: "page3",
+		},
+		"page3": {
+			Items:         []*calendar.Event{testEvent("fourth", false)},
+			NextSyncToken: "sync1",
+		},
+	}
+
+	var listCalls, entryCalls int
+	mux := http.NewServeMux()
+	mux.HandleFunc("/calendars/work/events", func(w http.ResponseWriter, r *http.Request) {
+		listCalls++
+		if r.URL.Query().Get("syncToken") == "sync1" {
+			writeJSON(t, w, &calendar.Events{NextSyncToken: "sync1"})
+			return
+		}
+		page, ok := pages[r.URL.Query().Get("pageToken")]
+		if !ok {
+			http.Error(w, "unknown page token", http.StatusBadRequest)
+			return
+		}
+		writeJSON(t, w, page)
+	})
+	mux.HandleFunc("/users/me/calendarList/work", func(w http.ResponseWriter, r *http.Request) {
+		entryCalls++
+		writeJSON(t, w, &calendar.CalendarListEntry{
+			Id:               "work",
+			DefaultReminders: []*calendar.EventReminder{{Method: "popup", Minutes: 30}},
+		})
+	})
+
+	provider := newTestProvider(t, mux)
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		events, err := provider.GetEvents(context.Background(), []string{"work"}, from, from.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("GetEvents() unexpected error: %v", err)
+		}
+		if len(events) != 4 {
+			t.Fatalf("GetEvents() expected 4 events across all pages, got %d", len(events))
+		}
+
+		for _, event := range events {
+			switch event.ID {
+			case "first", "third":
+				if len(event.Alarms) != 1 || event.Alarms[0].LeadTimeMinutes != 30 {
+					t.Errorf("GetEvents() expected the calendar's 30 minute default for %s, got %+v", event.ID, event.Alarms)
+				}
+			default:
+				if len(event.Alarms) != 0 {
+					t.Errorf("GetEvents() expected no alarms for %s, got %+v", event.ID, event.Alarms)
+				}
+			}
+		}
+	}
+
+	if listCalls != 4 {
+		t.Errorf("Expected 3 event list requests and then one sync request, got %d", listCalls)
+	}
+	if entryCalls != 1 {
+		t.Errorf("Expected the default reminders to be fetched once, got %d requests", entryCalls)
+	}
+}
+
+func TestProvider_GetCalendarsCachesDefaultReminders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/calendarList", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageToken") == "" {
+			writeJSON(t, w, &calendar.CalendarList{
+				Items: []*calendar.CalendarListEntry{{
+					Id:               "primary",
+					Summary:          "Me",
+					DefaultReminders: []*calendar.EventReminder{{Method: "email", Minutes: 60}},
+				}},
+				NextPageToken: "page2",
+			})
+			return
+		}
+		writeJSON(t, w, &calendar.CalendarList{
+			Items: []*calendar.CalendarListEntry{{Id: "holidays", Summary: "Holidays"}},
+		})
+	})
+	mux.HandleFunc("/users/me/calendarList/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected calendar list entry request %s", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/calendars/holidays/events", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &calendar.Events{Items: []*calendar.Event{testEvent("holiday", true)}})
+	})
+
+	provider := newTestProvider(t, mux)
+
+	calendars, err := provider.GetCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+	if len(calendars) != 2 || calendars[1].ID != "holidays" {
+		t.Fatalf("GetCalendars() expected calendars from both pages, got %+v", calendars)
+	}
+
+	if reminders := provider.calendarDefaultReminders(context.Background(), "primary"); len(reminders) != 1 || reminders[0].Minutes != 60 {
+		t.Errorf("Expected the primary calendar's 60 minute default, got %+v", reminders)
+	}
+
+	// A calendar without default reminders gets no alarms, not the fallback
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	events, err := provider.GetEvents(context.Background(), []string{"holidays"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	if len(events) != 1 || len(events[0].Alarms) != 0 {
+		t.Errorf("Expected one event without alarms, got %+v", events)
+	}
+}
+
+func TestProvider_DefaultRemindersUnavailable(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/calendarList/shared", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	mux.HandleFunc("/calendars/shared/events", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &calendar.Events{Items: []*calendar.Event{testEvent("meeting", true)}})
+	})
+
+	provider := newTestProvider(t, mux)
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := provider.GetEvents(context.Background(), []string{"shared"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	if len(events) != 1 || len(events[0].Alarms) != 1 || events[0].Alarms[0].LeadTimeMinutes != 10 {
+		t.Errorf("Expected the 10 minute fallback, got %+v", events)
+	}
+}
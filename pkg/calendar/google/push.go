@@ -0,0 +1,347 @@
+package google
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+const (
+	// DefaultPushTTL is the channel lifetime requested when none is configured
+	DefaultPushTTL = 24 * time.Hour
+
+	// pushRetryDelay is how long to wait before trying to open a channel again
+	pushRetryDelay = time.Minute
+
+	// channelStopTimeout bounds stopping a channel, which also happens on shutdown
+	channelStopTimeout = 10 * time.Second
+)
+
+// PushReceiver is the HTTP endpoint Google Calendar posts change notifications
+// to. Providers open channels with Watch; every notification for a known
+// channel with the right token is passed on as the ID of the changed calendar.
+type PushReceiver struct {
+	url      string
+	ttl      time.Duration
+	onChange func(calendarID string)
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	channels map[string]registration // Keyed by channel ID
+	server   *http.Server
+}
+
+// registration is what a notification is checked against
+type registration struct {
+	token      string
+	calendarID string
+}
+
+// pushChannel is an open notification channel for one calendar
+type pushChannel struct {
+	id         string
+	resourceID string
+	calendarID string
+	expiration time.Time
+}
+
+// NewPushReceiver creates a receiver for notifications sent to url, the public
+// HTTPS address that reaches the receiver. Channels are requested with the
+// given lifetime and renewed before they expire.
+func NewPushReceiver(url string, ttl time.Duration, onChange func(calendarID string), logger *slog.Logger) *PushReceiver {
+	if ttl <= 0 {
+		ttl = DefaultPushTTL
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &PushReceiver{
+		url:      url,
+		ttl:      ttl,
+		onChange: onChange,
+		logger:   logger,
+		channels: make(map[string]registration),
+	}
+}
+
+// Start listens on addr and serves notifications until Close
+func (r *PushReceiver) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for push notifications: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	r.mu.Lock()
+	r.server = server
+	r.mu.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.logger.Error("push notification receiver stopped", "error", err)
+		}
+	}()
+
+	r.logger.Info("receiving Google Calendar push notifications", "listen", listener.Addr().String(), "url", r.url)
+	return nil
+}
+
+// Close stops the HTTP server started by Start
+func (r *PushReceiver) Close(ctx context.Context) error {
+	r.mu.Lock()
+	server := r.server
+	r.server = nil
+	r.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// ServeHTTP handles one notification. Google retries notifications that are
+// not answered with a 2xx status.
+func (r *PushReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channelID := req.Header.Get("X-Goog-Channel-ID")
+	state := req.Header.Get("X-Goog-Resource-State")
+
+	r.mu.Lock()
+	reg, ok := r.channels[channelID]
+	r.mu.Unlock()
+
+	if !ok {
+		// A channel left over from an earlier run, or already replaced
+		r.logger.Debug("notification for unknown channel", "channel_id", channelID)
+		http.Error(w, "unknown channel", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get("X-Goog-Channel-Token")), []byte(reg.token)) != 1 {
+		r.logger.Warn("notification with invalid channel token", "channel_id", channelID, "remote", req.RemoteAddr)
+		http.Error(w, "invalid channel token", http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// The first message only confirms that the channel is open
+	if state == "sync" {
+		r.logger.Debug("push channel confirmed", "channel_id", channelID, "calendar_id", reg.calendarID)
+		return
+	}
+
+	r.logger.Info("calendar changed",
+		"calendar_id", reg.calendarID,
+		"state", state,
+		"message_number", req.Header.Get("X-Goog-Message-Number"))
+
+	if r.onChange != nil {
+		r.onChange(reg.calendarID)
+	}
+}
+
+func (r *PushReceiver) register(channelID string, reg registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.channels[channelID] = reg
+}
+
+func (r *PushReceiver) unregister(channelID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.channels, channelID)
+}
+
+// Watch opens a notification channel for each configured calendar, or for
+// every calendar of the account if none are configured, and keeps it open,
+// renewing it before it expires, until the provider is closed. It returns the
+// IDs of the watched calendars. Failing to open a channel is logged and
+// retried; polling goes on regardless.
+func (p *Provider) Watch(ctx context.Context, receiver *PushReceiver) ([]string, error) {
+	if p.service == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	calendarIDs := p.calendarIDs
+	if len(calendarIDs) == 0 {
+		calendars, err := p.GetCalendars(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list calendars to watch: %w", err)
+		}
+		for _, cal := range calendars {
+			calendarIDs = append(calendarIDs, cal.ID)
+		}
+		if len(calendarIDs) == 0 {
+			return nil, fmt.Errorf("no calendars to watch")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	p.watchMu.Lock()
+	if p.stopWatching != nil {
+		p.watchMu.Unlock()
+		cancel()
+		return nil, fmt.Errorf("provider is already watching")
+	}
+	p.stopWatching = cancel
+	p.watchMu.Unlock()
+
+	for _, calendarID := range calendarIDs {
+		p.watchers.Add(1)
+		go p.watchCalendar(ctx, receiver, calendarID)
+	}
+	return calendarIDs, nil
+}
+
+// watchCalendar keeps a channel open for one calendar. A replacement channel
+// is opened before the old one is stopped so no change goes unnoticed.
+func (p *Provider) watchCalendar(ctx context.Context, receiver *PushReceiver, calendarID string) {
+	defer p.watchers.Done()
+
+	var current *pushChannel
+	defer func() {
+		if current != nil {
+			p.stopChannel(receiver, current)
+		}
+	}()
+
+	for {
+		wait := pushRetryDelay
+
+		next, err := p.openChannel(ctx, receiver, calendarID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.logger.Warn("failed to open push notification channel, relying on polling",
+				"calendar_id", calendarID,
+				"retry_in", wait,
+				"error", err)
+		} else {
+			if current != nil {
+				p.stopChannel(receiver, current)
+			}
+			current = next
+
+			// Renew once 90% of the lifetime has passed
+			if remaining := time.Until(next.expiration); remaining > 0 {
+				wait = remaining * 9 / 10
+			}
+			p.logger.Debug("opened push notification channel",
+				"calendar_id", calendarID,
+				"channel_id", next.id,
+				"expires", next.expiration.Format(time.RFC3339),
+				"renew_in", wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// openChannel asks Google to post changes of a calendar to the receiver
+func (p *Provider) openChannel(ctx context.Context, receiver *PushReceiver, calendarID string) (*pushChannel, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Registered first: Google confirms the channel before Watch returns
+	receiver.register(id, registration{token: token, calendarID: calendarID})
+
+	resp, err := p.service.Events.Watch(calendarID, &calendar.Channel{
+		Id:      id,
+		Token:   token,
+		Type:    "web_hook",
+		Address: receiver.url,
+		Params:  map[string]string{"ttl": strconv.Itoa(int(receiver.ttl.Seconds()))},
+	}).Context(ctx).Do()
+	if err != nil {
+		receiver.unregister(id)
+		return nil, err
+	}
+
+	expiration := time.Now().Add(receiver.ttl)
+	if resp.Expiration > 0 {
+		expiration = time.UnixMilli(resp.Expiration)
+	}
+
+	return &pushChannel{
+		id:         id,
+		resourceID: resp.ResourceId,
+		calendarID: calendarID,
+		expiration: expiration,
+	}, nil
+}
+
+// stopChannel closes a channel. It runs on shutdown too, so it does not use
+// the watch context.
+func (p *Provider) stopChannel(receiver *PushReceiver, ch *pushChannel) {
+	receiver.unregister(ch.id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), channelStopTimeout)
+	defer cancel()
+
+	err := p.service.Channels.Stop(&calendar.Channel{
+		Id:         ch.id,
+		ResourceId: ch.resourceID,
+	}).Context(ctx).Do()
+	if err != nil {
+		// Google stops sending once the channel expires anyway
+		p.logger.Debug("failed to stop push notification channel",
+			"calendar_id", ch.calendarID,
+			"channel_id", ch.id,
+			"error", err)
+	}
+}
+
+// stopWatch closes the channels opened by Watch and waits for them to stop
+func (p *Provider) stopWatch() {
+	p.watchMu.Lock()
+	cancel := p.stopWatching
+	p.stopWatching = nil
+	p.watchMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		p.watchers.Wait()
+	}
+}
+
+// randomToken returns an unguessable channel ID or token
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate channel token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,243 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// fakeChannels serves Events.Watch and Channels.Stop, remembering the
+// channels so the test can post notifications like Google would
+type fakeChannels struct {
+	t *testing.T
+
+	mu      sync.Mutex
+	opened  []*calendar.Channel
+	stopped []string
+	watched chan struct{}
+}
+
+func (f *fakeChannels) watch(w http.ResponseWriter, r *http.Request) {
+	var channel calendar.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ttl, _ := strconv.Atoi(channel.Params["ttl"])
+	channel.ResourceId = "resource-" + strconv.Itoa(len(f.opened))
+	channel.Expiration = time.Now().Add(time.Duration(ttl) * time.Second).UnixMilli()
+
+	f.mu.Lock()
+	f.opened = append(f.opened, &channel)
+	f.mu.Unlock()
+
+	writeJSON(f.t, w, &channel)
+	f.watched <- struct{}{}
+}
+
+func (f *fakeChannels) stop(w http.ResponseWriter, r *http.Request) {
+	var channel calendar.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.stopped = append(f.stopped, channel.Id)
+	f.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeChannels) channel(i int) *calendar.Channel {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opened[i]
+}
+
+func (f *fakeChannels) stoppedIDs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.stopped...)
+}
+
+// notify posts a notification for a channel the way Google does
+func notify(t *testing.T, url, channelID, token, state string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, nil)
+	req.Header.Set("X-Goog-Channel-ID", channelID)
+	req.Header.Set("X-Goog-Channel-Token", token)
+	req.Header.Set("X-Goog-Resource-State", state)
+	req.Header.Set("X-Goog-Message-Number", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to post notification: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPushReceiver(t *testing.T) {
+	fake := &fakeChannels{t: t, watched: make(chan struct{}, 10)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/calendars/work/events/watch", fake.watch)
+	mux.HandleFunc("/channels/stop", fake.stop)
+	provider := newTestProvider(t, mux)
+	provider.SetCalendarIDs([]string{"work"})
+
+	changes := make(chan string, 10)
+	receiver := NewPushReceiver("", time.Hour, func(calendarID string) { changes <- calendarID }, nil)
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	receiver.url = server.URL
+
+	if watched, err := provider.Watch(context.Background(), receiver); err != nil || len(watched) != 1 || watched[0] != "work" {
+		t.Fatalf("Watch() = %v, %v, expected [work]", watched, err)
+	}
+	if _, err := provider.Watch(context.Background(), receiver); err == nil {
+		t.Error("Expected error when already watching")
+	}
+
+	select {
+	case <-fake.watched:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a watch request")
+	}
+
+	channel := fake.channel(0)
+	if channel.Type != "web_hook" || channel.Address != server.URL || channel.Token == "" || channel.Params["ttl"] != "3600" {
+		t.Errorf("Unexpected channel request %+v", channel)
+	}
+
+	tests := []struct {
+		name      string
+		channelID string
+		token     string
+		state     string
+		status    int
+		changed   bool
+	}{
+		{"Channel confirmation", channel.Id, channel.Token, "sync", http.StatusOK, false},
+		{"Calendar changed", channel.Id, channel.Token, "exists", http.StatusOK, true},
+		{"Wrong token", channel.Id, "guess", "exists", http.StatusForbidden, false},
+		{"Unknown channel", "stale", channel.Token, "exists", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := notify(t, server.URL, tt.channelID, tt.token, tt.state); status != tt.status {
+				t.Errorf("ServeHTTP() status = %d, want %d", status, tt.status)
+			}
+
+			select {
+			case calendarID := <-changes:
+				if !tt.changed {
+					t.Errorf("Expected no refresh, got one for %s", calendarID)
+				} else if calendarID != "work" {
+					t.Errorf("Expected a refresh of work, got %s", calendarID)
+				}
+			default:
+				if tt.changed {
+					t.Error("Expected a refresh")
+				}
+			}
+		})
+	}
+
+	if err := provider.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if stopped := fake.stoppedIDs(); len(stopped) != 1 || stopped[0] != channel.Id {
+		t.Errorf("Expected the channel to be stopped on close, got %v", stopped)
+	}
+	if status := notify(t, server.URL, channel.Id, channel.Token, "exists"); status != http.StatusNotFound {
+		t.Errorf("Expected a stopped channel to be unknown, got status %d", status)
+	}
+}
+
+func TestPushReceiver_RenewsChannels(t *testing.T) {
+	fake := &fakeChannels{t: t, watched: make(chan struct{}, 10)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/calendars/work/events/watch", fake.watch)
+	mux.HandleFunc("/channels/stop", fake.stop)
+	provider := newTestProvider(t, mux)
+	provider.SetCalendarIDs([]string{"work"})
+
+	receiver := NewPushReceiver("https://example.com/push", time.Second, nil, nil)
+	if _, err := provider.Watch(context.Background(), receiver); err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	defer provider.Close()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-fake.watched:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("Expected watch request %d", i+1)
+		}
+	}
+
+	// The replaced channel is stopped once its successor is open
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stopped := fake.stoppedIDs()
+		if len(stopped) > 0 {
+			if stopped[0] != fake.channel(0).Id {
+				t.Errorf("Expected the first channel to be stopped, got %v", stopped)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the replaced channel to be stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	receiver.mu.Lock()
+	_, stillRegistered := receiver.channels[fake.channel(0).Id]
+	receiver.mu.Unlock()
+	if stillRegistered {
+		t.Error("Expected the replaced channel to be unregistered")
+	}
+}
+
+func TestProvider_WatchesAllCalendarsByDefault(t *testing.T) {
+	fake := &fakeChannels{t: t, watched: make(chan struct{}, 10)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/calendarList", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &calendar.CalendarList{
+			Items: []*calendar.CalendarListEntry{{Id: "me@example.com", Primary: true}, {Id: "team@example.com"}},
+		})
+	})
+	mux.HandleFunc("/calendars/me@example.com/events/watch", fake.watch)
+	mux.HandleFunc("/calendars/team@example.com/events/watch", fake.watch)
+	mux.HandleFunc("/channels/stop", fake.stop)
+	provider := newTestProvider(t, mux)
+
+	receiver := NewPushReceiver("https://example.com/push", time.Hour, nil, nil)
+	watched, err := provider.Watch(context.Background(), receiver)
+	if err != nil {
+		t.Fatalf("Watch() unexpected error: %v", err)
+	}
+	defer provider.Close()
+	if len(watched) != 2 || watched[0] != "me@example.com" || watched[1] != "team@example.com" {
+		t.Errorf("Expected every calendar of the account to be watched, got %v", watched)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-fake.watched:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected watch request %d", i+1)
+		}
+	}
+}
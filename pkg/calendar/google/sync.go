@@ -0,0 +1,220 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// syncWindowSlack extends a full sync past the requested range, so polls that
+// move forward in time keep using the sync token until the window runs out
+const syncWindowSlack = 24 * time.Hour
+
+// calendarState is the local copy of one calendar. A sync token only reports
+// changes to the events of the full sync it came from, so the copy covers a
+// fixed window and is refetched once a poll reaches past it.
+type calendarState struct {
+	syncToken string
+	from, to  time.Time
+	events    map[string]*calendar.Event // Keyed by event ID
+}
+
+// covers reports whether the copy can answer a poll of [from, to)
+func (s *calendarState) covers(from, to time.Time) bool {
+	return s.syncToken != "" && !from.Before(s.from) && !to.After(s.to)
+}
+
+// getEventsFromCalendar brings the local copy of a calendar up to date and
+// returns its events overlapping [from, to). The first poll lists the whole
+// window; later ones only ask for changes since the previous sync token.
+// Events deleted or cancelled upstream are recorded for TakeRemovedEvents.
+func (p *Provider) getEventsFromCalendar(ctx context.Context, calendarID string, from, to time.Time) ([]*models.Event, error) {
+	defaults := p.calendarDefaultReminders(ctx, calendarID)
+
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	state := p.calendars[calendarID]
+	if state != nil && state.covers(from, to) {
+		err := p.syncChanges(ctx, calendarID, state)
+		if isGone(err) {
+			p.logger.Info("sync token expired, resyncing calendar", "calendar_id", calendarID)
+			state, err = p.fullSync(ctx, calendarID, from, to, state)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		state, err = p.fullSync(ctx, calendarID, from, to, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.calendars[calendarID] = state
+
+	var events []*models.Event
+	for _, item := range state.events {
+		event, err := p.convertEvent(item, calendarID, defaults)
+		if err != nil {
+			p.logger.Warn("failed to convert event",
+				"event_id", item.Id,
+				"calendar_id", calendarID,
+				"error", err)
+			continue
+		}
+
+		if event.EndTime.After(from) && event.StartTime.Before(to) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].StartTime.Equal(events[j].StartTime) {
+			return events[i].StartTime.Before(events[j].StartTime)
+		}
+		return events[i].ID < events[j].ID
+	})
+
+	return events, nil
+}
+
+// fullSync lists every event of the calendar in [from, to+syncWindowSlack) and
+// returns a new copy holding them and the sync token for later changes. Events
+// of the previous copy that should be in the new window but were not listed
+// have been deleted in the meantime.
+func (p *Provider) fullSync(ctx context.Context, calendarID string, from, to time.Time, previous *calendarState) (*calendarState, error) {
+	state := &calendarState{
+		from:   from,
+		to:     to.Add(syncWindowSlack),
+		events: make(map[string]*calendar.Event),
+	}
+
+	// orderBy cannot be combined with syncToken, so neither is used here
+	call := p.service.Events.List(calendarID).
+		Context(ctx).
+		TimeMin(state.from.Format(time.RFC3339)).
+		TimeMax(state.to.Format(time.RFC3339)).
+		SingleEvents(true)
+
+	pages := 0
+	err := call.Pages(ctx, func(page *calendar.Events) error {
+		pages++
+		for _, item := range page.Items {
+			if item.Status == "cancelled" {
+				continue
+			}
+			state.events[item.Id] = item
+		}
+		// Only the last page carries the token
+		if page.NextSyncToken != "" {
+			state.syncToken = page.NextSyncToken
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		for id, item := range previous.events {
+			if _, ok := state.events[id]; ok {
+				continue
+			}
+			if p.eventWithin(item, state.from, state.to) {
+				p.removed = append(p.removed, id)
+			}
+		}
+	}
+
+	p.logger.Debug("fetched calendar events",
+		"calendar_id", calendarID,
+		"pages", pages,
+		"count", len(state.events),
+		"incremental", state.syncToken != "")
+
+	return state, nil
+}
+
+// syncChanges applies the changes made since the state's sync token
+func (p *Provider) syncChanges(ctx context.Context, calendarID string, state *calendarState) error {
+	call := p.service.Events.List(calendarID).
+		Context(ctx).
+		SyncToken(state.syncToken).
+		SingleEvents(true)
+
+	changed, deleted := 0, 0
+	var nextToken string
+	err := call.Pages(ctx, func(page *calendar.Events) error {
+		for _, item := range page.Items {
+			if item.Status == "cancelled" {
+				// Only report events the rest of the system may have seen
+				if _, ok := state.events[item.Id]; ok {
+					delete(state.events, item.Id)
+					p.removed = append(p.removed, item.Id)
+					deleted++
+				}
+				continue
+			}
+			state.events[item.Id] = item
+			changed++
+		}
+		if page.NextSyncToken != "" {
+			nextToken = page.NextSyncToken
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Changes are applied by event ID, so repeating them after a failed page
+	// is harmless; the token only moves once every page has been read
+	if nextToken != "" {
+		state.syncToken = nextToken
+	}
+
+	p.logger.Debug("synced calendar changes",
+		"calendar_id", calendarID,
+		"changed", changed,
+		"deleted", deleted)
+
+	return nil
+}
+
+// eventWithin reports whether an event overlaps [from, to)
+func (p *Provider) eventWithin(item *calendar.Event, from, to time.Time) bool {
+	start, err := parseEventTime(item.Start, p.location)
+	if err != nil {
+		return false
+	}
+	end, err := parseEventTime(item.End, p.location)
+	if err != nil {
+		return false
+	}
+	return end.After(from) && start.Before(to)
+}
+
+// isGone reports whether the server rejected a sync token as expired
+func isGone(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusGone
+}
+
+// TakeRemovedEvents returns the IDs of events deleted or cancelled upstream
+// since the previous call
+func (p *Provider) TakeRemovedEvents() []string {
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	removed := p.removed
+	p.removed = nil
+	return removed
+}
@@ -0,0 +1,224 @@
+package google
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// fakeEvents serves Events.List for one calendar with sync tokens. The token
+// is the number of changes the client has seen.
+type fakeEvents struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	events   map[string]*calendar.Event
+	changes  []string // Event ID of each change, in order
+	minToken int      // Older tokens are answered with 410 Gone
+	requests []url.Values
+}
+
+func newFakeEvents(t *testing.T, events ...*calendar.Event) *fakeEvents {
+	f := &fakeEvents{t: t, events: make(map[string]*calendar.Event)}
+	for _, event := range events {
+		f.put(event)
+	}
+	return f
+}
+
+func (f *fakeEvents) put(event *calendar.Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events[event.Id] = event
+	f.changes = append(f.changes, event.Id)
+}
+
+func (f *fakeEvents) cancel(id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Deleted events are reported with nothing but their ID and status
+	f.events[id] = &calendar.Event{Id: id, Status: "cancelled"}
+	f.changes = append(f.changes, id)
+}
+
+func (f *fakeEvents) expireTokens() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.minToken = len(f.changes)
+}
+
+func (f *fakeEvents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	f.requests = append(f.requests, query)
+	response := &calendar.Events{NextSyncToken: strconv.Itoa(len(f.changes))}
+
+	if token := query.Get("syncToken"); token != "" {
+		if query.Get("timeMin") != "" || query.Get("orderBy") != "" {
+			http.Error(w, `{"error":{"code":400,"message":"invalid sync request"}}`, http.StatusBadRequest)
+			return
+		}
+		seen, err := strconv.Atoi(token)
+		if err != nil || seen < f.minToken {
+			http.Error(w, `{"error":{"code":410,"message":"Sync token is no longer valid"}}`, http.StatusGone)
+			return
+		}
+		reported := make(map[string]bool)
+		for _, id := range f.changes[seen:] {
+			if !reported[id] {
+				reported[id] = true
+				response.Items = append(response.Items, f.events[id])
+			}
+		}
+		writeJSON(f.t, w, response)
+		return
+	}
+
+	for _, id := range sortedKeys(f.events) {
+		if f.events[id].Status != "cancelled" {
+			response.Items = append(response.Items, f.events[id])
+		}
+	}
+	writeJSON(f.t, w, response)
+}
+
+func sortedKeys(events map[string]*calendar.Event) []string {
+	var keys []string
+	for id := range events {
+		keys = append(keys, id)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func timedEvent(id string, start time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:      id,
+		Summary: id,
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+	}
+}
+
+func newSyncTestProvider(t *testing.T, fake *fakeEvents) *Provider {
+	mux := http.NewServeMux()
+	mux.Handle("/calendars/work/events", fake)
+	mux.HandleFunc("/users/me/calendarList/work", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, &calendar.CalendarListEntry{Id: "work"})
+	})
+	return newTestProvider(t, mux)
+}
+
+func eventIDs(t *testing.T, provider *Provider, from time.Time) []string {
+	t.Helper()
+	events, err := provider.GetEvents(context.Background(), []string{"work"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestProvider_IncrementalSync(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := newFakeEvents(t,
+		timedEvent("standup", from.Add(9*time.Hour)),
+		timedEvent("review", from.Add(14*time.Hour)),
+		timedEvent("retro", from.Add(16*time.Hour)))
+	provider := newSyncTestProvider(t, fake)
+
+	if ids := eventIDs(t, provider, from); !equalIDs(ids, []string{"standup", "review", "retro"}) {
+		t.Fatalf("Expected all events in start order, got %v", ids)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected no removals after the first sync, got %v", removed)
+	}
+
+	fake.put(timedEvent("review", from.Add(15*time.Hour)))
+	fake.put(timedEvent("lunch", from.Add(12*time.Hour)))
+	fake.cancel("standup")
+	fake.cancel("never-seen")
+
+	if ids := eventIDs(t, provider, from); !equalIDs(ids, []string{"lunch", "review", "retro"}) {
+		t.Errorf("Expected the changes to be applied, got %v", ids)
+	}
+	if removed := provider.TakeRemovedEvents(); !equalIDs(removed, []string{"standup"}) {
+		t.Errorf("Expected standup to be reported as deleted, got %v", removed)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected removals to be drained, got %v", removed)
+	}
+
+	second := fake.requests[1]
+	if second.Get("syncToken") != "3" {
+		t.Errorf("Expected the second poll to send the sync token, got %v", second)
+	}
+
+	// Polls moving forward within the synced window keep using the token
+	eventIDs(t, provider, from.Add(12*time.Hour))
+	if last := fake.requests[len(fake.requests)-1]; last.Get("syncToken") == "" {
+		t.Errorf("Expected a sync request within the window, got %v", last)
+	}
+
+	// Past the window the calendar is listed again
+	eventIDs(t, provider, from.Add(48*time.Hour))
+	if last := fake.requests[len(fake.requests)-1]; last.Get("syncToken") != "" || last.Get("timeMin") == "" {
+		t.Errorf("Expected a full listing past the window, got %v", last)
+	}
+	if removed := provider.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected events left behind by the window not to be reported, got %v", removed)
+	}
+}
+
+func TestProvider_SyncTokenExpired(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := newFakeEvents(t,
+		timedEvent("standup", from.Add(9*time.Hour)),
+		timedEvent("review", from.Add(14*time.Hour)))
+	provider := newSyncTestProvider(t, fake)
+
+	eventIDs(t, provider, from)
+
+	// Deleted while the token was lost: the full resync still notices
+	fake.mu.Lock()
+	delete(fake.events, "review")
+	fake.mu.Unlock()
+	fake.put(timedEvent("lunch", from.Add(12*time.Hour)))
+	fake.expireTokens()
+
+	if ids := eventIDs(t, provider, from); !equalIDs(ids, []string{"standup", "lunch"}) {
+		t.Errorf("Expected a full resync after 410 Gone, got %v", ids)
+	}
+	if removed := provider.TakeRemovedEvents(); !equalIDs(removed, []string{"review"}) {
+		t.Errorf("Expected review to be reported as deleted, got %v", removed)
+	}
+
+	n := len(fake.requests)
+	if n != 3 || fake.requests[1].Get("syncToken") == "" || fake.requests[2].Get("syncToken") != "" {
+		t.Errorf("Expected a rejected sync followed by a full listing, got %v", fake.requests)
+	}
+}
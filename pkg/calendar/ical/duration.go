@@ -0,0 +1,217 @@
+package ical
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is an RFC 5545 DURATION value (section 3.3.6), e.g. "-P1W", "P1DT2H" or "-PT15M".
+// Weeks and days are nominal: they advance calendar days and so follow DST changes,
+// while hours, minutes and seconds are exact.
+type Duration struct {
+	Negative bool
+	Weeks    int
+	Days     int
+	Hours    int
+	Minutes  int
+	Seconds  int
+}
+
+// durationUnit describes one component designator of a duration
+type durationUnit struct {
+	rank   int  // Position in the required ordering
+	inTime bool // Whether the unit belongs after the 'T' designator
+}
+
+var durationUnits = map[byte]durationUnit{
+	'W': {rank: 1},
+	'D': {rank: 2},
+	'H': {rank: 3, inTime: true},
+	'M': {rank: 4, inTime: true},
+	'S': {rank: 5, inTime: true},
+}
+
+// maxDurationSeconds keeps TimeDuration within the range of time.Duration
+const maxDurationSeconds = math.MaxInt64 / int64(time.Second)
+
+// ParseDuration parses an RFC 5545 duration:
+//
+//	dur-value = (["+"] / "-") "P" (dur-date / dur-time / dur-week)
+//
+// Components must appear in order (W or D, then T followed by H, M, S), each at most
+// once, and weeks cannot be combined with other components.
+func ParseDuration(value string) (Duration, error) {
+	var d Duration
+
+	s := value
+	if s == "" {
+		return Duration{}, fmt.Errorf("invalid duration: empty value")
+	}
+	switch s[0] {
+	case '-':
+		d.Negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	offset := len(value) - len(s)
+
+	if s == "" || s[0] != 'P' {
+		return Duration{}, fmt.Errorf("invalid duration %q: expected 'P' at offset %d", value, offset)
+	}
+	s = s[1:]
+	offset++
+	if s == "" {
+		return Duration{}, fmt.Errorf("invalid duration %q: no components after 'P'", value)
+	}
+
+	inTime := false
+	lastRank := 0
+	for i := 0; i < len(s); {
+		if s[i] == 'T' {
+			if inTime {
+				return Duration{}, fmt.Errorf("invalid duration %q: repeated 'T' at offset %d", value, offset+i)
+			}
+			if lastRank == durationUnits['W'].rank {
+				return Duration{}, fmt.Errorf("invalid duration %q: weeks cannot be combined with other components", value)
+			}
+			inTime = true
+			i++
+			if i == len(s) {
+				return Duration{}, fmt.Errorf("invalid duration %q: no time components after 'T'", value)
+			}
+			continue
+		}
+
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return Duration{}, fmt.Errorf("invalid duration %q: expected digits at offset %d, found %q", value, offset+i, s[i])
+		}
+		if i == len(s) {
+			return Duration{}, fmt.Errorf("invalid duration %q: number %q at offset %d has no unit designator", value, s[start:i], offset+start)
+		}
+
+		n, err := strconv.Atoi(s[start:i])
+		if err != nil {
+			return Duration{}, fmt.Errorf("invalid duration %q: number %q out of range", value, s[start:i])
+		}
+
+		designator := s[i]
+		unit, ok := durationUnits[designator]
+		switch {
+		case !ok:
+			return Duration{}, fmt.Errorf("invalid duration %q: unknown unit %q at offset %d", value, designator, offset+i)
+		case unit.inTime && !inTime:
+			return Duration{}, fmt.Errorf("invalid duration %q: unit %q at offset %d must follow 'T'", value, designator, offset+i)
+		case !unit.inTime && inTime:
+			return Duration{}, fmt.Errorf("invalid duration %q: unit %q at offset %d cannot follow 'T'", value, designator, offset+i)
+		case unit.rank <= lastRank:
+			return Duration{}, fmt.Errorf("invalid duration %q: unit %q at offset %d is repeated or out of order", value, designator, offset+i)
+		case lastRank == durationUnits['W'].rank:
+			return Duration{}, fmt.Errorf("invalid duration %q: weeks cannot be combined with other components", value)
+		}
+		lastRank = unit.rank
+
+		switch designator {
+		case 'W':
+			d.Weeks = n
+		case 'D':
+			d.Days = n
+		case 'H':
+			d.Hours = n
+		case 'M':
+			d.Minutes = n
+		case 'S':
+			d.Seconds = n
+		}
+		i++
+	}
+
+	if d.totalSeconds() > maxDurationSeconds {
+		return Duration{}, fmt.Errorf("invalid duration %q: too large", value)
+	}
+
+	return d, nil
+}
+
+// totalSeconds returns the unsigned length of the duration with nominal 24-hour days,
+// saturating instead of overflowing
+func (d Duration) totalSeconds() int64 {
+	total := int64(0)
+	for _, part := range []struct{ n, unit int64 }{
+		{int64(d.Weeks), 7 * 86400},
+		{int64(d.Days), 86400},
+		{int64(d.Hours), 3600},
+		{int64(d.Minutes), 60},
+		{int64(d.Seconds), 1},
+	} {
+		if part.n > (math.MaxInt64-total)/part.unit {
+			return math.MaxInt64
+		}
+		total += part.n * part.unit
+	}
+	return total
+}
+
+// TimeDuration returns the duration as a time.Duration, counting a day as 24 hours
+func (d Duration) TimeDuration() time.Duration {
+	result := time.Duration(d.totalSeconds()) * time.Second
+	if d.Negative {
+		return -result
+	}
+	return result
+}
+
+// AddTo adds the duration to t. Weeks and days are added as calendar days in t's
+// location, so "P1D" keeps the wall-clock time across a DST change.
+func (d Duration) AddTo(t time.Time) time.Time {
+	sign := 1
+	if d.Negative {
+		sign = -1
+	}
+	exact := time.Duration(d.Hours)*time.Hour + time.Duration(d.Minutes)*time.Minute + time.Duration(d.Seconds)*time.Second
+	return t.AddDate(0, 0, sign*(7*d.Weeks+d.Days)).Add(time.Duration(sign) * exact)
+}
+
+// IsZero returns true if the duration has no length
+func (d Duration) IsZero() bool {
+	return d.Weeks == 0 && d.Days == 0 && d.Hours == 0 && d.Minutes == 0 && d.Seconds == 0
+}
+
+// String formats the duration in RFC 5545 form, e.g. "-P1DT2H"
+func (d Duration) String() string {
+	var b strings.Builder
+	if d.Negative && !d.IsZero() {
+		b.WriteByte('-')
+	}
+	b.WriteByte('P')
+
+	if d.Weeks != 0 && d.Days == 0 && d.Hours == 0 && d.Minutes == 0 && d.Seconds == 0 {
+		fmt.Fprintf(&b, "%dW", d.Weeks)
+		return b.String()
+	}
+
+	days := 7*d.Weeks + d.Days
+	if days != 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if d.Hours != 0 || d.Minutes != 0 || d.Seconds != 0 || days == 0 {
+		b.WriteByte('T')
+		if d.Hours != 0 {
+			fmt.Fprintf(&b, "%dH", d.Hours)
+		}
+		if d.Minutes != 0 {
+			fmt.Fprintf(&b, "%dM", d.Minutes)
+		}
+		if d.Seconds != 0 || (d.Hours == 0 && d.Minutes == 0) {
+			fmt.Fprintf(&b, "%dS", d.Seconds)
+		}
+	}
+	return b.String()
+}
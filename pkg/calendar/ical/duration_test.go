@@ -0,0 +1,231 @@
+package ical
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "negative 15 minutes",
+			input:    "-PT15M",
+			expected: -15 * time.Minute,
+		},
+		{
+			name:     "positive 10 minutes",
+			input:    "PT10M",
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "explicit plus sign",
+			input:    "+PT10M",
+			expected: 10 * time.Minute,
+		},
+		{
+			name:     "negative 1 hour",
+			input:    "-PT1H",
+			expected: -1 * time.Hour,
+		},
+		{
+			name:     "complex format: negative 0 days 0 hours 5 minutes 0 seconds",
+			input:    "-P0DT0H5M0S",
+			expected: -5 * time.Minute,
+		},
+		{
+			name:     "complex format: positive 2 hours 15 minutes 30 seconds",
+			input:    "P0DT2H15M30S",
+			expected: 2*time.Hour + 15*time.Minute + 30*time.Second,
+		},
+		{
+			name:     "just minutes and seconds",
+			input:    "PT45M30S",
+			expected: 45*time.Minute + 30*time.Second,
+		},
+		{
+			name:     "just seconds",
+			input:    "PT120S",
+			expected: 120 * time.Second,
+		},
+		{
+			name:     "zero duration",
+			input:    "PT0M",
+			expected: 0,
+		},
+		{
+			name:     "very large duration",
+			input:    "PT9999M",
+			expected: 9999 * time.Minute,
+		},
+		{
+			name:     "one day",
+			input:    "-P1D",
+			expected: -24 * time.Hour,
+		},
+		{
+			name:     "days and time",
+			input:    "-P1DT2H3M4S",
+			expected: -(26*time.Hour + 3*time.Minute + 4*time.Second),
+		},
+		{
+			name:     "weeks",
+			input:    "-P1W",
+			expected: -7 * 24 * time.Hour,
+		},
+		{name: "empty string", input: "", wantErr: true},
+		{name: "unsupported format", input: "INVALID", wantErr: true},
+		{name: "just P", input: "P", wantErr: true},
+		{name: "just PT", input: "PT", wantErr: true},
+		{name: "sign only", input: "-", wantErr: true},
+		{name: "missing number", input: "PTM", wantErr: true},
+		{name: "missing unit", input: "PT15", wantErr: true},
+		{name: "time unit without T", input: "P15M", wantErr: true},
+		{name: "day after T", input: "PT1D", wantErr: true},
+		{name: "units out of order", input: "PT5M1H", wantErr: true},
+		{name: "repeated unit", input: "PT5M5M", wantErr: true},
+		{name: "repeated T", input: "PT1HT5M", wantErr: true},
+		{name: "weeks with days", input: "P1W2D", wantErr: true},
+		{name: "weeks with time", input: "P1WT1H", wantErr: true},
+		{name: "unknown unit", input: "P1Y", wantErr: true},
+		{name: "fractional value", input: "PT1.5H", wantErr: true},
+		{name: "lowercase", input: "-pt15m", wantErr: true},
+		{name: "too large", input: "P99999999999D", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseDuration(tt.input)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseDuration() expected error for input %q, got %+v", tt.input, result)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseDuration() unexpected error for input %q: %v", tt.input, err)
+			}
+
+			if result.TimeDuration() != tt.expected {
+				t.Errorf("ParseDuration() for input %q = %v, expected %v", tt.input, result.TimeDuration(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDurationErrorMessages(t *testing.T) {
+	tests := []struct {
+		input    string
+		contains string
+	}{
+		{input: "PT5M1H", contains: `unit 'H' at offset 5 is repeated or out of order`},
+		{input: "P15M", contains: `unit 'M' at offset 3 must follow 'T'`},
+		{input: "PT15", contains: `number "15" at offset 2 has no unit designator`},
+		{input: "-X", contains: `expected 'P' at offset 1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseDuration(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("ParseDuration(%q) error = %v, expected it to contain %q", tt.input, err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "-PT15M", expected: "-PT15M"},
+		{input: "-P0DT0H5M0S", expected: "-PT5M"},
+		{input: "P1DT2H", expected: "P1DT2H"},
+		{input: "-P2W", expected: "-P2W"},
+		{input: "-PT0S", expected: "PT0S"},
+		{input: "PT1H0M30S", expected: "PT1H30S"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDuration(tt.input)
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) unexpected error: %v", tt.input, err)
+			}
+			if d.String() != tt.expected {
+				t.Errorf("String() = %q, expected %q", d.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestDurationAddToKeepsWallClockAcrossDST(t *testing.T) {
+	loc := mustLoadLocation(t, "America/New_York")
+
+	// DST starts on 2024-03-10 in New York, so that day is only 23 hours long
+	start := time.Date(2024, 3, 9, 9, 0, 0, 0, loc)
+
+	day, _ := ParseDuration("P1D")
+	if result := day.AddTo(start); result.Hour() != 9 || result.Day() != 10 {
+		t.Errorf("P1D.AddTo() = %v, expected 09:00 on March 10", result)
+	}
+
+	hours, _ := ParseDuration("PT24H")
+	if result := hours.AddTo(start); result.Hour() != 10 {
+		t.Errorf("PT24H.AddTo() = %v, expected 10:00 on March 10", result)
+	}
+
+	before, _ := ParseDuration("-P1DT30M")
+	if result := before.AddTo(start); !result.Equal(time.Date(2024, 3, 8, 8, 30, 0, 0, loc)) {
+		t.Errorf("-P1DT30M.AddTo() = %v, expected 08:30 on March 8", result)
+	}
+}
+
+func TestParseICalDataDayBeforeAlarmAndDuration(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:review@example.com
+DTSTART:20240115T100000Z
+DURATION:PT1H30M
+SUMMARY:Quarterly Review
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-P1D
+END:VALARM
+BEGIN:VALARM
+ACTION:DISPLAY
+TRIGGER:-P1W
+END:VALARM
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	event := events[0]
+	if event.EndTime.Sub(event.StartTime) != 90*time.Minute {
+		t.Errorf("Expected 90 minute duration, got %v", event.EndTime.Sub(event.StartTime))
+	}
+	if len(event.Alarms) != 2 || event.Alarms[0].LeadTimeMinutes != 24*60 || event.Alarms[1].LeadTimeMinutes != 7*24*60 {
+		t.Errorf("Expected alarms 1 day and 1 week before, got %+v", event.Alarms)
+	}
+}
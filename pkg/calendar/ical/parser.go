@@ -3,6 +3,7 @@ package ical
 import (
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -11,38 +12,244 @@ import (
 	"github.com/venkytv/calendar-notifier/internal/models"
 )
 
-// ParseICalData parses iCal data using the arran4/golang-ical library
+// ParseICalData parses iCal data using the arran4/golang-ical library.
+// Floating times are interpreted in the local time zone.
 func ParseICalData(icalData string, calendarID, calendarName string, from, to time.Time, userEmail string, logger *slog.Logger) ([]*models.Event, error) {
-	if logger == nil {
-		logger = slog.Default()
-	}
+	return ParseICalDataInLocation(icalData, calendarID, calendarName, from, to, userEmail, time.Local, logger)
+}
+
+// ParseICalDataInLocation parses iCal data like ParseICalData, interpreting floating
+// times and all-day dates in defaultLoc. TZIDs are resolved against the feed's
+// VTIMEZONE definitions, IANA names and Windows zone names; events whose time zone
+// cannot be resolved are skipped with a warning rather than shifted.
+func ParseICalDataInLocation(icalData string, calendarID, calendarName string, from, to time.Time, userEmail string, defaultLoc *time.Location, logger *slog.Logger) ([]*models.Event, error) {
 	// Parse iCal data using arran4/golang-ical
 	calendar, err := ics.ParseCalendar(strings.NewReader(icalData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse iCal data: %v", err)
 	}
 
+	return CalendarEvents(calendar, calendarID, calendarName, from, to, userEmail, defaultLoc, logger), nil
+}
+
+// CalendarEvents converts the events of a parsed calendar that fall within
+// [from, to) like ParseICalDataInLocation, so a calendar parsed once can be
+// queried for several windows
+func CalendarEvents(calendar *ics.Calendar, calendarID, calendarName string, from, to time.Time, userEmail string, defaultLoc *time.Location, logger *slog.Logger) []*models.Event {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	tz := newTimezoneResolver(calendar, defaultLoc, logger)
+
 	var events []*models.Event
 
-	// Process each event in the calendar
-	for _, event := range calendar.Events() {
-		internalEvent, err := ConvertICSEventToInternalEvent(event, calendarID, calendarName, userEmail, logger)
+	// Process each series in the calendar; RECURRENCE-ID components override
+	// individual occurrences of the series that shares their UID
+	for _, series := range groupEventSeries(calendar.Events()) {
+		var overrideIDs []time.Time
+		var overrides []*models.Event
+
+		for _, override := range series.overrides {
+			internalEvent, recurrenceID, err := convertOverride(override, tz, series.location(tz), calendarID, calendarName, userEmail, logger)
+			if err != nil {
+				logger.Warn("Failed to convert iCal recurrence override", "error", err, "calendar_id", calendarID)
+				continue
+			}
+			// A cancelled override replaces the occurrence with a cancelled event
+			overrideIDs = append(overrideIDs, recurrenceID)
+			overrides = append(overrides, internalEvent)
+		}
+
+		for _, event := range series.masters {
+			internalEvent, err := convertEvent(event, tz, calendarID, calendarName, userEmail, logger)
+			if err != nil {
+				logger.Warn("Failed to convert iCal event", "error", err, "event_id", event.Id(), "calendar_id", calendarID)
+				continue
+			}
+
+			// Expand recurring events and filter occurrences that fall within our time range
+			events = append(events, expandRecurrences(event, internalEvent, tz, from, to, overrideIDs)...)
+		}
+
+		// Overridden occurrences are filtered on their new times
+		for _, override := range overrides {
+			if override.StartTime.Before(to) && override.EndTime.After(from) {
+				events = append(events, override)
+			}
+		}
+	}
+
+	return events
+}
+
+// eventSeries holds the components sharing one UID: the master (normally one)
+// and any RECURRENCE-ID overrides of individual occurrences
+type eventSeries struct {
+	masters   []*ics.VEvent
+	overrides []*ics.VEvent
+}
+
+// location returns the time zone of the series DTSTART, used for floating
+// RECURRENCE-ID values
+func (s *eventSeries) location(tz *timezoneResolver) *time.Location {
+	for _, master := range s.masters {
+		if start, err := tz.propertyTime(master.GetProperty(ics.ComponentPropertyDtStart)); err == nil {
+			return start.Location()
+		}
+	}
+	return tz.defaultLoc
+}
+
+// groupEventSeries groups VEVENTs by UID, preserving the order in which UIDs first appear
+func groupEventSeries(vevents []*ics.VEvent) []*eventSeries {
+	var ordered []*eventSeries
+	byUID := make(map[string]*eventSeries)
+
+	for _, event := range vevents {
+		uid := event.Id()
+		series, exists := byUID[uid]
+		if !exists {
+			series = &eventSeries{}
+			byUID[uid] = series
+			ordered = append(ordered, series)
+		}
+
+		if event.HasProperty(ics.ComponentPropertyRecurrenceId) {
+			series.overrides = append(series.overrides, event)
+		} else {
+			series.masters = append(series.masters, event)
+		}
+	}
+
+	return ordered
+}
+
+// convertOverride converts a RECURRENCE-ID component and gives it the ID of the
+// occurrence it replaces, so it updates that occurrence rather than adding a new one
+func convertOverride(event *ics.VEvent, tz *timezoneResolver, loc *time.Location, calendarID, calendarName, userEmail string, logger *slog.Logger) (*models.Event, time.Time, error) {
+	prop := event.GetProperty(ics.ComponentPropertyRecurrenceId)
+	times, err := tz.propertyTimes(prop, loc)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to parse RECURRENCE-ID: %w", err)
+	}
+	if len(times) != 1 {
+		return nil, time.Time{}, fmt.Errorf("expected a single RECURRENCE-ID value, got %q", prop.Value)
+	}
+	recurrenceID := times[0]
+
+	internalEvent, err := convertEvent(event, tz, calendarID, calendarName, userEmail, logger)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	internalEvent.ID = occurrenceID(internalEvent.ID, recurrenceID, isDateValue(prop))
+
+	return internalEvent, recurrenceID, nil
+}
+
+// expandRecurrences returns the occurrences of an event (RRULE and RDATE) that overlap
+// the [from, to) window, minus EXDATEs and the given overridden occurrences.
+// Non-recurring events are returned unchanged if they overlap.
+// Each occurrence of a recurring event gets a stable ID derived from the UID and its start.
+// Recurrence properties that cannot be parsed are logged and ignored.
+func expandRecurrences(event *ics.VEvent, base *models.Event, tz *timezoneResolver, from, to time.Time, overridden []time.Time) []*models.Event {
+	rrules := event.GetProperties(ics.ComponentPropertyRrule)
+	rdates := event.GetProperties(ics.ComponentPropertyRdate)
+
+	if len(rrules) == 0 && len(rdates) == 0 {
+		if base.StartTime.Before(to) && base.EndTime.After(from) {
+			return []*models.Event{base}
+		}
+		return nil
+	}
+
+	loc := base.StartTime.Location()
+	duration := base.EndTime.Sub(base.StartTime)
+
+	// The rule is always walked from DTSTART so that COUNT is honoured. A rule
+	// that cannot be expanded still leaves the DTSTART occurrence to notify about.
+	starts := []time.Time{base.StartTime}
+	for _, prop := range rrules {
+		rule, err := ParseRecurrenceRule(prop.Value, loc)
 		if err != nil {
-			logger.Warn("Failed to convert iCal event", "error", err, "calendar_id", calendarID)
+			tz.logger.Warn("Ignoring RRULE of iCal event, keeping its first occurrence only",
+				"error", err, "event_id", base.ID, "rrule", prop.Value)
 			continue
 		}
+		starts = append(starts, rule.Occurrences(base.StartTime, to)...)
+	}
+	for _, prop := range rdates {
+		times, err := tz.propertyTimes(prop, loc)
+		if err != nil {
+			tz.logger.Warn("Ignoring unparseable RDATE of iCal event", "error", err, "event_id", base.ID)
+			continue
+		}
+		starts = append(starts, times...)
+	}
 
-		// Filter events that fall within our time range
-		if internalEvent.StartTime.Before(to) && internalEvent.EndTime.After(from) {
-			events = append(events, internalEvent)
+	// Occurrences removed by EXDATE or replaced by a RECURRENCE-ID override
+	excluded := make(map[int64]bool)
+	for _, t := range overridden {
+		excluded[t.Unix()] = true
+	}
+	for _, prop := range event.GetProperties(ics.ComponentPropertyExdate) {
+		times, err := tz.propertyTimes(prop, loc)
+		if err != nil {
+			tz.logger.Warn("Ignoring unparseable EXDATE of iCal event", "error", err, "event_id", base.ID)
+			continue
+		}
+		for _, t := range times {
+			excluded[t.Unix()] = true
+		}
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	starts = dedupeTimes(starts)
+
+	dateOnly := isDateValue(event.GetProperty(ics.ComponentPropertyDtStart))
+
+	var occurrences []*models.Event
+	for _, start := range starts {
+		end := start.Add(duration)
+		if !start.Before(to) || !end.After(from) || excluded[start.Unix()] {
+			continue
+		}
+
+		occurrence := *base
+		occurrence.ID = occurrenceID(base.ID, start, dateOnly)
+		occurrence.StartTime = start
+		occurrence.EndTime = end
+		occurrence.Alarms = nil
+		for _, alarm := range base.Alarms {
+			// An absolute trigger names a single instant, so it belongs to the first occurrence only
+			if alarm.IsAbsolute() && !start.Equal(base.StartTime) {
+				continue
+			}
+			occurrence.Alarms = append(occurrence.Alarms, alarm)
 		}
+		occurrences = append(occurrences, &occurrence)
 	}
 
-	return events, nil
+	return occurrences
 }
 
-// ConvertICSEventToInternalEvent converts an ics.VEvent to our internal Event model
+// occurrenceID builds a stable per-occurrence ID, following Google's "<uid>_<start>" convention
+func occurrenceID(uid string, start time.Time, dateOnly bool) string {
+	if dateOnly {
+		return fmt.Sprintf("%s_%s", uid, start.Format(icalDateFormat))
+	}
+	return fmt.Sprintf("%s_%s", uid, start.UTC().Format(icalUTCFormat))
+}
+
+// ConvertICSEventToInternalEvent converts an ics.VEvent to our internal Event model.
+// Without the enclosing calendar only IANA and Windows TZIDs can be resolved, and
+// floating times are interpreted in the local time zone.
 func ConvertICSEventToInternalEvent(event *ics.VEvent, calendarID, calendarName string, userEmail string, logger *slog.Logger) (*models.Event, error) {
+	return convertEvent(event, newTimezoneResolver(nil, time.Local, logger), calendarID, calendarName, userEmail, logger)
+}
+
+// convertEvent converts an ics.VEvent, resolving its times with tz
+func convertEvent(event *ics.VEvent, tz *timezoneResolver, calendarID, calendarName string, userEmail string, logger *slog.Logger) (*models.Event, error) {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -69,17 +276,31 @@ func ConvertICSEventToInternalEvent(event *ics.VEvent, calendarID, calendarName
 	}
 
 	// Parse start time
-	startTime, err := event.GetStartAt()
+	dtstart := event.GetProperty(ics.ComponentPropertyDtStart)
+	startTime, err := tz.propertyTime(dtstart)
 	if err == nil {
 		internalEvent.StartTime = startTime
+		internalEvent.AllDay = isDateValue(dtstart)
 	} else {
 		return nil, fmt.Errorf("failed to parse start time: %v", err)
 	}
 
 	// Parse end time
-	endTime, err := event.GetEndAt()
-	if err == nil {
+	if dtend := event.GetProperty(ics.ComponentPropertyDtEnd); dtend != nil {
+		endTime, err := tz.propertyTime(dtend)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse end time: %v", err)
+		}
 		internalEvent.EndTime = endTime
+	} else if durationProp := event.GetProperty(ics.ComponentPropertyDuration); durationProp != nil {
+		duration, err := ParseDuration(durationProp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duration: %v", err)
+		}
+		internalEvent.EndTime = duration.AddTo(internalEvent.StartTime)
+	} else if internalEvent.AllDay {
+		// An all-day event without an end lasts the whole day
+		internalEvent.EndTime = internalEvent.StartTime.AddDate(0, 0, 1)
 	} else {
 		// Set default end time if not provided (assume 1 hour duration)
 		if !internalEvent.StartTime.IsZero() {
@@ -100,6 +321,9 @@ func ConvertICSEventToInternalEvent(event *ics.VEvent, calendarID, calendarName
 	// Extract response status from attendees
 	internalEvent.ResponseStatus = extractResponseStatusFromAttendees(event, userEmail)
 
+	// Extract status and transparency
+	internalEvent.Status, internalEvent.Transparency = extractStatusAndTransparency(event)
+
 	// Validate required fields
 	if internalEvent.ID == "" {
 		return nil, fmt.Errorf("event missing UID")
@@ -111,11 +335,9 @@ func ConvertICSEventToInternalEvent(event *ics.VEvent, calendarID, calendarName
 	return internalEvent, nil
 }
 
-// ConvertICSAlarmToInternalAlarm converts an ics.VAlarm to our internal Alarm model
+// ConvertICSAlarmToInternalAlarm converts an ics.VAlarm to our internal Alarm model.
+// A TRIGGER that cannot be parsed is an error, so the caller can skip the alarm.
 func ConvertICSAlarmToInternalAlarm(alarm *ics.VAlarm, event *ics.VEvent, calendarID string, logger *slog.Logger) (*models.Alarm, error) {
-	if logger == nil {
-		logger = slog.Default()
-	}
 	internalAlarm := &models.Alarm{
 		Method:   "popup", // Default
 		Severity: "normal", // Default
@@ -130,22 +352,24 @@ func ConvertICSAlarmToInternalAlarm(alarm *ics.VAlarm, event *ics.VEvent, calend
 	if trigger := alarm.GetProperty(ics.ComponentPropertyTrigger); trigger != nil {
 		triggerValue := trigger.Value
 
-		// Check if it's an absolute time (format: YYYYMMDDTHHMMSSZ)
-		if len(triggerValue) > 10 && (triggerValue[8] == 'T' || triggerValue[len(triggerValue)-1] == 'Z') {
-			// This is an absolute trigger time - we can't calculate lead time without event context
-			// For now, use a default lead time
-			logger.Warn("Absolute trigger times not fully supported, using default lead time", "trigger_value", triggerValue, "event_id", event.Id(), "calendar_id", calendarID)
-			internalAlarm.LeadTimeMinutes = 15 // Default
+		if isAbsoluteTrigger(trigger) {
+			// Absolute triggers must be in UTC (RFC 5545 section 3.8.6.3)
+			triggerTime, err := parseTimeValue(triggerValue, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid absolute trigger %q: %w", triggerValue, err)
+			}
+			internalAlarm.AbsoluteTime = &triggerTime
 		} else {
 			// Parse iCal duration format (e.g., "-P0DT0H5M0S", "-PT15M")
-			duration, err := parseICalDuration(triggerValue)
+			duration, err := ParseDuration(triggerValue)
 			if err != nil {
-				logger.Warn("Failed to parse trigger duration, using default", "trigger_value", triggerValue, "error", err, "event_id", event.Id(), "calendar_id", calendarID)
-				internalAlarm.LeadTimeMinutes = 15 // Default
-			} else {
-				// Convert to positive minutes (scheduler expects positive lead times)
-				minutes := int(duration.Abs().Minutes())
-				internalAlarm.LeadTimeMinutes = minutes
+				return nil, fmt.Errorf("invalid trigger duration %q: %w", triggerValue, err)
+			}
+			// Negative durations are before the reference point, i.e. a positive lead time
+			internalAlarm.LeadTimeMinutes = int(-duration.TimeDuration().Minutes())
+
+			if related, ok := trigger.ICalParameters["RELATED"]; ok && len(related) > 0 && strings.EqualFold(related[0], "END") {
+				internalAlarm.RelatedTo = models.AlarmRelatedEnd
 			}
 		}
 	}
@@ -153,81 +377,45 @@ func ConvertICSAlarmToInternalAlarm(alarm *ics.VAlarm, event *ics.VEvent, calend
 	return internalAlarm, nil
 }
 
-// parseICalDuration parses iCal duration (e.g., "-P0DT0H5M0S", "-PT15M", "P0DT0H5M0S")
-func parseICalDuration(duration string) (time.Duration, error) {
-	// Remove leading negative sign and remember it
-	negative := false
-	if len(duration) > 0 && duration[0] == '-' {
-		negative = true
-		duration = duration[1:]
+// isAbsoluteTrigger reports whether a TRIGGER holds a DATE-TIME rather than a duration
+func isAbsoluteTrigger(trigger *ics.IANAProperty) bool {
+	if value, ok := trigger.ICalParameters["VALUE"]; ok && len(value) > 0 {
+		return strings.EqualFold(value[0], "DATE-TIME")
 	}
+	// Some producers omit VALUE=DATE-TIME; durations always contain a 'P'
+	return !strings.Contains(trigger.Value, "P")
+}
 
-	var result time.Duration
-
-	// Handle full iCal format: P[n]DT[n]H[n]M[n]S
-	if len(duration) > 2 && duration[0] == 'P' {
-		// Parse P0DT0H5M0S format
-		remaining := duration[1:] // Skip 'P'
-
-		// Parse days (if present)
-		if dayIndex := strings.Index(remaining, "D"); dayIndex >= 0 {
-			// Skip days for now, move to time part
-			if tIndex := strings.Index(remaining, "T"); tIndex >= 0 {
-				remaining = remaining[tIndex+1:] // Skip to time part after 'T'
-			}
-		} else if strings.HasPrefix(remaining, "T") {
-			remaining = remaining[1:] // Skip 'T' if no days
-		}
-
-		// Parse hours
-		if hIndex := strings.Index(remaining, "H"); hIndex >= 0 {
-			hoursStr := remaining[:hIndex]
-			if hours := parseInt(hoursStr); hours > 0 {
-				result += time.Duration(hours) * time.Hour
-			}
-			remaining = remaining[hIndex+1:]
+// extractStatusAndTransparency maps STATUS and TRANSP to our internal values.
+// Outlook's X-MICROSOFT-CDO-BUSYSTATUS:FREE is treated as transparent.
+func extractStatusAndTransparency(event *ics.VEvent) (status, transparency string) {
+	if prop := event.GetProperty(ics.ComponentPropertyStatus); prop != nil {
+		switch strings.ToUpper(strings.TrimSpace(prop.Value)) {
+		case "CONFIRMED":
+			status = models.EventStatusConfirmed
+		case "TENTATIVE":
+			status = models.EventStatusTentative
+		case "CANCELLED":
+			status = models.EventStatusCancelled
 		}
+	}
 
-		// Parse minutes
-		if mIndex := strings.Index(remaining, "M"); mIndex >= 0 {
-			minutesStr := remaining[:mIndex]
-			if minutes := parseInt(minutesStr); minutes > 0 {
-				result += time.Duration(minutes) * time.Minute
-			}
-			remaining = remaining[mIndex+1:]
+	if prop := event.GetProperty(ics.ComponentPropertyTransp); prop != nil {
+		switch strings.ToUpper(strings.TrimSpace(prop.Value)) {
+		case "OPAQUE":
+			transparency = models.TransparencyOpaque
+		case "TRANSPARENT":
+			transparency = models.TransparencyTransparent
 		}
+	}
 
-		// Parse seconds
-		if sIndex := strings.Index(remaining, "S"); sIndex >= 0 {
-			secondsStr := remaining[:sIndex]
-			if seconds := parseInt(secondsStr); seconds > 0 {
-				result += time.Duration(seconds) * time.Second
-			}
-		}
-	} else {
-		// Handle simple formats: PT15M, PT1H, etc.
-		switch duration {
-		case "PT15M":
-			result = 15 * time.Minute
-		case "PT5M":
-			result = 5 * time.Minute
-		case "PT10M":
-			result = 10 * time.Minute
-		case "PT30M":
-			result = 30 * time.Minute
-		case "PT1H":
-			result = 1 * time.Hour
-		case "PT2H":
-			result = 2 * time.Hour
-		default:
-			return 0, fmt.Errorf("unsupported duration format: %s", duration)
+	if prop := event.GetProperty(ics.ComponentProperty("X-MICROSOFT-CDO-BUSYSTATUS")); prop != nil {
+		if strings.EqualFold(strings.TrimSpace(prop.Value), "FREE") {
+			transparency = models.TransparencyTransparent
 		}
 	}
 
-	if negative {
-		result = -result
-	}
-	return result, nil
+	return status, transparency
 }
 
 // extractResponseStatusFromAttendees extracts the user's response status from ATTENDEE properties
@@ -284,19 +472,38 @@ func extractResponseStatusFromAttendees(event *ics.VEvent, userEmail string) str
 	return ""
 }
 
-// parseInt is a simple helper to parse integers, returning 0 if invalid
-func parseInt(s string) int {
-	if s == "" {
-		return 0
+const (
+	icalDateFormat  = "20060102"
+	icalLocalFormat = "20060102T150405"
+	icalUTCFormat   = "20060102T150405Z"
+)
+
+// parseTimeValue parses an iCal DATE or DATE-TIME value. UTC values (trailing "Z")
+// ignore loc; floating values and dates are interpreted in loc.
+func parseTimeValue(value string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.Local
 	}
-	// Simple conversion - could use strconv.Atoi but this handles edge cases
-	var result int
-	for _, r := range s {
-		if r >= '0' && r <= '9' {
-			result = result*10 + int(r-'0')
-		} else {
-			return 0 // Invalid character
-		}
+
+	switch {
+	case len(value) == len(icalUTCFormat) && strings.HasSuffix(value, "Z"):
+		return time.ParseInLocation(icalUTCFormat, value, time.UTC)
+	case len(value) == len(icalLocalFormat):
+		return time.ParseInLocation(icalLocalFormat, value, loc)
+	case len(value) == len(icalDateFormat):
+		return time.ParseInLocation(icalDateFormat, value, loc)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported date/time value %q", value)
 	}
-	return result
-}
\ No newline at end of file
+}
+
+// isDateValue reports whether a date/time property holds a DATE (all-day) value
+func isDateValue(prop *ics.IANAProperty) bool {
+	if prop == nil {
+		return false
+	}
+	if value, ok := prop.ICalParameters["VALUE"]; ok && len(value) > 0 {
+		return strings.EqualFold(value[0], "DATE")
+	}
+	return len(prop.Value) == len(icalDateFormat)
+}
@@ -7,148 +7,9 @@ import (
 	"time"
 
 	ics "github.com/arran4/golang-ical"
-)
-
-func TestParseICalDuration(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected time.Duration
-		wantErr  bool
-	}{
-		{
-			name:     "negative 15 minutes",
-			input:    "-PT15M",
-			expected: -15 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "negative 5 minutes",
-			input:    "-PT5M",
-			expected: -5 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "positive 10 minutes",
-			input:    "PT10M",
-			expected: 10 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "negative 30 minutes",
-			input:    "-PT30M",
-			expected: -30 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "negative 1 hour",
-			input:    "-PT1H",
-			expected: -1 * time.Hour,
-			wantErr:  false,
-		},
-		{
-			name:     "positive 2 hours",
-			input:    "PT2H",
-			expected: 2 * time.Hour,
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: negative 0 days 0 hours 5 minutes 0 seconds",
-			input:    "-P0DT0H5M0S",
-			expected: -5 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: negative 0 days 1 hour 30 minutes 0 seconds",
-			input:    "-P0DT1H30M0S",
-			expected: -(1*time.Hour + 30*time.Minute),
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: positive 2 hours 15 minutes 30 seconds",
-			input:    "P0DT2H15M30S",
-			expected: 2*time.Hour + 15*time.Minute + 30*time.Second,
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: just minutes and seconds",
-			input:    "PT45M30S",
-			expected: 45*time.Minute + 30*time.Second,
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: just hours",
-			input:    "PT3H",
-			expected: 3 * time.Hour,
-			wantErr:  false,
-		},
-		{
-			name:     "complex format: just seconds",
-			input:    "PT120S",
-			expected: 120 * time.Second,
-			wantErr:  false,
-		},
-		{
-			name:     "unsupported format",
-			input:    "INVALID",
-			expected: 0,
-			wantErr:  true,
-		},
-		{
-			name:     "empty string",
-			input:    "",
-			expected: 0,
-			wantErr:  true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseICalDuration(tt.input)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("parseICalDuration() expected error for input %s, got nil", tt.input)
-				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("parseICalDuration() unexpected error for input %s: %v", tt.input, err)
-				return
-			}
 
-			if result != tt.expected {
-				t.Errorf("parseICalDuration() for input %s = %v, expected %v", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
-
-func TestParseInt(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected int
-	}{
-		{"123", 123},
-		{"0", 0},
-		{"5", 5},
-		{"999", 999},
-		{"", 0},
-		{"abc", 0}, // Invalid characters should return 0
-		{"12abc", 0}, // Mixed should return 0
-		{"0123", 123}, // Leading zeros should work
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := parseInt(tt.input)
-			if result != tt.expected {
-				t.Errorf("parseInt(%s) = %d, expected %d", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
 
 // TestConvertICSAlarmToInternalAlarm tests alarm conversion through iCal data parsing
 func TestConvertICSAlarmToInternalAlarm(t *testing.T) {
@@ -576,75 +437,6 @@ END:VCALENDAR`
 	}
 }
 
-// TestParseICalDurationEdgeCases tests additional edge cases for duration parsing
-func TestParseICalDurationEdgeCases(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected time.Duration
-		wantErr  bool
-	}{
-		{
-			name:     "zero duration",
-			input:    "PT0M",
-			expected: 0,
-			wantErr:  false,
-		},
-		{
-			name:     "very large duration",
-			input:    "PT9999M",
-			expected: 9999 * time.Minute,
-			wantErr:  false,
-		},
-		{
-			name:     "mixed components with zeros",
-			input:    "P0DT0H0M30S",
-			expected: 30 * time.Second,
-			wantErr:  false,
-		},
-		{
-			name:     "just 'P' (invalid)",
-			input:    "P",
-			expected: 0,
-			wantErr:  true,
-		},
-		{
-			name:     "just 'PT' (invalid)",
-			input:    "PT",
-			expected: 0,
-			wantErr:  true,
-		},
-		{
-			name:     "negative complex with all components",
-			input:    "-P1DT2H3M4S",
-			expected: -(2*time.Hour + 3*time.Minute + 4*time.Second), // Days are skipped in current implementation
-			wantErr:  false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseICalDuration(tt.input)
-
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("parseICalDuration() expected error for input %s, got nil", tt.input)
-				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("parseICalDuration() unexpected error for input %s: %v", tt.input, err)
-				return
-			}
-
-			if result != tt.expected {
-				t.Errorf("parseICalDuration() for input %s = %v, expected %v", tt.input, result, tt.expected)
-			}
-		})
-	}
-}
-
 // TestConvertICSAlarmEdgeCases tests edge cases for alarm conversion
 func TestConvertICSAlarmEdgeCases(t *testing.T) {
 	logger := slog.Default()
@@ -693,7 +485,7 @@ END:VCALENDAR`,
 			expectDefault:   false,
 		},
 		{
-			name: "alarm with absolute time trigger (should use default)",
+			name: "alarm with absolute time trigger",
 			icalData: `BEGIN:VCALENDAR
 VERSION:2.0
 BEGIN:VEVENT
@@ -707,9 +499,9 @@ ACTION:EMAIL
 END:VALARM
 END:VEVENT
 END:VCALENDAR`,
-			expectedMinutes: 15, // Default fallback
+			expectedMinutes: 0, // Absolute triggers carry a time, not a lead
 			expectedMethod:  "EMAIL",
-			expectDefault:   true,
+			expectDefault:   false,
 		},
 	}
 
@@ -839,4 +631,159 @@ END:VCALENDAR`
 			t.Errorf("Expected event missing from results: %s", expectedID)
 		}
 	}
-}
\ No newline at end of file
+}
+func TestParseICalDataSkipsMalformedTrigger(t *testing.T) {
+	icalData := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:trigger-test\r\n" +
+		"DTSTART:20240115T100000Z\r\nDTEND:20240115T110000Z\r\nSUMMARY:Test\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT10X\r\nEND:VALARM\r\n" +
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT5M\r\nEND:VALARM\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	calendar, err := ics.ParseCalendar(strings.NewReader(icalData))
+	if err != nil {
+		t.Fatalf("Failed to parse iCal data: %v", err)
+	}
+	event := calendar.Events()[0]
+	if _, err := ConvertICSAlarmToInternalAlarm(event.Alarms()[0], event, "test-calendar", slog.Default()); err == nil {
+		t.Error("ConvertICSAlarmToInternalAlarm() expected error for a malformed trigger duration")
+	}
+
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, from.Add(24*time.Hour), "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 || len(events[0].Alarms) != 1 || events[0].Alarms[0].LeadTimeMinutes != 5 {
+		t.Errorf("Expected only the valid 5 minute alarm, got %+v", events)
+	}
+}
+
+func TestConvertICSAlarmTriggerKinds(t *testing.T) {
+	logger := slog.Default()
+	absolute := time.Date(2024, 1, 15, 9, 45, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		trigger         string
+		expectedMinutes int
+		expectedRelated models.AlarmRelation
+		expectedAbs     *time.Time
+	}{
+		{
+			name:            "before start",
+			trigger:         "TRIGGER:-PT10M",
+			expectedMinutes: 10,
+		},
+		{
+			name:            "after start",
+			trigger:         "TRIGGER:PT5M",
+			expectedMinutes: -5,
+		},
+		{
+			name:            "before end",
+			trigger:         "TRIGGER;RELATED=END:-PT5M",
+			expectedMinutes: 5,
+			expectedRelated: models.AlarmRelatedEnd,
+		},
+		{
+			name:        "absolute with value parameter",
+			trigger:     "TRIGGER;VALUE=DATE-TIME:20240115T094500Z",
+			expectedAbs: &absolute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icalData := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:trigger-test\r\n" +
+				"DTSTART:20240115T100000Z\r\nDTEND:20240115T110000Z\r\nSUMMARY:Test\r\n" +
+				"BEGIN:VALARM\r\nACTION:DISPLAY\r\n" + tt.trigger + "\r\nEND:VALARM\r\n" +
+				"END:VEVENT\r\nEND:VCALENDAR\r\n"
+
+			calendar, err := ics.ParseCalendar(strings.NewReader(icalData))
+			if err != nil {
+				t.Fatalf("Failed to parse iCal data: %v", err)
+			}
+			event := calendar.Events()[0]
+
+			result, err := ConvertICSAlarmToInternalAlarm(event.Alarms()[0], event, "test-calendar", logger)
+			if err != nil {
+				t.Fatalf("ConvertICSAlarmToInternalAlarm() unexpected error: %v", err)
+			}
+
+			if result.LeadTimeMinutes != tt.expectedMinutes {
+				t.Errorf("LeadTimeMinutes = %d, expected %d", result.LeadTimeMinutes, tt.expectedMinutes)
+			}
+			if result.RelatedTo != tt.expectedRelated {
+				t.Errorf("RelatedTo = %q, expected %q", result.RelatedTo, tt.expectedRelated)
+			}
+			if tt.expectedAbs == nil {
+				if result.AbsoluteTime != nil {
+					t.Errorf("AbsoluteTime = %v, expected nil", result.AbsoluteTime)
+				}
+			} else if result.AbsoluteTime == nil || !result.AbsoluteTime.Equal(*tt.expectedAbs) {
+				t.Errorf("AbsoluteTime = %v, expected %v", result.AbsoluteTime, tt.expectedAbs)
+			}
+		})
+	}
+}
+
+func TestParseICalDataStatusAndTransparency(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:confirmed@example.com
+DTSTART:20240115T100000Z
+DTEND:20240115T110000Z
+STATUS:CONFIRMED
+TRANSP:OPAQUE
+SUMMARY:Confirmed
+END:VEVENT
+BEGIN:VEVENT
+UID:cancelled@example.com
+DTSTART:20240115T120000Z
+DTEND:20240115T130000Z
+STATUS:CANCELLED
+SUMMARY:Canceled: Sync
+END:VEVENT
+BEGIN:VEVENT
+UID:transparent@example.com
+DTSTART:20240115T140000Z
+DTEND:20240115T150000Z
+TRANSP:TRANSPARENT
+SUMMARY:Reminder
+END:VEVENT
+BEGIN:VEVENT
+UID:free@example.com
+DTSTART:20240115T160000Z
+DTEND:20240115T170000Z
+X-MICROSOFT-CDO-BUSYSTATUS:FREE
+SUMMARY:Lunch
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	expected := map[string][2]string{
+		"confirmed@example.com":   {models.EventStatusConfirmed, models.TransparencyOpaque},
+		"cancelled@example.com":   {models.EventStatusCancelled, ""},
+		"transparent@example.com": {"", models.TransparencyTransparent},
+		"free@example.com":        {"", models.TransparencyTransparent},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for _, event := range events {
+		want := expected[event.ID]
+		if event.Status != want[0] || event.Transparency != want[1] {
+			t.Errorf("Event %s status/transparency = %q/%q, expected %q/%q",
+				event.ID, event.Status, event.Transparency, want[0], want[1])
+		}
+	}
+}
@@ -1,13 +1,18 @@
 package ical
 
 import (
+	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	ics "github.com/arran4/golang-ical"
+
 	"github.com/venkytv/calendar-notifier/internal/models"
 	calendarPkg "github.com/venkytv/calendar-notifier/pkg/calendar"
 	"github.com/venkytv/calendar-notifier/pkg/retry"
@@ -15,11 +20,27 @@ import (
 
 // Provider is an iCal provider using the arran4/golang-ical library
 type Provider struct {
-	name    string
-	url     string
-	client  *http.Client
-	logger  *slog.Logger
-	retryer *retry.Retryer
+	name     string
+	url      string
+	location *time.Location
+	client   *http.Client
+	logger   *slog.Logger
+	retryer  *retry.Retryer
+
+	// Last successfully parsed feed and the validators it was served with, for
+	// conditional requests
+	mu           sync.Mutex
+	calendar     *ics.Calendar
+	etag         string
+	lastModified string
+}
+
+// feedResponse is the result of fetching the feed
+type feedResponse struct {
+	data         string
+	etag         string
+	lastModified string
+	notModified  bool // 304: the cached calendar is still current
 }
 
 // NewProvider creates a new iCal provider using arran4/golang-ical
@@ -103,6 +124,25 @@ func (p *Provider) SetLogger(logger *slog.Logger) {
 	}
 }
 
+// SetTimeZone sets the IANA time zone used for floating times and all-day dates
+// (defaults to the local time zone)
+func (p *Provider) SetTimeZone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid iCal time zone %q: %v", name, err)
+	}
+	p.location = loc
+	return nil
+}
+
+// SetHTTPClient replaces the client used to download the feed, e.g. with one
+// from caldav.NewHTTPClient for private feeds that need authentication
+func (p *Provider) SetHTTPClient(client *http.Client) {
+	if client != nil {
+		p.client = client
+	}
+}
+
 // Initialize sets up the iCal provider with the URL
 func (p *Provider) Initialize(ctx context.Context, url string) error {
 	if url == "" {
@@ -120,24 +160,45 @@ func (p *Provider) GetEvents(ctx context.Context, calendarIDs []string, from, to
 		return nil, fmt.Errorf("iCal provider not initialized")
 	}
 
-	// Fetch iCal data
-	icalData, err := p.fetchICalData(ctx)
+	calendar, err := p.loadCalendar(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch iCal data: %v", err)
+		return nil, err
 	}
 
-	// Parse iCal data using shared parser
 	// Pass empty string for userEmail as iCal feeds don't have authentication
-	events, err := ParseICalData(icalData, p.url, "iCal Calendar", from, to, "", p.logger)
+	return CalendarEvents(calendar, p.url, "iCal Calendar", from, to, "", p.location, p.logger), nil
+}
+
+// loadCalendar returns the parsed feed, downloading and parsing it again only if
+// the server reports that it changed
+func (p *Provider) loadCalendar(ctx context.Context) (*ics.Calendar, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	resp, err := p.fetchICalData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iCal data: %v", err)
+	}
+
+	if resp.notModified {
+		p.logger.Debug("iCal feed not modified, reusing parsed calendar", "url", p.url)
+		return p.calendar, nil
+	}
+
+	calendar, err := ics.ParseCalendar(strings.NewReader(resp.data))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse iCal data: %v", err)
 	}
 
-	return events, nil
+	p.calendar = calendar
+	p.etag = resp.etag
+	p.lastModified = resp.lastModified
+	return calendar, nil
 }
 
-// fetchICalData retrieves iCal data from the URL with retry logic
-func (p *Provider) fetchICalData(ctx context.Context) (string, error) {
+// fetchICalData retrieves iCal data from the URL with retry logic. Once a feed
+// has been parsed, the request is conditional on its ETag and Last-Modified.
+func (p *Provider) fetchICalData(ctx context.Context) (*feedResponse, error) {
 	operation := func() (interface{}, error) {
 		req, err := http.NewRequestWithContext(ctx, "GET", p.url, nil)
 		if err != nil {
@@ -145,7 +206,16 @@ func (p *Provider) fetchICalData(ctx context.Context) (string, error) {
 		}
 
 		req.Header.Set("Accept", "text/calendar,application/calendar")
+		req.Header.Set("Accept-Encoding", "gzip")
 		req.Header.Set("User-Agent", "calendar-notifier/1.0")
+		if p.calendar != nil {
+			if p.etag != "" {
+				req.Header.Set("If-None-Match", p.etag)
+			}
+			if p.lastModified != "" {
+				req.Header.Set("If-Modified-Since", p.lastModified)
+			}
+		}
 
 		p.logger.Debug("Fetching iCal data", "url", p.url)
 
@@ -155,6 +225,10 @@ func (p *Provider) fetchICalData(ctx context.Context) (string, error) {
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusNotModified && p.calendar != nil {
+			return &feedResponse{notModified: true}, nil
+		}
+
 		if resp.StatusCode != http.StatusOK {
 			// Create HTTP error for proper retry classification
 			httpErr := retry.NewHTTPError(resp.StatusCode, resp.Status, p.url)
@@ -165,16 +239,33 @@ func (p *Provider) fetchICalData(ctx context.Context) (string, error) {
 			return nil, httpErr
 		}
 
-		body, err := io.ReadAll(resp.Body)
+		// Setting Accept-Encoding ourselves turns off net/http's transparent
+		// decompression, so gzip bodies are decoded here
+		var reader io.Reader = resp.Body
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress response body: %v", err)
+			}
+			defer gz.Close()
+			reader = gz
+		}
+
+		body, err := io.ReadAll(reader)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read response body: %v", err)
 		}
 
 		p.logger.Debug("Successfully fetched iCal data",
 			"url", p.url,
-			"content_length", len(body))
-
-		return string(body), nil
+			"content_length", len(body),
+			"content_encoding", resp.Header.Get("Content-Encoding"))
+
+		return &feedResponse{
+			data:         string(body),
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}, nil
 	}
 
 	result, err := p.retryer.DoWithResult(ctx, operation)
@@ -182,10 +273,10 @@ func (p *Provider) fetchICalData(ctx context.Context) (string, error) {
 		p.logger.Error("Failed to fetch iCal data after retries",
 			"url", p.url,
 			"error", err)
-		return "", err
+		return nil, err
 	}
 
-	return result.(string), nil
+	return result.(*feedResponse), nil
 }
 
 // GetCalendars returns a basic calendar list
@@ -206,14 +297,14 @@ func (p *Provider) GetCalendars(ctx context.Context) ([]*calendarPkg.Calendar, e
 	return []*calendarPkg.Calendar{calendar}, nil
 }
 
-// IsHealthy performs a health check by attempting to fetch calendar data
+// IsHealthy performs a health check by attempting to fetch calendar data. The
+// request is conditional, so an unchanged feed is not downloaded again.
 func (p *Provider) IsHealthy(ctx context.Context) error {
 	if p.url == "" {
 		return fmt.Errorf("iCal provider not initialized")
 	}
 
-	// Try to fetch data as a health check
-	_, err := p.fetchICalData(ctx)
+	_, err := p.loadCalendar(ctx)
 	if err != nil {
 		return fmt.Errorf("iCal health check failed: %v", err)
 	}
@@ -1,10 +1,13 @@
 package ical
 
 import (
+	"compress/gzip"
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 )
@@ -127,6 +130,82 @@ END:VCALENDAR`
 	}
 }
 
+func TestProvider_ConditionalFetch(t *testing.T) {
+	feed := func(title string) string {
+		return "BEGIN:VCALENDAR\nVERSION:2.0\nPRODID:-//Test//Test//EN\nBEGIN:VEVENT\nUID:standup@example.com\n" +
+			"DTSTART:20251001T100000Z\nDTEND:20251001T103000Z\nRRULE:FREQ=DAILY\nSUMMARY:" + title + "\nEND:VEVENT\nEND:VCALENDAR"
+	}
+
+	var mu sync.Mutex
+	version, downloads, notModified := 1, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Expected Accept-Encoding gzip, got %q", r.Header.Get("Accept-Encoding"))
+		}
+
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Wed, 01 Oct 2025 08:00:00 GMT")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(feed(fmt.Sprintf("Standup v%d", version))))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	provider := NewProvider()
+	if err := provider.Initialize(context.Background(), server.URL); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	poll := func(day int) string {
+		t.Helper()
+		from := time.Date(2025, 10, day, 0, 0, 0, 0, time.UTC)
+		events, err := provider.GetEvents(context.Background(), nil, from, from.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("GetEvents() unexpected error: %v", err)
+		}
+		if len(events) != 1 || !events[0].StartTime.Equal(from.Add(10*time.Hour)) {
+			t.Fatalf("Expected the occurrence on October %d, got %v", day, events)
+		}
+		return events[0].Title
+	}
+
+	if title := poll(1); title != "Standup v1" {
+		t.Errorf("Expected Standup v1, got %s", title)
+	}
+
+	// An unchanged feed is not downloaded again, but later windows still expand
+	if title := poll(2); title != "Standup v1" {
+		t.Errorf("Expected Standup v1 from the cached calendar, got %s", title)
+	}
+	if err := provider.IsHealthy(context.Background()); err != nil {
+		t.Errorf("IsHealthy() unexpected error: %v", err)
+	}
+	if downloads != 1 || notModified != 2 {
+		t.Errorf("Expected 1 download and 2 not-modified replies, got %d and %d", downloads, notModified)
+	}
+
+	mu.Lock()
+	version = 2
+	mu.Unlock()
+	if title := poll(3); title != "Standup v2" {
+		t.Errorf("Expected the changed feed, got %s", title)
+	}
+	if downloads != 2 {
+		t.Errorf("Expected the changed feed to be downloaded, got %d downloads", downloads)
+	}
+}
+
 func TestProvider_GetEvents_NotInitialized(t *testing.T) {
 	provider := NewProvider()
 	from := time.Now()
@@ -219,15 +298,43 @@ func TestProvider_fetchICalData(t *testing.T) {
 	provider := NewProvider()
 	provider.url = server.URL
 
-	data, err := provider.fetchICalData(context.Background())
+	resp, err := provider.fetchICalData(context.Background())
 	if err != nil {
-		t.Errorf("Unexpected error: %v", err)
+		t.Fatalf("Unexpected error: %v", err)
 	}
-	if data != testData {
-		t.Errorf("Expected data '%s', got '%s'", testData, data)
+	if resp.data != testData {
+		t.Errorf("Expected data '%s', got '%s'", testData, resp.data)
 	}
 }
 
+func TestProvider_SetHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Feed-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("BEGIN:VCALENDAR\nVERSION:2.0\nEND:VCALENDAR"))
+	}))
+	defer server.Close()
+
+	provider := NewProvider()
+	provider.url = server.URL
+	provider.SetHTTPClient(&http.Client{Transport: headerTransport{"X-Feed-Key", "secret"}})
+
+	if _, err := provider.fetchICalData(context.Background()); err != nil {
+		t.Errorf("Expected the configured client to be used, got error: %v", err)
+	}
+}
+
+// headerTransport adds a fixed header to every request
+type headerTransport [2]string
+
+func (h headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(h[0], h[1])
+	return http.DefaultTransport.RoundTrip(req)
+}
+
 func TestProvider_fetchICalData_ContextCancellation(t *testing.T) {
 	// Create a server that never responds
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,498 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of an RRULE
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// maxRecurrencePeriods bounds the number of FREQ periods walked while expanding
+// a single rule, so a malformed rule can never spin forever
+const maxRecurrencePeriods = 100000
+
+// WeekdayNum is a single BYDAY entry such as "MO", "2TU" or "-1FR"
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int // Ordinal within the month/year; 0 means every matching weekday
+}
+
+// RecurrenceRule is a parsed RFC 5545 RRULE
+type RecurrenceRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int       // 0 means unbounded
+	Until      time.Time // Zero means unbounded
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRecurrenceRule parses an RRULE value (e.g. "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10").
+// loc is used to interpret a floating UNTIL value.
+func ParseRecurrenceRule(rule string, loc *time.Location) (*RecurrenceRule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	r := &RecurrenceRule{
+		Interval:  1,
+		WeekStart: time.Monday,
+	}
+
+	for _, part := range strings.Split(strings.TrimSpace(rule), ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				r.Freq = Frequency(value)
+			default:
+				return nil, fmt.Errorf("unsupported RRULE frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseTimeValue(value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE UNTIL %q: %w", value, err)
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, item := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			days, err := parseIntList(value, -31, 31)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYMONTHDAY %q: %w", value, err)
+			}
+			r.ByMonthDay = days
+		case "BYMONTH":
+			months, err := parseIntList(value, 1, 12)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYMONTH %q: %w", value, err)
+			}
+			for _, m := range months {
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			positions, err := parseIntList(value, -366, 366)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE BYSETPOS %q: %w", value, err)
+			}
+			r.BySetPos = positions
+		case "WKST":
+			wd, ok := weekdayCodes[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid RRULE WKST %q", value)
+			}
+			r.WeekStart = wd
+		default:
+			// BYHOUR, BYMINUTE, BYWEEKNO etc. are not needed for meeting reminders
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("RRULE is missing FREQ")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("RRULE must not contain both COUNT and UNTIL")
+	}
+
+	return r, nil
+}
+
+// parseWeekdayNum parses a BYDAY entry such as "MO", "2TU" or "-1FR"
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid RRULE BYDAY %q", s)
+	}
+
+	wd, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid RRULE BYDAY %q", s)
+	}
+
+	var n int
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid RRULE BYDAY %q", s)
+		}
+	}
+
+	return WeekdayNum{Weekday: wd, N: n}, nil
+}
+
+// parseIntList parses a comma separated list of non-zero integers within [min, max]
+func parseIntList(s string, min, max int) ([]int, error) {
+	var result []int
+	for _, item := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("value %d out of range", n)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+// Occurrences returns the start times of the series beginning at dtstart, in order,
+// stopping at COUNT, UNTIL or the first occurrence at or after before.
+// DTSTART is always the first occurrence, as required by RFC 5545.
+func (r *RecurrenceRule) Occurrences(dtstart, before time.Time) []time.Time {
+	var result []time.Time
+	if !dtstart.Before(before) {
+		return result
+	}
+
+	result = append(result, dtstart)
+	if r.Count == 1 {
+		return result
+	}
+
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		periodStart, candidates := r.periodCandidates(dtstart, period)
+		if !periodStart.Before(before) {
+			break
+		}
+
+		for _, candidate := range candidates {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && candidate.After(r.Until) {
+				return result
+			}
+			if !candidate.Before(before) {
+				return result
+			}
+
+			result = append(result, candidate)
+			if r.Count > 0 && len(result) >= r.Count {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+// periodCandidates returns the first instant of the given period together with the
+// sorted candidate occurrences that the BYxxx parts produce within it
+func (r *RecurrenceRule) periodCandidates(dtstart time.Time, period int) (time.Time, []time.Time) {
+	loc := dtstart.Location()
+	year, month, day := dtstart.Date()
+	hour, min, sec := dtstart.Clock()
+	step := period * r.Interval
+
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hour, min, sec, 0, loc)
+	}
+
+	var periodStart time.Time
+	var days []time.Time
+
+	switch r.Freq {
+	case FrequencyDaily:
+		periodStart = time.Date(year, month, day+step, 0, 0, 0, 0, loc)
+		y, m, d := periodStart.Date()
+		if r.matchesLimits(y, m, d) {
+			days = append(days, at(y, m, d))
+		}
+
+	case FrequencyWeekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		periodStart = time.Date(year, month, day-offset+7*step, 0, 0, 0, 0, loc)
+		y, m, d := periodStart.Date()
+
+		weekdays := []time.Weekday{dtstart.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, wd := range r.ByDay {
+				weekdays = append(weekdays, wd.Weekday)
+			}
+		}
+		for _, wd := range weekdays {
+			candidate := at(y, m, d+(int(wd)-int(r.WeekStart)+7)%7)
+			if r.monthAllowed(candidate.Month()) {
+				days = append(days, candidate)
+			}
+		}
+
+	case FrequencyMonthly:
+		periodStart = time.Date(year, month+time.Month(step), 1, 0, 0, 0, 0, loc)
+		y, m, _ := periodStart.Date()
+		if r.monthAllowed(m) {
+			for _, d := range r.monthDays(y, m, day) {
+				days = append(days, at(y, m, d))
+			}
+		}
+
+	case FrequencyYearly:
+		periodStart = time.Date(year+step, time.January, 1, 0, 0, 0, 0, loc)
+		y := periodStart.Year()
+
+		switch {
+		case len(r.ByMonth) > 0:
+			for _, m := range r.ByMonth {
+				for _, d := range r.monthDays(y, m, day) {
+					days = append(days, at(y, m, d))
+				}
+			}
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				for _, d := range r.monthDays(y, m, day) {
+					days = append(days, at(y, m, d))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for _, yd := range r.yearWeekdays(y) {
+				days = append(days, at(y, time.January, yd))
+			}
+		default:
+			if day <= daysIn(y, month) {
+				days = append(days, at(y, month, day))
+			}
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	days = dedupeTimes(days)
+
+	return periodStart, r.applySetPos(days)
+}
+
+// matchesLimits applies BYMONTH, BYMONTHDAY and BYDAY as filters (used for DAILY)
+func (r *RecurrenceRule) matchesLimits(y int, m time.Month, d int) bool {
+	if !r.monthAllowed(m) {
+		return false
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		n := daysIn(y, m)
+		found := false
+		for _, md := range r.ByMonthDay {
+			if resolveMonthDay(md, n) == d {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(r.ByDay) > 0 {
+		wd := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Weekday()
+		found := false
+		for _, bd := range r.ByDay {
+			if bd.Weekday == wd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// monthAllowed reports whether BYMONTH permits the given month
+func (r *RecurrenceRule) monthAllowed(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays expands BYMONTHDAY/BYDAY within one month; without either it falls
+// back to the day of month of DTSTART
+func (r *RecurrenceRule) monthDays(y int, m time.Month, dtstartDay int) []int {
+	n := daysIn(y, m)
+	var result []int
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			d := resolveMonthDay(md, n)
+			if d < 1 {
+				continue
+			}
+			if len(r.ByDay) > 0 && !r.weekdayListed(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Weekday()) {
+				continue
+			}
+			result = append(result, d)
+		}
+
+	case len(r.ByDay) > 0:
+		first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Weekday()
+		for _, bd := range r.ByDay {
+			var matches []int
+			for d := 1 + (int(bd.Weekday)-int(first)+7)%7; d <= n; d += 7 {
+				matches = append(matches, d)
+			}
+			result = append(result, pickOrdinal(matches, bd.N)...)
+		}
+
+	default:
+		if dtstartDay <= n {
+			result = append(result, dtstartDay)
+		}
+	}
+
+	return result
+}
+
+// yearWeekdays expands BYDAY within a whole year, returning days of the year
+func (r *RecurrenceRule) yearWeekdays(y int) []int {
+	n := time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	first := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC).Weekday()
+
+	var result []int
+	for _, bd := range r.ByDay {
+		var matches []int
+		for d := 1 + (int(bd.Weekday)-int(first)+7)%7; d <= n; d += 7 {
+			matches = append(matches, d)
+		}
+		result = append(result, pickOrdinal(matches, bd.N)...)
+	}
+	return result
+}
+
+// weekdayListed reports whether the weekday appears in BYDAY
+func (r *RecurrenceRule) weekdayListed(wd time.Weekday) bool {
+	for _, bd := range r.ByDay {
+		if bd.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// applySetPos keeps only the BYSETPOS positions of the sorted candidate set
+func (r *RecurrenceRule) applySetPos(candidates []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(candidates) == 0 {
+		return candidates
+	}
+
+	var result []time.Time
+	for _, pos := range r.BySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(candidates) + pos
+		}
+		if idx >= 0 && idx < len(candidates) {
+			result = append(result, candidates[idx])
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return dedupeTimes(result)
+}
+
+// pickOrdinal selects the n-th entry (1-based, negative from the end); 0 selects all
+func pickOrdinal(matches []int, n int) []int {
+	if n == 0 {
+		return matches
+	}
+	idx := n - 1
+	if n < 0 {
+		idx = len(matches) + n
+	}
+	if idx < 0 || idx >= len(matches) {
+		return nil
+	}
+	return []int{matches[idx]}
+}
+
+// resolveMonthDay converts a possibly negative BYMONTHDAY to a day of month,
+// returning 0 if the month is too short
+func resolveMonthDay(md, n int) int {
+	if md < 0 {
+		md = n + md + 1
+	}
+	if md < 1 || md > n {
+		return 0
+	}
+	return md
+}
+
+// daysIn returns the number of days in the given month
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// dedupeTimes removes adjacent duplicates from a sorted slice
+func dedupeTimes(times []time.Time) []time.Time {
+	if len(times) < 2 {
+		return times
+	}
+	result := times[:1]
+	for _, t := range times[1:] {
+		if !t.Equal(result[len(result)-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,457 @@
+package ical
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+		check   func(t *testing.T, r *RecurrenceRule)
+	}{
+		{
+			name:  "weekly with byday",
+			input: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			check: func(t *testing.T, r *RecurrenceRule) {
+				if r.Freq != FrequencyWeekly || r.Interval != 2 || len(r.ByDay) != 2 {
+					t.Errorf("unexpected rule: %+v", r)
+				}
+			},
+		},
+		{
+			name:  "monthly with ordinal weekday",
+			input: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			check: func(t *testing.T, r *RecurrenceRule) {
+				if r.ByDay[0].Weekday != time.Friday || r.ByDay[0].N != -1 || r.Count != 3 {
+					t.Errorf("unexpected rule: %+v", r)
+				}
+			},
+		},
+		{
+			name:  "until in UTC",
+			input: "FREQ=DAILY;UNTIL=20240120T100000Z",
+			check: func(t *testing.T, r *RecurrenceRule) {
+				if !r.Until.Equal(time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC)) {
+					t.Errorf("unexpected until: %v", r.Until)
+				}
+			},
+		},
+		{name: "missing freq", input: "INTERVAL=2", wantErr: true},
+		{name: "unsupported freq", input: "FREQ=SECONDLY", wantErr: true},
+		{name: "invalid interval", input: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "invalid byday", input: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "count and until", input: "FREQ=DAILY;COUNT=2;UNTIL=20240120", wantErr: true},
+		{name: "malformed part", input: "FREQ=DAILY;COUNT", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRecurrenceRule(tt.input, time.UTC)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseRecurrenceRule(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q) unexpected error: %v", tt.input, err)
+			}
+			tt.check(t, r)
+		})
+	}
+}
+
+func TestRecurrenceRuleOccurrences(t *testing.T) {
+	utc := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  time.Time
+		before   time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "daily with count",
+			rule:     "FREQ=DAILY;COUNT=3",
+			dtstart:  utc(2024, 1, 1, 9),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 1, 9), utc(2024, 1, 2, 9), utc(2024, 1, 3, 9)},
+		},
+		{
+			name:     "daily with until",
+			rule:     "FREQ=DAILY;INTERVAL=2;UNTIL=20240105T090000Z",
+			dtstart:  utc(2024, 1, 1, 9),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 1, 9), utc(2024, 1, 3, 9), utc(2024, 1, 5, 9)},
+		},
+		{
+			name:    "weekly on monday and wednesday",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,WE",
+			dtstart: utc(2024, 1, 1, 10), // Monday
+			before:  utc(2024, 1, 15, 0),
+			expected: []time.Time{
+				utc(2024, 1, 1, 10), utc(2024, 1, 3, 10),
+				utc(2024, 1, 8, 10), utc(2024, 1, 10, 10),
+			},
+		},
+		{
+			name:     "biweekly without byday",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;COUNT=3",
+			dtstart:  utc(2024, 1, 4, 10), // Thursday
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 4, 10), utc(2024, 1, 18, 10), utc(2024, 2, 1, 10)},
+		},
+		{
+			name:     "monthly on second tuesday",
+			rule:     "FREQ=MONTHLY;BYDAY=2TU;COUNT=3",
+			dtstart:  utc(2024, 1, 9, 15),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 9, 15), utc(2024, 2, 13, 15), utc(2024, 3, 12, 15)},
+		},
+		{
+			name:     "monthly on last day",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart:  utc(2024, 1, 31, 12),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 31, 12), utc(2024, 2, 29, 12), utc(2024, 3, 31, 12)},
+		},
+		{
+			name:     "monthly skips short months",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			dtstart:  utc(2024, 1, 31, 12),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 31, 12), utc(2024, 3, 31, 12), utc(2024, 5, 31, 12)},
+		},
+		{
+			name:     "last weekday of month via bysetpos",
+			rule:     "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3",
+			dtstart:  utc(2024, 1, 31, 17),
+			before:   utc(2025, 1, 1, 0),
+			expected: []time.Time{utc(2024, 1, 31, 17), utc(2024, 2, 29, 17), utc(2024, 3, 29, 17)},
+		},
+		{
+			name:     "yearly second sunday of march",
+			rule:     "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU;COUNT=2",
+			dtstart:  utc(2024, 3, 10, 2),
+			before:   utc(2030, 1, 1, 0),
+			expected: []time.Time{utc(2024, 3, 10, 2), utc(2025, 3, 9, 2)},
+		},
+		{
+			name:     "stops at before",
+			rule:     "FREQ=DAILY",
+			dtstart:  utc(2024, 1, 1, 9),
+			before:   utc(2024, 1, 3, 9),
+			expected: []time.Time{utc(2024, 1, 1, 9), utc(2024, 1, 2, 9)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseRecurrenceRule(%q) unexpected error: %v", tt.rule, err)
+			}
+
+			result := rule.Occurrences(tt.dtstart, tt.before)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Occurrences() returned %d times %v, expected %d", len(result), result, len(tt.expected))
+			}
+			for i := range result {
+				if !result[i].Equal(tt.expected[i]) {
+					t.Errorf("occurrence %d = %v, expected %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceRuleOccurrencesKeepsWallClockAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	rule, err := ParseRecurrenceRule("FREQ=WEEKLY;COUNT=2", loc)
+	if err != nil {
+		t.Fatalf("ParseRecurrenceRule() unexpected error: %v", err)
+	}
+
+	// DST starts on 2024-03-10 in New York
+	dtstart := time.Date(2024, 3, 5, 9, 0, 0, 0, loc)
+	result := rule.Occurrences(dtstart, dtstart.AddDate(1, 0, 0))
+	if len(result) != 2 {
+		t.Fatalf("expected 2 occurrences, got %d", len(result))
+	}
+	if result[1].Hour() != 9 {
+		t.Errorf("expected second occurrence at 09:00 local, got %v", result[1])
+	}
+}
+
+func TestParseICalDataRecurringEvents(t *testing.T) {
+	logger := slog.Default()
+
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTART:20230102T090000Z
+DTEND:20230102T091500Z
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR
+SUMMARY:Standup
+BEGIN:VALARM
+TRIGGER:-PT5M
+ACTION:DISPLAY
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:extra@example.com
+DTSTART:20240110T140000Z
+DTEND:20240110T150000Z
+RDATE:20240116T140000Z,20240301T140000Z
+SUMMARY:Extra Sessions
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", logger)
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	expectedIDs := map[string]time.Time{
+		"standup@example.com_20240115T090000Z": time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC),
+		"standup@example.com_20240117T090000Z": time.Date(2024, 1, 17, 9, 0, 0, 0, time.UTC),
+		"standup@example.com_20240119T090000Z": time.Date(2024, 1, 19, 9, 0, 0, 0, time.UTC),
+		"extra@example.com_20240116T140000Z":   time.Date(2024, 1, 16, 14, 0, 0, 0, time.UTC),
+	}
+
+	if len(events) != len(expectedIDs) {
+		t.Fatalf("Expected %d events, got %d", len(expectedIDs), len(events))
+	}
+
+	for _, event := range events {
+		start, ok := expectedIDs[event.ID]
+		if !ok {
+			t.Errorf("Unexpected event in results: %s", event.ID)
+			continue
+		}
+		if !event.StartTime.Equal(start) {
+			t.Errorf("Event %s start = %v, expected %v", event.ID, event.StartTime, start)
+		}
+		if strings.HasPrefix(event.ID, "standup") {
+			if event.EndTime.Sub(event.StartTime) != 15*time.Minute {
+				t.Errorf("Event %s duration = %v, expected 15m", event.ID, event.EndTime.Sub(event.StartTime))
+			}
+			if len(event.Alarms) != 1 || event.Alarms[0].LeadTimeMinutes != 5 {
+				t.Errorf("Event %s expected the series alarm to be copied, got %+v", event.ID, event.Alarms)
+			}
+		}
+	}
+}
+
+func TestParseICalDataAllDayOccurrenceID(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:payday@example.com
+DTSTART;VALUE=DATE:20240101
+DTEND;VALUE=DATE:20240102
+RRULE:FREQ=MONTHLY;BYMONTHDAY=15
+SUMMARY:Payday
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].ID != "payday@example.com_20240315" {
+		t.Errorf("Expected date-based occurrence ID, got %s", events[0].ID)
+	}
+}
+
+func TestParseICalDataRecurrenceExceptions(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:sync@example.com
+DTSTART:20240101T100000Z
+DTEND:20240101T103000Z
+RRULE:FREQ=DAILY
+EXDATE:20240116T100000Z
+SUMMARY:Daily Sync
+END:VEVENT
+BEGIN:VEVENT
+UID:sync@example.com
+RECURRENCE-ID:20240117T100000Z
+DTSTART:20240117T150000Z
+DTEND:20240117T153000Z
+SUMMARY:Daily Sync (moved)
+END:VEVENT
+BEGIN:VEVENT
+UID:sync@example.com
+RECURRENCE-ID:20240118T100000Z
+DTSTART:20240118T100000Z
+DTEND:20240118T103000Z
+STATUS:CANCELLED
+SUMMARY:Daily Sync
+END:VEVENT
+BEGIN:VEVENT
+UID:sync@example.com
+RECURRENCE-ID:20240110T100000Z
+DTSTART:20240119T080000Z
+DTEND:20240119T083000Z
+SUMMARY:Daily Sync (moved into window)
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Time{
+		"sync@example.com_20240115T100000Z": time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+		// 16th removed by EXDATE
+		"sync@example.com_20240117T100000Z": time.Date(2024, 1, 17, 15, 0, 0, 0, time.UTC), // moved
+		"sync@example.com_20240118T100000Z": time.Date(2024, 1, 18, 10, 0, 0, 0, time.UTC), // cancelled by override
+		"sync@example.com_20240119T100000Z": time.Date(2024, 1, 19, 10, 0, 0, 0, time.UTC),
+		"sync@example.com_20240110T100000Z": time.Date(2024, 1, 19, 8, 0, 0, 0, time.UTC), // moved from outside the window
+	}
+
+	if len(events) != len(expected) {
+		for _, event := range events {
+			t.Logf("got %s at %v", event.ID, event.StartTime)
+		}
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+
+	for _, event := range events {
+		start, ok := expected[event.ID]
+		if !ok {
+			t.Errorf("Unexpected event in results: %s", event.ID)
+			continue
+		}
+		if !event.StartTime.Equal(start) {
+			t.Errorf("Event %s start = %v, expected %v", event.ID, event.StartTime, start)
+		}
+		if cancelled := event.ID == "sync@example.com_20240118T100000Z"; event.IsCancelled() != cancelled {
+			t.Errorf("Event %s IsCancelled() = %v, expected %v", event.ID, event.IsCancelled(), cancelled)
+		}
+	}
+}
+
+func TestParseICalDataExdateWithTZID(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:review@example.com
+DTSTART;TZID=Europe/Berlin:20240101T090000
+DTEND;TZID=Europe/Berlin:20240101T100000
+RRULE:FREQ=WEEKLY
+EXDATE;TZID=Europe/Berlin:20240115T090000,20240122T090000
+SUMMARY:Weekly Review
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event after EXDATE, got %d", len(events))
+	}
+	if events[0].ID != "review@example.com_20240129T080000Z" {
+		t.Errorf("Expected remaining occurrence on Jan 29, got %s", events[0].ID)
+	}
+}
+
+func TestParseICalDataMalformedExdateIsIgnored(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:review@example.com
+DTSTART:20240115T090000Z
+DTEND:20240115T100000Z
+RRULE:FREQ=WEEKLY;COUNT=3
+EXDATE:20240122T090000Z
+EXDATE:not-a-date
+SUMMARY:Weekly Review
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	// The valid EXDATE still applies; the malformed one is skipped
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	expected := []string{"review@example.com_20240115T090000Z", "review@example.com_20240129T090000Z"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected occurrences %v, got %v", expected, ids)
+	}
+}
+
+func TestParseICalDataUnsupportedRuleKeepsFirstOccurrence(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:hourly@example.com
+DTSTART:20240115T090000Z
+DTEND:20240115T091500Z
+RRULE:FREQ=HOURLY;COUNT=3
+SUMMARY:Hourly Check
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 || !events[0].StartTime.Equal(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the DTSTART occurrence only, got %+v", events)
+	}
+	if events[0].ID != "hourly@example.com_20240115T090000Z" {
+		t.Errorf("Expected an occurrence ID, got %s", events[0].ID)
+	}
+}
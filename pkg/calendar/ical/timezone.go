@@ -0,0 +1,401 @@
+package ical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+// vtimezoneHorizon is how far ahead transitions of an embedded VTIMEZONE are generated
+var vtimezoneHorizon = time.Date(2038, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// timezoneResolver resolves TZID parameters of a single feed to time.Locations.
+// Lookups are cached, and each unresolvable TZID is logged once.
+type timezoneResolver struct {
+	defaultLoc *time.Location
+	embedded   map[string]*ics.VTimezone
+	cache      map[string]*time.Location
+	failed     map[string]error
+	logger     *slog.Logger
+}
+
+// newTimezoneResolver creates a resolver for the VTIMEZONE blocks of cal (which may be nil).
+// Floating times are interpreted in defaultLoc, or the local zone if it is nil.
+func newTimezoneResolver(cal *ics.Calendar, defaultLoc *time.Location, logger *slog.Logger) *timezoneResolver {
+	if defaultLoc == nil {
+		defaultLoc = time.Local
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	r := &timezoneResolver{
+		defaultLoc: defaultLoc,
+		embedded:   make(map[string]*ics.VTimezone),
+		cache:      make(map[string]*time.Location),
+		failed:     make(map[string]error),
+		logger:     logger,
+	}
+
+	if cal != nil {
+		for _, vtz := range cal.Timezones() {
+			if tzid := vtz.GetProperty(ics.ComponentPropertyTzid); tzid != nil {
+				r.embedded[normalizeTZID(tzid.Value)] = vtz
+			}
+		}
+	}
+
+	return r
+}
+
+// location returns the time.Location for a TZID parameter value
+func (r *timezoneResolver) location(tzid string) (*time.Location, error) {
+	name := normalizeTZID(tzid)
+	if loc, ok := r.cache[name]; ok {
+		return loc, nil
+	}
+	if err, ok := r.failed[name]; ok {
+		return nil, err
+	}
+
+	loc, err := r.resolve(name)
+	if err != nil {
+		r.failed[name] = err
+		r.logger.Warn("Unable to resolve iCal time zone, events using it will be skipped",
+			"tzid", name,
+			"error", err)
+		return nil, err
+	}
+
+	r.cache[name] = loc
+	return loc, nil
+}
+
+// resolve tries, in order: the IANA zone named by an embedded VTIMEZONE, the TZID as
+// an IANA name, a Windows zone name, a vendor-prefixed IANA name, and finally a zone
+// synthesized from the embedded VTIMEZONE observances
+func (r *timezoneResolver) resolve(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty TZID")
+	}
+
+	vtz := r.embedded[name]
+	if vtz != nil {
+		if lic := vtz.GetProperty(ics.ComponentProperty("X-LIC-LOCATION")); lic != nil {
+			if loc, err := time.LoadLocation(lic.Value); err == nil {
+				return loc, nil
+			}
+		}
+	}
+
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc, nil
+	}
+
+	if iana, ok := windowsZones[name]; ok {
+		if loc, err := time.LoadLocation(iana); err == nil {
+			return loc, nil
+		}
+	}
+
+	// Vendor-prefixed IDs such as "/mozilla.org/20050126_1/Europe/Berlin"
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i := 1; i+1 < len(parts); i++ {
+		if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return loc, nil
+		}
+	}
+
+	if vtz != nil {
+		loc, err := buildVTimezoneLocation(name, vtz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid VTIMEZONE definition for %q: %w", name, err)
+		}
+		return loc, nil
+	}
+
+	return nil, fmt.Errorf("unknown time zone %q: not an IANA or Windows zone name and no VTIMEZONE definition in the feed", name)
+}
+
+// propertyTimes parses a (possibly multi-valued) date/time property, resolving its TZID.
+// Values without a TZID that are not in UTC are interpreted in floating.
+func (r *timezoneResolver) propertyTimes(prop *ics.IANAProperty, floating *time.Location) ([]time.Time, error) {
+	if prop == nil {
+		return nil, fmt.Errorf("property not found")
+	}
+
+	loc := floating
+	if tzid, ok := prop.ICalParameters["TZID"]; ok && len(tzid) > 0 {
+		var err error
+		loc, err = r.location(tzid[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var times []time.Time
+	for _, value := range strings.Split(prop.Value, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if start, _, isPeriod := strings.Cut(value, "/"); isPeriod {
+			value = start
+		}
+
+		t, err := parseTimeValue(value, loc)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+
+	return times, nil
+}
+
+// propertyTime parses a single-valued date/time property such as DTSTART
+func (r *timezoneResolver) propertyTime(prop *ics.IANAProperty) (time.Time, error) {
+	times, err := r.propertyTimes(prop, r.defaultLoc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(times) != 1 {
+		return time.Time{}, fmt.Errorf("expected a single date/time value, got %q", prop.Value)
+	}
+	return times[0], nil
+}
+
+// normalizeTZID trims whitespace and the quotes some producers put around TZIDs
+func normalizeTZID(tzid string) string {
+	return strings.Trim(strings.TrimSpace(tzid), `"`)
+}
+
+// tzTransition is a single onset of a VTIMEZONE observance
+type tzTransition struct {
+	at     int64 // Unix seconds
+	offset int   // Seconds east of UTC after the transition
+	isDST  bool
+	name   string
+}
+
+// buildVTimezoneLocation synthesizes a time.Location from the STANDARD and DAYLIGHT
+// observances of a VTIMEZONE by generating their onsets and encoding them as TZif data
+func buildVTimezoneLocation(name string, vtz *ics.VTimezone) (*time.Location, error) {
+	var transitions []tzTransition
+
+	for _, component := range vtz.Components {
+		var observance *ics.ComponentBase
+		isDST := false
+		switch c := component.(type) {
+		case *ics.Standard:
+			observance = &c.ComponentBase
+		case *ics.Daylight:
+			observance = &c.ComponentBase
+			isDST = true
+		default:
+			continue
+		}
+
+		onsets, err := observanceTransitions(observance, isDST)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, onsets...)
+	}
+
+	if len(transitions) == 0 {
+		return nil, fmt.Errorf("no STANDARD or DAYLIGHT observances")
+	}
+
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].at < transitions[j].at })
+
+	data, err := encodeTZif(transitions)
+	if err != nil {
+		return nil, err
+	}
+	return time.LoadLocationFromTZData(name, data)
+}
+
+// observanceTransitions expands the DTSTART, RRULE and RDATE of one observance into transitions
+func observanceTransitions(observance *ics.ComponentBase, isDST bool) ([]tzTransition, error) {
+	offsetFrom, err := parseUTCOffset(observance.GetProperty(ics.ComponentProperty(ics.PropertyTzoffsetfrom)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TZOFFSETFROM: %w", err)
+	}
+	offsetTo, err := parseUTCOffset(observance.GetProperty(ics.ComponentProperty(ics.PropertyTzoffsetto)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TZOFFSETTO: %w", err)
+	}
+
+	name := formatUTCOffset(offsetTo)
+	if tzname := observance.GetProperty(ics.ComponentProperty(ics.PropertyTzname)); tzname != nil && tzname.Value != "" {
+		name = tzname.Value
+	}
+
+	dtstartProp := observance.GetProperty(ics.ComponentPropertyDtStart)
+	if dtstartProp == nil {
+		return nil, fmt.Errorf("observance missing DTSTART")
+	}
+
+	// Observance times are local wall-clock times in the zone before the onset;
+	// expanding them as UTC keeps the wall clock intact and avoids DST arithmetic
+	dtstart, err := parseTimeValue(strings.TrimSuffix(dtstartProp.Value, "Z"), time.UTC)
+	if err != nil {
+		return nil, fmt.Errorf("invalid observance DTSTART: %w", err)
+	}
+
+	onsets := []time.Time{dtstart}
+	for _, prop := range observance.GetProperties(ics.ComponentPropertyRrule) {
+		// UNTIL is given in UTC; move it to the wall clock of the onsets
+		rule, err := ParseRecurrenceRule(prop.Value, time.FixedZone("", offsetFrom))
+		if err != nil {
+			return nil, fmt.Errorf("invalid observance RRULE: %w", err)
+		}
+		if !rule.Until.IsZero() {
+			rule.Until = rule.Until.UTC().Add(time.Duration(offsetFrom) * time.Second)
+		}
+		onsets = append(onsets, rule.Occurrences(dtstart, vtimezoneHorizon)...)
+	}
+	for _, prop := range observance.GetProperties(ics.ComponentPropertyRdate) {
+		for _, value := range strings.Split(prop.Value, ",") {
+			t, err := parseTimeValue(strings.TrimSuffix(strings.TrimSpace(value), "Z"), time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("invalid observance RDATE: %w", err)
+			}
+			onsets = append(onsets, t)
+		}
+	}
+
+	transitions := make([]tzTransition, 0, len(onsets))
+	for _, onset := range onsets {
+		transitions = append(transitions, tzTransition{
+			at:     onset.Unix() - int64(offsetFrom),
+			offset: offsetTo,
+			isDST:  isDST,
+			name:   name,
+		})
+	}
+	return transitions, nil
+}
+
+// parseUTCOffset parses a UTC-OFFSET value such as "+0100", "-0800" or "+053000"
+func parseUTCOffset(prop *ics.IANAProperty) (int, error) {
+	if prop == nil {
+		return 0, fmt.Errorf("missing")
+	}
+
+	value := strings.TrimSpace(prop.Value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+
+	sign := 1
+	switch value[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+
+	var fields [3]int
+	for i := 0; 1+2*i < len(value); i++ {
+		n, err := strconv.Atoi(value[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("malformed offset %q", value)
+		}
+		fields[i] = n
+	}
+
+	return sign * (fields[0]*3600 + fields[1]*60 + fields[2]), nil
+}
+
+// formatUTCOffset renders an offset as a zone abbreviation such as "+0530"
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, (offset%3600)/60)
+}
+
+// encodeTZif encodes transitions as version 1 TZif data for time.LoadLocationFromTZData.
+// Transitions outside the 32-bit range of version 1 data are dropped.
+func encodeTZif(transitions []tzTransition) ([]byte, error) {
+	type zoneType struct {
+		offset int
+		isDST  bool
+		name   string
+	}
+
+	var types []zoneType
+	typeIndex := make(map[zoneType]int)
+	var abbrevs []byte
+	abbrevIndex := make(map[string]int)
+
+	var times []int32
+	var indices []byte
+
+	for _, tr := range transitions {
+		if tr.at < math.MinInt32 || tr.at > math.MaxInt32 {
+			continue
+		}
+
+		zt := zoneType{offset: tr.offset, isDST: tr.isDST, name: tr.name}
+		idx, ok := typeIndex[zt]
+		if !ok {
+			if _, ok := abbrevIndex[zt.name]; !ok {
+				abbrevIndex[zt.name] = len(abbrevs)
+				abbrevs = append(abbrevs, zt.name...)
+				abbrevs = append(abbrevs, 0)
+			}
+			idx = len(types)
+			typeIndex[zt] = idx
+			types = append(types, zt)
+		}
+
+		times = append(times, int32(tr.at))
+		indices = append(indices, byte(idx))
+	}
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("no transitions within the supported range")
+	}
+	if len(types) > math.MaxUint8 || len(abbrevs) > math.MaxUint8 {
+		return nil, fmt.Errorf("too many distinct observances")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("TZif")
+	buf.Write(make([]byte, 16)) // Version 1 plus reserved bytes
+
+	// isutcnt, isstdcnt, leapcnt, timecnt, typecnt, charcnt
+	for _, n := range []int{0, 0, 0, len(times), len(types), len(abbrevs)} {
+		binary.Write(&buf, binary.BigEndian, uint32(n))
+	}
+	for _, t := range times {
+		binary.Write(&buf, binary.BigEndian, t)
+	}
+	buf.Write(indices)
+	for _, zt := range types {
+		binary.Write(&buf, binary.BigEndian, int32(zt.offset))
+		if zt.isDST {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(byte(abbrevIndex[zt.name]))
+	}
+	buf.Write(abbrevs)
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,372 @@
+package ical
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+	return loc
+}
+
+func TestParseICalDataWindowsTimeZone(t *testing.T) {
+	mustLoadLocation(t, "America/Los_Angeles")
+
+	// Outlook-style feed: Windows TZID with an embedded VTIMEZONE and no IANA hint
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:Microsoft Exchange Server 2010
+BEGIN:VTIMEZONE
+TZID:Pacific Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T020000
+TZOFFSETFROM:-0700
+TZOFFSETTO:-0800
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=1SU;BYMONTH=11
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:-0800
+TZOFFSETTO:-0700
+RRULE:FREQ=YEARLY;INTERVAL=1;BYDAY=2SU;BYMONTH=3
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:planning@example.com
+DTSTART;TZID=Pacific Standard Time:20240710T090000
+DTEND;TZID=Pacific Standard Time:20240710T100000
+SUMMARY:Planning
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	expected := time.Date(2024, 7, 10, 16, 0, 0, 0, time.UTC)
+	if !events[0].StartTime.Equal(expected) {
+		t.Errorf("Expected start %v, got %v", expected, events[0].StartTime.UTC())
+	}
+}
+
+func TestParseICalDataEmbeddedVTimezone(t *testing.T) {
+	// A zone that exists in no database: UTC+3 in winter, UTC+4 from the
+	// last Sunday of March to the last Sunday of October
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VTIMEZONE
+TZID:Office Local Time
+BEGIN:STANDARD
+DTSTART:19701025T030000
+TZOFFSETFROM:+0400
+TZOFFSETTO:+0300
+TZNAME:OLT
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:19700329T020000
+TZOFFSETFROM:+0300
+TZOFFSETTO:+0400
+TZNAME:OLST
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:winter@example.com
+DTSTART;TZID=Office Local Time:20240115T090000
+DTEND;TZID=Office Local Time:20240115T100000
+SUMMARY:Winter Meeting
+END:VEVENT
+BEGIN:VEVENT
+UID:summer@example.com
+DTSTART;TZID=Office Local Time:20240715T090000
+DTEND;TZID=Office Local Time:20240715T100000
+SUMMARY:Summer Meeting
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Time{
+		"winter@example.com": time.Date(2024, 1, 15, 6, 0, 0, 0, time.UTC),
+		"summer@example.com": time.Date(2024, 7, 15, 5, 0, 0, 0, time.UTC),
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for _, event := range events {
+		if !event.StartTime.Equal(expected[event.ID]) {
+			t.Errorf("Event %s start = %v, expected %v", event.ID, event.StartTime.UTC(), expected[event.ID])
+		}
+	}
+
+	if name, _ := events[1].StartTime.Zone(); name != "OLST" {
+		t.Errorf("Expected summer event in OLST, got %s", name)
+	}
+}
+
+func TestParseICalDataVTimezoneRuleUntil(t *testing.T) {
+	// Summer time ends for good after 2022; UNTIL names the last onset,
+	// 02:00 local time, in UTC
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VTIMEZONE
+TZID:Office Local Time
+BEGIN:STANDARD
+DTSTART:20201025T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU;UNTIL=20221030T010000Z
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:20200329T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;UNTIL=20220327T010000Z
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:last-summer@example.com
+DTSTART;TZID=Office Local Time:20220715T090000
+DTEND;TZID=Office Local Time:20220715T100000
+SUMMARY:Last Summer Meeting
+END:VEVENT
+BEGIN:VEVENT
+UID:after@example.com
+DTSTART;TZID=Office Local Time:20230715T090000
+DTEND;TZID=Office Local Time:20230715T100000
+SUMMARY:Meeting After
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Time{
+		"last-summer@example.com": time.Date(2022, 7, 15, 7, 0, 0, 0, time.UTC),
+		"after@example.com":       time.Date(2023, 7, 15, 8, 0, 0, 0, time.UTC),
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for _, event := range events {
+		if !event.StartTime.Equal(expected[event.ID]) {
+			t.Errorf("Event %s start = %v, expected %v", event.ID, event.StartTime.UTC(), expected[event.ID])
+		}
+	}
+}
+
+func TestParseICalDataFloatingTimesUseDefaultLocation(t *testing.T) {
+	tokyo := mustLoadLocation(t, "Asia/Tokyo")
+
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:floating@example.com
+DTSTART:20240110T090000
+DTEND:20240110T100000
+SUMMARY:Floating
+END:VEVENT
+BEGIN:VEVENT
+UID:allday@example.com
+DTSTART;VALUE=DATE:20240111
+DTEND;VALUE=DATE:20240112
+SUMMARY:All Day
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalDataInLocation(icalData, "test-calendar", "Test Calendar", from, to, "", tokyo, slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalDataInLocation() unexpected error: %v", err)
+	}
+
+	expected := map[string]time.Time{
+		"floating@example.com": time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC),
+		"allday@example.com":   time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC),
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for _, event := range events {
+		if !event.StartTime.Equal(expected[event.ID]) {
+			t.Errorf("Event %s start = %v, expected %v", event.ID, event.StartTime.UTC(), expected[event.ID])
+		}
+	}
+}
+
+func TestParseICalDataSkipsUnresolvableTimeZone(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:unknown@example.com
+DTSTART;TZID=Nowhere Standard Time:20240110T090000
+DTEND;TZID=Nowhere Standard Time:20240110T100000
+SUMMARY:Unknown Zone
+END:VEVENT
+BEGIN:VEVENT
+UID:utc@example.com
+DTSTART:20240110T090000Z
+DTEND:20240110T100000Z
+SUMMARY:UTC Event
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalData(icalData, "test-calendar", "Test Calendar", from, to, "", slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalData() unexpected error: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != "utc@example.com" {
+		t.Fatalf("Expected only the UTC event, got %d events", len(events))
+	}
+}
+
+func TestTimezoneResolverLocation(t *testing.T) {
+	mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		tzid     string
+		expected string
+		wantErr  bool
+	}{
+		{tzid: "Europe/Berlin", expected: "Europe/Berlin"},
+		{tzid: `"Europe/Berlin"`, expected: "Europe/Berlin"},
+		{tzid: "W. Europe Standard Time", expected: "Europe/Berlin"},
+		{tzid: "/mozilla.org/20050126_1/Europe/Berlin", expected: "Europe/Berlin"},
+		{tzid: "/citadel.org/20190914_1/Asia/Kolkata", expected: "Asia/Kolkata"},
+		{tzid: "Nowhere Standard Time", wantErr: true},
+		{tzid: "", wantErr: true},
+	}
+
+	resolver := newTimezoneResolver(nil, time.UTC, slog.Default())
+	for _, tt := range tests {
+		t.Run(tt.tzid, func(t *testing.T) {
+			loc, err := resolver.location(tt.tzid)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("location(%q) expected error, got %v", tt.tzid, loc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("location(%q) unexpected error: %v", tt.tzid, err)
+			}
+			if loc.String() != tt.expected {
+				t.Errorf("location(%q) = %s, expected %s", tt.tzid, loc, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseUTCOffset(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{input: "+0100", expected: 3600},
+		{input: "-0800", expected: -8 * 3600},
+		{input: "+0530", expected: 5*3600 + 30*60},
+		{input: "+053015", expected: 5*3600 + 30*60 + 15},
+		{input: "0100", wantErr: true},
+		{input: "+1", wantErr: true},
+		{input: "+01a0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseUTCOffset(&ics.IANAProperty{BaseProperty: ics.BaseProperty{Value: tt.input}})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseUTCOffset(%q) expected error, got nil", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUTCOffset(%q) unexpected error: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("parseUTCOffset(%q) = %d, expected %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseICalDataAllDayEvent(t *testing.T) {
+	icalData := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//Test Calendar//EN
+BEGIN:VEVENT
+UID:birthday@example.com
+DTSTART;VALUE=DATE:20240111
+SUMMARY:Birthday
+END:VEVENT
+BEGIN:VEVENT
+UID:timed@example.com
+DTSTART:20240112T090000Z
+SUMMARY:Timed
+END:VEVENT
+END:VCALENDAR`
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := ParseICalDataInLocation(icalData, "test-calendar", "Test Calendar", from, to, "", time.UTC, slog.Default())
+	if err != nil {
+		t.Fatalf("ParseICalDataInLocation() unexpected error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	for _, event := range events {
+		switch event.ID {
+		case "birthday@example.com":
+			if !event.AllDay {
+				t.Error("Expected date-only event to be all-day")
+			}
+			if event.EndTime.Sub(event.StartTime) != 24*time.Hour {
+				t.Errorf("Expected all-day event to last one day, got %v", event.EndTime.Sub(event.StartTime))
+			}
+		case "timed@example.com":
+			if event.AllDay {
+				t.Error("Expected timed event not to be all-day")
+			}
+		}
+	}
+}
@@ -2,7 +2,12 @@ package calendar
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/venkytv/calendar-notifier/internal/models"
@@ -35,6 +40,14 @@ type Provider interface {
 	SetLogger(logger *slog.Logger)
 }
 
+// RemovalReporter is implemented by providers that learn from the server which
+// events were deleted, rather than just no longer seeing them
+type RemovalReporter interface {
+	// TakeRemovedEvents returns the IDs of events deleted upstream since the
+	// previous call
+	TakeRemovedEvents() []string
+}
+
 
 // ProviderFactory creates calendar providers based on configuration
 type ProviderFactory interface {
@@ -45,12 +58,113 @@ type ProviderFactory interface {
 	SupportedTypes() []string
 }
 
+// DefaultMaxStaleness is how long the last events fetched from a provider keep
+// being used while it fails, unless set with SetMaxStaleness
+const DefaultMaxStaleness = time.Hour
+
+const (
+	// DefaultFetchTimeout is how long fetching from a provider may take,
+	// unless set with SetFetchTimeout
+	DefaultFetchTimeout = time.Minute
+
+	// DefaultMaxConcurrentFetches is how many providers are fetched at once,
+	// unless set with SetMaxConcurrentFetches
+	DefaultMaxConcurrentFetches = 4
+)
+
 // Manager coordinates multiple calendar providers
 type Manager struct {
-	providers   map[string]Provider
-	factory     ProviderFactory
-	coordinator *EventCoordinator
-	logger      *slog.Logger
+	providers     map[string]Provider
+	filters       map[string]EventFilter
+	staleness     map[string]time.Duration
+	timeouts      map[string]time.Duration
+	pollIntervals map[string]time.Duration
+	wake          map[string]chan struct{} // Requests an early refresh in Run; holds at most one
+	factory       ProviderFactory
+	coordinator   *EventCoordinator
+	logger        *slog.Logger
+
+	maxConcurrentFetches int
+
+	// Written by fetch workers and refresh loops
+	mu       sync.Mutex
+	lastGood map[string]*providerSnapshot // Last successful fetch of each provider
+	lastErr  map[string]error             // Why the last fetch of a provider failed, if it did
+	removed  []string                     // IDs of events providers reported as deleted
+}
+
+// providerSnapshot holds the events of a successful fetch, filtered and with
+// their calendar name set
+type providerSnapshot struct {
+	events    []*models.Event
+	fetchedAt time.Time
+}
+
+// ProviderError describes a provider whose events could not be fetched
+type ProviderError struct {
+	Provider    string    // Name the provider was added with
+	Type        string    // Provider type
+	Err         error     // Why the fetch failed
+	LastSuccess time.Time // When the provider was last fetched successfully; zero if never
+	StaleEvents int       // Events used from that fetch; 0 if there was none or it was too old
+}
+
+func (e *ProviderError) Error() string {
+	if e.StaleEvents > 0 {
+		return fmt.Sprintf("%s: %v (using %d events fetched at %s)",
+			e.Provider, e.Err, e.StaleEvents, e.LastSuccess.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// FetchError is returned by GetAllEvents and CachedEvents when some providers
+// failed, or by CachedEvents when some were not fetched yet. The events of the
+// other providers, and recent events of the failed ones, are returned along
+// with it.
+type FetchError struct {
+	Failures  []*ProviderError // Sorted by provider name
+	Pending   []string         // Providers Run has not fetched yet, sorted
+	Providers int              // Number of providers asked
+}
+
+func (e *FetchError) Error() string {
+	if len(e.Failures) == 0 {
+		return fmt.Sprintf("%d of %d calendar providers not fetched yet: %s",
+			len(e.Pending), e.Providers, strings.Join(e.Pending, ", "))
+	}
+
+	var failures []string
+	for _, failure := range e.Failures {
+		failures = append(failures, failure.Error())
+	}
+	msg := fmt.Sprintf("%d of %d calendar providers failed: %s",
+		len(e.Failures), e.Providers, strings.Join(failures, "; "))
+	if len(e.Pending) > 0 {
+		msg += fmt.Sprintf(" (not fetched yet: %s)", strings.Join(e.Pending, ", "))
+	}
+	return msg
+}
+
+// FailedProviders returns the names of the providers whose current events
+// are unknown, because they failed or were not fetched yet
+func (e *FetchError) FailedProviders() []string {
+	names := make([]string, 0, len(e.Failures)+len(e.Pending))
+	for _, failure := range e.Failures {
+		names = append(names, failure.Provider)
+	}
+	return append(names, e.Pending...)
+}
+
+func (e *FetchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure
+	}
+	return errs
 }
 
 // NewManager creates a new calendar manager
@@ -67,19 +181,55 @@ func NewManagerWithCoordinator(factory ProviderFactory, coordinatorConfig *Coord
 	coordinator := NewEventCoordinator(coordinatorConfig, logger)
 
 	return &Manager{
-		providers:   make(map[string]Provider),
-		factory:     factory,
-		coordinator: coordinator,
-		logger:      logger,
+		providers:     make(map[string]Provider),
+		filters:       make(map[string]EventFilter),
+		staleness:     make(map[string]time.Duration),
+		timeouts:      make(map[string]time.Duration),
+		pollIntervals: make(map[string]time.Duration),
+		wake:          make(map[string]chan struct{}),
+		factory:       factory,
+		coordinator:   coordinator,
+		logger:        logger,
+		lastGood:      make(map[string]*providerSnapshot),
+		lastErr:       make(map[string]error),
+
+		maxConcurrentFetches: DefaultMaxConcurrentFetches,
 	}
 }
 
 // AddProvider adds a calendar provider to the manager
 func (m *Manager) AddProvider(name string, provider Provider) {
 	m.providers[name] = provider
+	m.wake[name] = make(chan struct{}, 1)
 	m.logger.Info("Added calendar provider", "name", name, "type", provider.Type())
 }
 
+// SetEventFilter sets which cancelled or free events of a provider still get
+// notifications. Providers without a filter use the zero EventFilter.
+func (m *Manager) SetEventFilter(name string, filter EventFilter) {
+	m.filters[name] = filter
+}
+
+// SetMaxStaleness sets how long the last events fetched from a provider are
+// still used while fetching from it fails. Providers without a limit use
+// DefaultMaxStaleness.
+func (m *Manager) SetMaxStaleness(name string, maxStaleness time.Duration) {
+	m.staleness[name] = maxStaleness
+}
+
+// SetFetchTimeout sets how long fetching the calendars and events of a
+// provider may take. Providers without a timeout use DefaultFetchTimeout.
+func (m *Manager) SetFetchTimeout(name string, timeout time.Duration) {
+	m.timeouts[name] = timeout
+}
+
+// SetMaxConcurrentFetches sets how many providers are fetched at once
+func (m *Manager) SetMaxConcurrentFetches(n int) {
+	if n > 0 {
+		m.maxConcurrentFetches = n
+	}
+}
+
 // GetProvider retrieves a calendar provider by name
 func (m *Manager) GetProvider(name string) (Provider, bool) {
 	provider, exists := m.providers[name]
@@ -87,64 +237,41 @@ func (m *Manager) GetProvider(name string) (Provider, bool) {
 }
 
 // GetAllEvents retrieves events from all configured providers within the time range
-// with multi-calendar coordination including deduplication and prioritization
+// with multi-calendar coordination including deduplication and prioritization.
+// Providers are fetched concurrently and independently: if some fail, the
+// events of the others are returned together with a *FetchError describing
+// the failures.
 func (m *Manager) GetAllEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
 	var allEvents []*models.Event
+	var failures []*ProviderError
 
 	m.logger.Debug("Fetching events from all providers",
 		"provider_count", len(m.providers),
 		"from", from.Format(time.RFC3339),
 		"to", to.Format(time.RFC3339))
 
-	for name, provider := range m.providers {
-		m.logger.Debug("Fetching events from provider",
-			"provider_name", name,
-			"provider_type", provider.Type())
+	// Fetch in parallel, but merge in provider name order so coordination
+	// sees the same input whichever provider answers first
+	names := m.providerNames()
+	results := m.fetchAll(ctx, names, from, to)
 
-		// Get available calendars from the provider
-		calendars, err := provider.GetCalendars(ctx)
+	m.mu.Lock()
+	for i, name := range names {
+		provider := m.providers[name]
+		events, err := results[i].events, results[i].err
 		if err != nil {
-			m.logger.Error("Failed to get calendars from provider",
-				"provider_name", name,
-				"provider_type", provider.Type(),
-				"error", err)
-			return nil, err
+			var failure *ProviderError
+			failure, events = m.providerFailure(name, provider, err, from, to)
+			failures = append(failures, failure)
+			m.lastErr[name] = err
+		} else {
+			m.lastGood[name] = &providerSnapshot{events: events, fetchedAt: time.Now()}
+			delete(m.lastErr, name)
 		}
 
-		var calendarIDs []string
-		for _, cal := range calendars {
-			calendarIDs = append(calendarIDs, cal.ID)
-		}
-
-		// If no calendars found, skip this provider
-		if len(calendarIDs) == 0 {
-			m.logger.Debug("No calendars found for provider",
-				"provider_name", name,
-				"provider_type", provider.Type())
-			continue
-		}
-
-		events, err := provider.GetEvents(ctx, calendarIDs, from, to)
-		if err != nil {
-			m.logger.Error("Failed to get events from provider",
-				"provider_name", name,
-				"provider_type", provider.Type(),
-				"error", err)
-			return nil, err
-		}
-
-		// Set calendar name for each event
-		for _, event := range events {
-			event.CalendarName = name
-		}
-
-		m.logger.Debug("Fetched events from provider",
-			"provider_name", name,
-			"provider_type", provider.Type(),
-			"event_count", len(events))
-
 		allEvents = append(allEvents, events...)
 	}
+	m.mu.Unlock()
 
 	m.logger.Debug("Raw events fetched", "total_count", len(allEvents))
 
@@ -160,9 +287,183 @@ func (m *Manager) GetAllEvents(ctx context.Context, from, to time.Time) ([]*mode
 		"coordinated_events", len(coordinatedEvents),
 		"duplicates_removed", len(allEvents)-len(coordinatedEvents))
 
+	if len(failures) > 0 {
+		if coordinatedEvents == nil {
+			coordinatedEvents = []*models.Event{}
+		}
+		return coordinatedEvents, &FetchError{Failures: failures, Providers: len(m.providers)}
+	}
+
 	return coordinatedEvents, nil
 }
 
+// fetchResult is the outcome of fetching one provider
+type fetchResult struct {
+	events []*models.Event
+	err    error
+}
+
+// fetchAll fetches the named providers with at most maxConcurrentFetches at a
+// time, each under its own deadline. Results are in the order of names.
+func (m *Manager) fetchAll(ctx context.Context, names []string, from, to time.Time) []fetchResult {
+	results := make([]fetchResult, len(names))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(m.maxConcurrentFetches, len(names)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				name := names[i]
+				timeout := m.fetchTimeout(name)
+				fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+				events, err := m.fetchProvider(fetchCtx, name, m.providers[name], from, to)
+				if err != nil && ctx.Err() == nil && errors.Is(fetchCtx.Err(), context.DeadlineExceeded) {
+					err = fmt.Errorf("timed out after %s: %w", timeout, err)
+				}
+				cancel()
+				results[i] = fetchResult{events: events, err: err}
+			}
+		}()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// fetchTimeout returns how long a fetch from the named provider may take
+func (m *Manager) fetchTimeout(name string) time.Duration {
+	if timeout, ok := m.timeouts[name]; ok && timeout > 0 {
+		return timeout
+	}
+	return DefaultFetchTimeout
+}
+
+// fetchProvider fetches the events of one provider, named and filtered
+func (m *Manager) fetchProvider(ctx context.Context, name string, provider Provider, from, to time.Time) ([]*models.Event, error) {
+	m.logger.Debug("Fetching events from provider",
+		"provider_name", name,
+		"provider_type", provider.Type())
+
+	// Get available calendars from the provider
+	calendars, err := provider.GetCalendars(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendars: %w", err)
+	}
+
+	var calendarIDs []string
+	for _, cal := range calendars {
+		calendarIDs = append(calendarIDs, cal.ID)
+	}
+
+	// If no calendars found, skip this provider
+	if len(calendarIDs) == 0 {
+		m.logger.Debug("No calendars found for provider",
+			"provider_name", name,
+			"provider_type", provider.Type())
+		return nil, nil
+	}
+
+	events, err := provider.GetEvents(ctx, calendarIDs, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	// Set calendar name for each event
+	for _, event := range events {
+		event.CalendarName = name
+	}
+
+	// Collect deletions the provider learned about during the fetch
+	if reporter, ok := provider.(RemovalReporter); ok {
+		if removed := reporter.TakeRemovedEvents(); len(removed) > 0 {
+			m.logger.Info("Provider reported deleted events",
+				"provider_name", name,
+				"removed_count", len(removed))
+			m.mu.Lock()
+			m.removed = append(m.removed, removed...)
+			m.mu.Unlock()
+		}
+	}
+
+	// Drop cancelled events, and free ones if the calendar opts in
+	fetchedCount := len(events)
+	events = m.filters[name].Apply(events)
+
+	m.logger.Debug("Fetched events from provider",
+		"provider_name", name,
+		"provider_type", provider.Type(),
+		"event_count", len(events),
+		"filtered_count", fetchedCount-len(events))
+
+	return events, nil
+}
+
+// providerFailure logs a failed fetch and describes it. It returns the
+// provider's last good events within the range if they are recent enough.
+func (m *Manager) providerFailure(name string, provider Provider, err error, from, to time.Time) (*ProviderError, []*models.Event) {
+	failure := &ProviderError{Provider: name, Type: provider.Type(), Err: err}
+
+	maxStaleness, ok := m.staleness[name]
+	if !ok {
+		maxStaleness = DefaultMaxStaleness
+	}
+
+	var stale []*models.Event
+	snapshot := m.lastGood[name]
+	if snapshot != nil {
+		failure.LastSuccess = snapshot.fetchedAt
+		if time.Since(snapshot.fetchedAt) <= maxStaleness {
+			stale = snapshot.within(from, to)
+			failure.StaleEvents = len(stale)
+		}
+	}
+
+	if failure.StaleEvents > 0 {
+		m.logger.Warn("Failed to fetch events from provider, using last good events",
+			"provider_name", name,
+			"provider_type", provider.Type(),
+			"fetched_at", snapshot.fetchedAt.Format(time.RFC3339),
+			"event_count", failure.StaleEvents,
+			"error", err)
+	} else {
+		m.logger.Error("Failed to fetch events from provider",
+			"provider_name", name,
+			"provider_type", provider.Type(),
+			"last_success", failure.LastSuccess,
+			"error", err)
+	}
+
+	return failure, stale
+}
+
+// within returns the snapshot events that overlap [from, to)
+func (s *providerSnapshot) within(from, to time.Time) []*models.Event {
+	var events []*models.Event
+	for _, event := range s.events {
+		if !event.HasEnded(from) && event.StartTime.Before(to) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// TakeRemovedEvents returns the IDs of events that providers reported as
+// deleted upstream since the previous call
+func (m *Manager) TakeRemovedEvents() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := m.removed
+	m.removed = nil
+	return removed
+}
+
 // Close closes all providers
 func (m *Manager) Close() error {
 	for _, provider := range m.providers {
@@ -202,4 +503,11 @@ func (m *Manager) GetProviderList() []string {
 		names = append(names, name)
 	}
 	return names
-}
\ No newline at end of file
+}
+
+// providerNames returns the names of all providers in sorted order
+func (m *Manager) providerNames() []string {
+	names := m.GetProviderList()
+	sort.Strings(names)
+	return names
+}
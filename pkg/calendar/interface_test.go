@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -129,6 +130,91 @@ func TestManagerGetAllEvents(t *testing.T) {
 	}
 }
 
+func TestManagerGetAllEventsAppliesEventFilter(t *testing.T) {
+	manager := NewManager(NewDefaultProviderFactory())
+
+	now := time.Now()
+	newEvents := func() []*models.Event {
+		return []*models.Event{
+			{ID: "busy", Title: "Busy", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)},
+			{ID: "cancelled", Title: "Cancelled", StartTime: now.Add(3 * time.Hour), EndTime: now.Add(4 * time.Hour), Status: models.EventStatusCancelled},
+			{ID: "free", Title: "Free", StartTime: now.Add(5 * time.Hour), EndTime: now.Add(6 * time.Hour), Transparency: models.TransparencyTransparent},
+		}
+	}
+
+	// The work calendar opts in to skipping free events
+	work := NewMockProvider("work", "mock")
+	work.SetCalendars([]*Calendar{{ID: "work"}})
+	work.SetEvents(newEvents())
+	manager.AddProvider("work", work)
+	manager.SetEventFilter("work", EventFilter{SkipTransparent: true})
+
+	holidays := NewMockProvider("holidays", "mock")
+	holidays.SetCalendars([]*Calendar{{ID: "holidays"}})
+	holidayEvents := newEvents()
+	for _, event := range holidayEvents {
+		// Offset so the coordinator does not merge them with the work events
+		event.ID = "holiday-" + event.ID
+		event.StartTime = event.StartTime.Add(30 * time.Minute)
+		event.EndTime = event.EndTime.Add(30 * time.Minute)
+	}
+	holidays.SetEvents(holidayEvents)
+	manager.AddProvider("holidays", holidays)
+
+	events, err := manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to get events: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, event := range events {
+		got[event.ID] = true
+	}
+	for _, id := range []string{"busy", "holiday-busy", "holiday-free"} {
+		if !got[id] {
+			t.Errorf("Expected event %s to be kept", id)
+		}
+	}
+	for _, id := range []string{"cancelled", "free", "holiday-cancelled"} {
+		if got[id] {
+			t.Errorf("Expected event %s to be filtered out", id)
+		}
+	}
+}
+
+// removingProvider is a MockProvider that reports deleted events
+type removingProvider struct {
+	*MockProvider
+	removed []string
+}
+
+func (r *removingProvider) TakeRemovedEvents() []string {
+	removed := r.removed
+	r.removed = nil
+	return removed
+}
+
+func TestManagerCollectsRemovedEvents(t *testing.T) {
+	manager := NewManager(NewDefaultProviderFactory())
+
+	provider := &removingProvider{MockProvider: NewMockProvider("caldav", "mock"), removed: []string{"standup_20251001T140000Z"}}
+	provider.SetCalendars([]*Calendar{{ID: "work"}})
+	manager.AddProvider("work", provider)
+
+	now := time.Now()
+	if _, err := manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour)); err != nil {
+		t.Fatalf("Failed to get events: %v", err)
+	}
+
+	removed := manager.TakeRemovedEvents()
+	if len(removed) != 1 || removed[0] != "standup_20251001T140000Z" {
+		t.Errorf("Expected the reported removal, got %v", removed)
+	}
+	if removed := manager.TakeRemovedEvents(); len(removed) != 0 {
+		t.Errorf("Expected removals to be drained, got %v", removed)
+	}
+}
+
 func TestManagerHealthCheck(t *testing.T) {
 	factory := NewDefaultProviderFactory()
 	manager := NewManager(factory)
@@ -146,4 +232,176 @@ func TestManagerHealthCheck(t *testing.T) {
 	if results["test"] != nil {
 		t.Errorf("Expected healthy provider, got error: %v", results["test"])
 	}
-}
\ No newline at end of file
+}
+// flakyProvider is a MockProvider whose fetches fail while err is set
+type flakyProvider struct {
+	*MockProvider
+	err error
+}
+
+func (f *flakyProvider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.MockProvider.GetEvents(ctx, calendarIDs, from, to)
+}
+
+func eventIDSet(events []*models.Event) map[string]bool {
+	ids := make(map[string]bool)
+	for _, event := range events {
+		ids[event.ID] = true
+	}
+	return ids
+}
+
+func TestManagerToleratesProviderFailures(t *testing.T) {
+	manager := NewManager(NewDefaultProviderFactory())
+	now := time.Now()
+
+	work := NewMockProvider("work", "mock")
+	work.SetCalendars([]*Calendar{{ID: "work"}})
+	work.SetEvents([]*models.Event{{ID: "standup", Title: "Standup", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}})
+	manager.AddProvider("work", work)
+
+	holidays := &flakyProvider{MockProvider: NewMockProvider("holidays", "mock")}
+	holidays.SetCalendars([]*Calendar{{ID: "holidays"}})
+	holidays.SetEvents([]*models.Event{
+		{ID: "holiday", Title: "Holiday", StartTime: now.Add(3 * time.Hour), EndTime: now.Add(4 * time.Hour)},
+		{ID: "past-holiday", Title: "Past Holiday", StartTime: now.Add(5 * time.Hour), EndTime: now.Add(6 * time.Hour)},
+	})
+	manager.AddProvider("holidays", holidays)
+
+	// A provider that never succeeded contributes nothing
+	broken := &flakyProvider{MockProvider: NewMockProvider("broken", "mock"), err: errors.New("connection refused")}
+	broken.SetCalendars([]*Calendar{{ID: "broken"}})
+	manager.AddProvider("broken", broken)
+
+	events, err := manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour))
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) {
+		t.Fatalf("Expected a FetchError, got %v", err)
+	}
+	if len(fetchErr.Failures) != 1 || fetchErr.Failures[0].Provider != "broken" || fetchErr.Providers != 3 {
+		t.Errorf("Expected only the broken provider to be reported, got %+v", fetchErr)
+	}
+	if names := fetchErr.FailedProviders(); len(names) != 1 || names[0] != "broken" {
+		t.Errorf("Expected FailedProviders() to name the broken provider, got %v", names)
+	}
+	if failure := fetchErr.Failures[0]; !failure.LastSuccess.IsZero() || failure.StaleEvents != 0 || !errors.Is(err, broken.err) {
+		t.Errorf("Unexpected failure %+v", failure)
+	}
+	if ids := eventIDSet(events); !ids["standup"] || !ids["holiday"] || len(ids) != 3 {
+		t.Errorf("Expected the events of the working providers, got %v", ids)
+	}
+
+	// The holiday feed goes down: its last good events are used, limited to the new range
+	holidays.err = errors.New("503 Service Unavailable")
+	events, err = manager.GetAllEvents(context.Background(), now.Add(4*time.Hour), now.Add(24*time.Hour))
+	if !errors.As(err, &fetchErr) || len(fetchErr.Failures) != 2 {
+		t.Fatalf("Expected two failures, got %v", err)
+	}
+	failure := fetchErr.Failures[1]
+	if failure.Provider != "holidays" || failure.StaleEvents != 1 || failure.LastSuccess.IsZero() {
+		t.Errorf("Expected the holidays provider to use one stale event, got %+v", failure)
+	}
+	if ids := eventIDSet(events); !ids["standup"] || !ids["past-holiday"] || ids["holiday"] {
+		t.Errorf("Expected work events and stale holidays within the range, got %v", ids)
+	}
+
+	// Past the staleness limit the stale events are dropped
+	manager.SetMaxStaleness("holidays", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	events, err = manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour))
+	if !errors.As(err, &fetchErr) || fetchErr.Failures[1].StaleEvents != 0 {
+		t.Fatalf("Expected the stale events to be too old, got %v", err)
+	}
+	if ids := eventIDSet(events); len(ids) != 1 || !ids["standup"] {
+		t.Errorf("Expected only the work events, got %v", ids)
+	}
+
+	// Once every provider recovers no error is returned
+	holidays.err = nil
+	broken.err = nil
+	if _, err := manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour)); err != nil {
+		t.Errorf("Expected no error after recovery, got %v", err)
+	}
+}
+
+// slowProvider is a MockProvider whose fetches take delay, or until their
+// context is done. It records how many fetches of its group run at once.
+type slowProvider struct {
+	*MockProvider
+	delay time.Duration
+	group *fetchGroup
+}
+
+type fetchGroup struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (s *slowProvider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
+	s.group.mu.Lock()
+	s.group.running++
+	s.group.peak = max(s.group.peak, s.group.running)
+	s.group.mu.Unlock()
+	defer func() {
+		s.group.mu.Lock()
+		s.group.running--
+		s.group.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(s.delay):
+		return s.MockProvider.GetEvents(ctx, calendarIDs, from, to)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestManagerFetchesConcurrently(t *testing.T) {
+	manager := NewManager(NewDefaultProviderFactory())
+	manager.SetMaxConcurrentFetches(2)
+	group := &fetchGroup{}
+	now := time.Now()
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		provider := &slowProvider{MockProvider: NewMockProvider(name, "mock"), delay: 50 * time.Millisecond, group: group}
+		provider.SetCalendars([]*Calendar{{ID: name}})
+		provider.SetEvents([]*models.Event{{ID: name, Title: name, StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}})
+		manager.AddProvider(name, provider)
+	}
+
+	// A server that never answers is cut off by its own deadline
+	hung := &slowProvider{MockProvider: NewMockProvider("hung", "mock"), delay: time.Hour, group: &fetchGroup{}}
+	hung.SetCalendars([]*Calendar{{ID: "hung"}})
+	manager.AddProvider("hung", hung)
+	manager.SetFetchTimeout("hung", 20*time.Millisecond)
+
+	start := time.Now()
+	events, err := manager.GetAllEvents(context.Background(), now, now.Add(24*time.Hour))
+	elapsed := time.Since(start)
+
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) || len(fetchErr.Failures) != 1 || fetchErr.Failures[0].Provider != "hung" {
+		t.Fatalf("Expected only the hung provider to fail, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the hung provider to time out, got %v", err)
+	}
+	if group.peak != 2 {
+		t.Errorf("Expected 2 fetches at a time, got %d", group.peak)
+	}
+	if elapsed >= 200*time.Millisecond {
+		t.Errorf("Expected fetches to overlap, took %v", elapsed)
+	}
+
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 4 {
+		t.Errorf("Expected the events of the 4 working providers, got %v", ids)
+	}
+}
@@ -0,0 +1,282 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// DefaultAuthority is the Microsoft identity platform sign-in host
+	DefaultAuthority = "https://login.microsoftonline.com"
+
+	// DefaultGraphURL is the Microsoft Graph v1.0 endpoint
+	DefaultGraphURL = "https://graph.microsoft.com/v1.0"
+
+	// Delegated scopes for reading the signed-in user's calendars; offline
+	// access is what yields a refresh token
+	calendarsReadScope = "https://graph.microsoft.com/Calendars.Read"
+	offlineAccessScope = "offline_access"
+
+	// applicationScope requests the application permissions granted to the
+	// app registration, which must include Calendars.Read
+	applicationScope = "https://graph.microsoft.com/.default"
+)
+
+// Credentials is the credentials file of an app registration in Microsoft
+// Entra ID. With a client secret the app reads calendars with application
+// permissions (client credentials flow); without one a user signs in once
+// with the device code flow.
+type Credentials struct {
+	TenantID     string `json:"tenant_id"`               // Directory (tenant) ID; defaults to "common" without a client secret
+	ClientID     string `json:"client_id"`               // Application (client) ID
+	ClientSecret string `json:"client_secret,omitempty"` // Client secret value, for application permissions
+	Authority    string `json:"authority,omitempty"`     // Sign-in host for national clouds (default DefaultAuthority)
+	GraphURL     string `json:"graph_url,omitempty"`     // Graph endpoint for national clouds (default DefaultGraphURL)
+}
+
+// LoadCredentials reads and checks a credentials file, filling in defaults
+func LoadCredentials(credentialsPath string) (*Credentials, error) {
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+
+	if creds.ClientID == "" {
+		return nil, fmt.Errorf("client_id is missing from credentials file")
+	}
+	if creds.TenantID == "" {
+		// The client credentials flow needs the tenant that granted the permissions
+		if creds.UsesClientCredentials() {
+			return nil, fmt.Errorf("tenant_id is required with a client secret")
+		}
+		creds.TenantID = "common"
+	}
+	if creds.Authority == "" {
+		creds.Authority = DefaultAuthority
+	}
+	if creds.GraphURL == "" {
+		creds.GraphURL = DefaultGraphURL
+	}
+	creds.Authority = strings.TrimRight(creds.Authority, "/")
+	creds.GraphURL = strings.TrimRight(creds.GraphURL, "/")
+
+	return &creds, nil
+}
+
+// UsesClientCredentials reports whether the app authenticates as itself with
+// a client secret rather than on behalf of a signed-in user
+func (c *Credentials) UsesClientCredentials() bool {
+	return c.ClientSecret != ""
+}
+
+// endpoint returns the OAuth2 endpoints of the tenant
+func (c *Credentials) endpoint() oauth2.Endpoint {
+	base := c.Authority + "/" + url.PathEscape(c.TenantID) + "/oauth2/v2.0"
+	return oauth2.Endpoint{
+		AuthURL:       base + "/authorize",
+		TokenURL:      base + "/token",
+		DeviceAuthURL: base + "/devicecode",
+		AuthStyle:     oauth2.AuthStyleInParams,
+	}
+}
+
+// NewClientCredentialsClient returns an HTTP client authenticated as the app
+// itself. Tokens are obtained and renewed from the client secret alone.
+func NewClientCredentialsClient(ctx context.Context, creds *Credentials) (*http.Client, error) {
+	endpoint := creds.endpoint()
+	config := &clientcredentials.Config{
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+		TokenURL:     endpoint.TokenURL,
+		Scopes:       []string{applicationScope},
+		AuthStyle:    endpoint.AuthStyle,
+	}
+
+	// Fail early on a wrong secret or tenant
+	tokenSource := config.TokenSource(ctx)
+	if _, err := tokenSource.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get token for client %s: %w", creds.ClientID, err)
+	}
+
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// DefaultTokenFile returns where the token of a signed-in user is stored when
+// no token file is configured: next to the credentials, with a .token suffix
+func DefaultTokenFile(credentialsPath string) string {
+	return credentialsPath + ".token"
+}
+
+// TokenManager keeps the delegated token obtained with the device code flow
+type TokenManager struct {
+	config    *oauth2.Config
+	tokenFile string
+	logger    *slog.Logger
+}
+
+// NewTokenManager creates a token manager storing its token in tokenPath
+func NewTokenManager(creds *Credentials, tokenPath string, logger *slog.Logger) *TokenManager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &TokenManager{
+		config: &oauth2.Config{
+			ClientID: creds.ClientID,
+			Scopes:   []string{calendarsReadScope, offlineAccessScope},
+			Endpoint: creds.endpoint(),
+		},
+		tokenFile: tokenPath,
+		logger:    logger,
+	}
+}
+
+// AuthorizeDevice runs the OAuth2 device code flow: prompt is given the code
+// the user enters at the verification URL on any device, after which the
+// token endpoint is polled until access is granted or the code expires. The
+// token is saved on success. The app registration must allow public client
+// flows.
+func (tm *TokenManager) AuthorizeDevice(ctx context.Context, prompt func(*oauth2.DeviceAuthResponse)) (*oauth2.Token, error) {
+	auth, err := tm.config.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device authorization: %w", err)
+	}
+
+	prompt(auth)
+
+	token, err := tm.config.DeviceAccessToken(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	if err := tm.SaveToken(token); err != nil {
+		return nil, fmt.Errorf("failed to save token: %w", err)
+	}
+
+	tm.logger.Info("successfully obtained and saved Microsoft Graph token")
+	return token, nil
+}
+
+// LoadToken loads the saved token from disk
+func (tm *TokenManager) LoadToken() (*oauth2.Token, error) {
+	data, err := os.ReadFile(tm.tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var token oauth2.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	return &token, nil
+}
+
+// SaveToken saves a token to disk, readable only by the owner. The file is
+// replaced atomically so a crash cannot leave a truncated token behind.
+func (tm *TokenManager) SaveToken(token *oauth2.Token) error {
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(tm.tokenFile), filepath.Base(tm.tokenFile)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), tm.tokenFile); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+
+	return nil
+}
+
+// IsTokenValid checks if a stored token exists and is valid
+func (tm *TokenManager) IsTokenValid() bool {
+	token, err := tm.LoadToken()
+	if err != nil {
+		return false
+	}
+
+	// Token is valid if it hasn't expired or has a refresh token
+	return token.Valid() || token.RefreshToken != ""
+}
+
+// GetClient returns an HTTP client that refreshes the stored token as needed
+// and saves every refreshed token. Microsoft rotates refresh tokens, so the
+// one on disk must be replaced each time.
+func (tm *TokenManager) GetClient(ctx context.Context) (*http.Client, error) {
+	token, err := tm.LoadToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load token: %w", err)
+	}
+
+	tokenSource := &savingTokenSource{
+		base:   tm.config.TokenSource(ctx, token),
+		tm:     tm,
+		access: token.AccessToken,
+	}
+
+	// Get a fresh token now, so a revoked grant fails at startup
+	if _, err := tokenSource.Token(); err != nil {
+		return nil, fmt.Errorf("failed to get valid token: %w", err)
+	}
+
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// savingTokenSource saves tokens its base source refreshed
+type savingTokenSource struct {
+	base oauth2.TokenSource
+	tm   *TokenManager
+
+	mu     sync.Mutex
+	access string // Access token last seen
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.AccessToken != s.access {
+		s.access = token.AccessToken
+		s.tm.logger.Info("token refreshed, saving new token")
+		if err := s.tm.SaveToken(token); err != nil {
+			s.tm.logger.Warn("failed to save refreshed token", "error", err)
+		}
+	}
+	return token, nil
+}
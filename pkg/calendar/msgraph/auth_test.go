@@ -0,0 +1,204 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeIdentity stands in for the token and device code endpoints of the
+// Microsoft identity platform for tenant "contoso"
+type fakeIdentity struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	pending  int // Device token polls answered with authorization_pending
+	requests []map[string]string
+}
+
+func (f *fakeIdentity) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := make(map[string]string)
+	for key := range r.PostForm {
+		form[key] = r.PostForm.Get(key)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, form)
+
+	switch r.URL.Path {
+	case "/contoso/oauth2/v2.0/devicecode":
+		writeJSON(f.t, w, map[string]any{
+			"device_code":      "device-123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://microsoft.com/devicelogin",
+			"expires_in":       900,
+			"interval":         1,
+		})
+	case "/contoso/oauth2/v2.0/token":
+		if form["grant_type"] == "urn:ietf:params:oauth:grant-type:device_code" && f.pending > 0 {
+			f.pending--
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		writeJSON(f.t, w, map[string]any{
+			"access_token":  "test-token",
+			"refresh_token": "refresh-123",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeCredentials(t *testing.T, dir string, creds map[string]string) string {
+	t.Helper()
+	data, _ := json.Marshal(creds)
+	path := filepath.Join(dir, "msgraph.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+	return path
+}
+
+func TestLoadCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		creds     map[string]string
+		expectErr bool
+		tenant    string
+	}{
+		{"Device flow defaults to common tenant", map[string]string{"client_id": "app"}, false, "common"},
+		{"Client credentials", map[string]string{"client_id": "app", "client_secret": "s3cret", "tenant_id": "contoso"}, false, "contoso"},
+		{"Client secret without tenant", map[string]string{"client_id": "app", "client_secret": "s3cret"}, true, ""},
+		{"Missing client ID", map[string]string{"tenant_id": "contoso"}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := LoadCredentials(writeCredentials(t, t.TempDir(), tt.creds))
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadCredentials() unexpected error: %v", err)
+			}
+			if creds.TenantID != tt.tenant || creds.Authority != DefaultAuthority || creds.GraphURL != DefaultGraphURL {
+				t.Errorf("Unexpected credentials %+v", creds)
+			}
+		})
+	}
+}
+
+func TestInitialize_ClientCredentials(t *testing.T) {
+	identity := &fakeIdentity{t: t}
+	identityServer := httptest.NewServer(identity)
+	defer identityServer.Close()
+	fake := newFakeGraph(t, "/users/alice@example.com")
+	fake.calendars = []map[string]any{{"id": "cal-1", "name": "Calendar", "isDefaultCalendar": true}}
+
+	credentialsPath := writeCredentials(t, t.TempDir(), map[string]string{
+		"tenant_id":     "contoso",
+		"client_id":     "app",
+		"client_secret": "s3cret",
+		"authority":     identityServer.URL,
+		"graph_url":     fake.server.URL,
+	})
+
+	provider := NewProvider()
+	if err := provider.Initialize(context.Background(), credentialsPath); err == nil {
+		t.Error("Expected error without a mailbox to read")
+	}
+
+	provider.SetUser("alice@example.com")
+	if err := provider.Initialize(context.Background(), credentialsPath); err != nil {
+		t.Fatalf("Initialize() unexpected error: %v", err)
+	}
+	if _, err := provider.GetCalendars(context.Background()); err != nil {
+		t.Fatalf("GetCalendars() unexpected error: %v", err)
+	}
+
+	request := identity.requests[0]
+	if request["grant_type"] != "client_credentials" || request["scope"] != applicationScope || request["client_secret"] != "s3cret" {
+		t.Errorf("Unexpected token request %v", request)
+	}
+}
+
+func TestAuthorizeDevice(t *testing.T) {
+	identity := &fakeIdentity{t: t, pending: 1}
+	identityServer := httptest.NewServer(identity)
+	defer identityServer.Close()
+	fake := newFakeGraph(t, "/me")
+	fake.calendars = []map[string]any{{"id": "cal-1", "name": "Calendar", "isDefaultCalendar": true}}
+
+	dir := t.TempDir()
+	credentialsPath := writeCredentials(t, dir, map[string]string{
+		"tenant_id": "contoso",
+		"client_id": "app",
+		"authority": identityServer.URL,
+		"graph_url": fake.server.URL,
+	})
+
+	// Nothing to use before the user signs in
+	provider := NewProvider()
+	if err := provider.Initialize(context.Background(), credentialsPath); err == nil || !strings.Contains(err.Error(), "authentication required") {
+		t.Errorf("Expected authentication to be required, got %v", err)
+	}
+
+	creds, err := LoadCredentials(credentialsPath)
+	if err != nil {
+		t.Fatalf("LoadCredentials() unexpected error: %v", err)
+	}
+	tm := NewTokenManager(creds, DefaultTokenFile(credentialsPath), nil)
+
+	var prompted *oauth2.DeviceAuthResponse
+	token, err := tm.AuthorizeDevice(context.Background(), func(auth *oauth2.DeviceAuthResponse) { prompted = auth })
+	if err != nil {
+		t.Fatalf("AuthorizeDevice() unexpected error: %v", err)
+	}
+	if prompted == nil || prompted.UserCode != "ABCD-EFGH" {
+		t.Errorf("Expected the user code to be shown, got %+v", prompted)
+	}
+	if token.RefreshToken != "refresh-123" {
+		t.Errorf("Expected a refresh token, got %+v", token)
+	}
+
+	scope := identity.requests[0]["scope"]
+	if !strings.Contains(scope, calendarsReadScope) || !strings.Contains(scope, offlineAccessScope) {
+		t.Errorf("Expected delegated calendar and offline scopes, got %q", scope)
+	}
+
+	info, err := os.Stat(DefaultTokenFile(credentialsPath))
+	if err != nil {
+		t.Fatalf("Expected token file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected token file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// The saved token signs the provider in
+	if err := provider.Initialize(context.Background(), credentialsPath); err != nil {
+		t.Fatalf("Initialize() unexpected error: %v", err)
+	}
+	if _, err := provider.GetCalendars(context.Background()); err != nil {
+		t.Errorf("GetCalendars() unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package msgraph
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// graphDateTimeLayout is the layout of dateTime in Graph's dateTimeTimeZone,
+// which carries no offset and up to seven fractional digits
+const graphDateTimeLayout = "2006-01-02T15:04:05.9999999"
+
+// graphEvent is the part of a Graph event resource the notifier uses
+type graphEvent struct {
+	ID                         string               `json:"id"`
+	Subject                    string               `json:"subject"`
+	BodyPreview                string               `json:"bodyPreview"`
+	Start                      graphDateTime        `json:"start"`
+	End                        graphDateTime        `json:"end"`
+	IsAllDay                   bool                 `json:"isAllDay"`
+	IsCancelled                bool                 `json:"isCancelled"`
+	IsReminderOn               bool                 `json:"isReminderOn"`
+	ReminderMinutesBeforeStart int                  `json:"reminderMinutesBeforeStart"`
+	ResponseStatus             *graphResponseStatus `json:"responseStatus"`
+	ShowAs                     string               `json:"showAs"` // free, tentative, busy, oof, workingElsewhere, unknown
+	Location                   *graphLocation       `json:"location"`
+	CreatedDateTime            string               `json:"createdDateTime"`
+	LastModifiedDateTime       string               `json:"lastModifiedDateTime"`
+	OnlineMeeting              *graphOnlineMeeting  `json:"onlineMeeting"`
+	OnlineMeetingURL           string               `json:"onlineMeetingUrl"` // Older field, set by some clients only
+	WebLink                    string               `json:"webLink"`
+	Organizer                  *graphRecipient      `json:"organizer"`
+}
+
+type graphDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphResponseStatus struct {
+	Response string `json:"response"` // none, organizer, tentativelyAccepted, accepted, declined, notResponded
+}
+
+type graphLocation struct {
+	DisplayName string `json:"displayName"`
+}
+
+type graphOnlineMeeting struct {
+	JoinURL string `json:"joinUrl"`
+}
+
+type graphRecipient struct {
+	EmailAddress struct {
+		Name    string `json:"name"`
+		Address string `json:"address"`
+	} `json:"emailAddress"`
+}
+
+// convertEvent converts a Graph event to our internal Event model
+func (p *Provider) convertEvent(item *graphEvent, calendarID string) (*models.Event, error) {
+	startTime, err := parseGraphTime(item.Start, item.IsAllDay, p.location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse start time: %w", err)
+	}
+
+	endTime, err := parseGraphTime(item.End, item.IsAllDay, p.location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse end time: %w", err)
+	}
+
+	var createdAt, modifiedAt time.Time
+	if item.CreatedDateTime != "" {
+		if createdAt, err = time.Parse(time.RFC3339, item.CreatedDateTime); err != nil {
+			p.logger.Warn("failed to parse created time, using zero value",
+				"event_id", item.ID,
+				"error", err)
+		}
+	}
+	if item.LastModifiedDateTime != "" {
+		if modifiedAt, err = time.Parse(time.RFC3339, item.LastModifiedDateTime); err != nil {
+			p.logger.Warn("failed to parse modified time, using zero value",
+				"event_id", item.ID,
+				"error", err)
+		}
+	}
+
+	event := &models.Event{
+		ID:           item.ID,
+		Title:        item.Subject,
+		Description:  item.BodyPreview,
+		StartTime:    startTime,
+		EndTime:      endTime,
+		AllDay:       item.IsAllDay,
+		CalendarID:   calendarID,
+		CreatedAt:    createdAt,
+		ModifiedAt:   modifiedAt,
+		Status:       models.EventStatusConfirmed,
+		Transparency: models.TransparencyOpaque,
+		HTMLLink:     item.WebLink,
+	}
+
+	// Graph has one reminder per event, popped up in Outlook
+	if item.IsReminderOn {
+		event.Alarms = []models.Alarm{{
+			LeadTimeMinutes: item.ReminderMinutesBeforeStart,
+			Severity:        "normal",
+			Method:          "popup",
+		}}
+	}
+
+	if item.IsCancelled {
+		event.Status = models.EventStatusCancelled
+	}
+	if item.ShowAs == "free" {
+		event.Transparency = models.TransparencyTransparent
+	}
+	if item.ResponseStatus != nil {
+		event.ResponseStatus = convertResponse(item.ResponseStatus.Response)
+	}
+	if item.Location != nil {
+		event.Location = item.Location.DisplayName
+	}
+	if item.Organizer != nil {
+		event.Organizer = item.Organizer.EmailAddress.Address
+	}
+
+	event.JoinURL = item.OnlineMeetingURL
+	if item.OnlineMeeting != nil && item.OnlineMeeting.JoinURL != "" {
+		event.JoinURL = item.OnlineMeeting.JoinURL
+	}
+
+	return event, nil
+}
+
+// parseGraphTime parses a Graph dateTimeTimeZone. All-day events start and end
+// at midnight, which is taken in loc (or the local zone) like the other
+// providers do, whatever zone Graph reported the date in.
+func parseGraphTime(dt graphDateTime, allDay bool, loc *time.Location) (time.Time, error) {
+	if dt.DateTime == "" {
+		return time.Time{}, fmt.Errorf("no dateTime field found")
+	}
+
+	if allDay {
+		if loc == nil {
+			loc = time.Local
+		}
+		if len(dt.DateTime) < len("2006-01-02") {
+			return time.Time{}, fmt.Errorf("invalid date %q", dt.DateTime)
+		}
+		return time.ParseInLocation("2006-01-02", dt.DateTime[:len("2006-01-02")], loc)
+	}
+
+	// Requests ask for UTC, but honour whatever zone is given
+	zone := time.UTC
+	if dt.TimeZone != "" && dt.TimeZone != "UTC" {
+		tzLoc, err := time.LoadLocation(dt.TimeZone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown time zone %q: %w", dt.TimeZone, err)
+		}
+		zone = tzLoc
+	}
+
+	t, err := time.ParseInLocation(graphDateTimeLayout, dt.DateTime, zone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse datetime: %w", err)
+	}
+	return t, nil
+}
+
+// convertResponse maps a Graph response to our response status. Meetings
+// the user organizes or was not invited to count as accepted.
+func convertResponse(response string) string {
+	switch response {
+	case "accepted", "organizer", "none":
+		return "accepted"
+	case "tentativelyAccepted":
+		return "tentative"
+	case "declined":
+		return "declined"
+	case "notResponded":
+		return "needsAction"
+	default:
+		return ""
+	}
+}
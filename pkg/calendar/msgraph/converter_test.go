@@ -0,0 +1,197 @@
+package msgraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+func TestParseGraphTime(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+
+	tests := []struct {
+		name      string
+		dt        graphDateTime
+		allDay    bool
+		expected  time.Time
+		expectErr bool
+	}{
+		{
+			name:     "UTC with fractional seconds",
+			dt:       graphDateTime{DateTime: "2025-10-01T09:30:00.0000000", TimeZone: "UTC"},
+			expected: time.Date(2025, 10, 1, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "IANA zone",
+			dt:       graphDateTime{DateTime: "2025-10-01T09:30:00", TimeZone: "Europe/London"},
+			expected: time.Date(2025, 10, 1, 9, 30, 0, 0, london),
+		},
+		{
+			name:     "All-day date in provider zone",
+			dt:       graphDateTime{DateTime: "2025-10-01T00:00:00.0000000", TimeZone: "UTC"},
+			allDay:   true,
+			expected: time.Date(2025, 10, 1, 0, 0, 0, 0, london),
+		},
+		{
+			name:      "Missing dateTime",
+			dt:        graphDateTime{TimeZone: "UTC"},
+			expectErr: true,
+		},
+		{
+			name:      "Invalid dateTime",
+			dt:        graphDateTime{DateTime: "tomorrow", TimeZone: "UTC"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseGraphTime(tt.dt, tt.allDay, london)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseGraphTime() unexpected error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("parseGraphTime() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConvertEvent(t *testing.T) {
+	provider := NewProvider()
+	start := graphDateTime{DateTime: "2025-10-01T09:00:00.0000000", TimeZone: "UTC"}
+	end := graphDateTime{DateTime: "2025-10-01T10:00:00.0000000", TimeZone: "UTC"}
+
+	item := &graphEvent{
+		ID:                         "AAMk-1",
+		Subject:                    "Planning",
+		BodyPreview:                "Quarterly planning",
+		Start:                      start,
+		End:                        end,
+		IsReminderOn:               true,
+		ReminderMinutesBeforeStart: 15,
+		ResponseStatus:             &graphResponseStatus{Response: "tentativelyAccepted"},
+		ShowAs:                     "busy",
+		Location:                   &graphLocation{DisplayName: "Room 4"},
+		CreatedDateTime:            "2025-09-20T10:00:00.1234567Z",
+		OnlineMeeting:              &graphOnlineMeeting{JoinURL: "https://teams.microsoft.com/l/meetup-join/abc"},
+		OnlineMeetingURL:           "https://example.com/old",
+		WebLink:                    "https://outlook.office365.com/owa/?itemid=AAMk-1",
+		Organizer:                  &graphRecipient{},
+	}
+	item.Organizer.EmailAddress.Address = "boss@example.com"
+
+	event, err := provider.convertEvent(item, "cal-1")
+	if err != nil {
+		t.Fatalf("convertEvent() unexpected error: %v", err)
+	}
+
+	if event.ID != "AAMk-1" || event.Title != "Planning" || event.Description != "Quarterly planning" || event.CalendarID != "cal-1" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if !event.StartTime.Equal(time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)) || event.AllDay {
+		t.Errorf("Unexpected start %v (all day %v)", event.StartTime, event.AllDay)
+	}
+	if len(event.Alarms) != 1 || event.Alarms[0].LeadTimeMinutes != 15 || event.Alarms[0].Method != "popup" {
+		t.Errorf("Expected one 15 minute reminder, got %+v", event.Alarms)
+	}
+	if event.ResponseStatus != "tentative" || event.Status != models.EventStatusConfirmed || event.Transparency != models.TransparencyOpaque {
+		t.Errorf("Unexpected response %q, status %q, transparency %q", event.ResponseStatus, event.Status, event.Transparency)
+	}
+	if event.Location != "Room 4" || event.Organizer != "boss@example.com" || event.HTMLLink != item.WebLink {
+		t.Errorf("Unexpected location %q, organizer %q or link %q", event.Location, event.Organizer, event.HTMLLink)
+	}
+	if event.JoinURL != "https://teams.microsoft.com/l/meetup-join/abc" {
+		t.Errorf("Expected the online meeting join URL, got %q", event.JoinURL)
+	}
+	if event.CreatedAt.IsZero() {
+		t.Error("Expected created time to be parsed")
+	}
+}
+
+func TestConvertEventStatus(t *testing.T) {
+	provider := NewProvider()
+	start := graphDateTime{DateTime: "2025-10-01T09:00:00", TimeZone: "UTC"}
+	end := graphDateTime{DateTime: "2025-10-01T10:00:00", TimeZone: "UTC"}
+
+	tests := []struct {
+		name                 string
+		item                 graphEvent
+		expectedStatus       string
+		expectedTransparency string
+		expectedResponse     string
+		expectedJoinURL      string
+		expectedAlarms       int
+	}{
+		{
+			name:                 "cancelled",
+			item:                 graphEvent{IsCancelled: true, IsReminderOn: true},
+			expectedStatus:       models.EventStatusCancelled,
+			expectedTransparency: models.TransparencyOpaque,
+			expectedAlarms:       1,
+		},
+		{
+			name:                 "free without reminder",
+			item:                 graphEvent{ShowAs: "free"},
+			expectedStatus:       models.EventStatusConfirmed,
+			expectedTransparency: models.TransparencyTransparent,
+		},
+		{
+			name:                 "organizer with legacy meeting URL",
+			item:                 graphEvent{ResponseStatus: &graphResponseStatus{Response: "organizer"}, OnlineMeetingURL: "https://example.com/join"},
+			expectedStatus:       models.EventStatusConfirmed,
+			expectedTransparency: models.TransparencyOpaque,
+			expectedResponse:     "accepted",
+			expectedJoinURL:      "https://example.com/join",
+		},
+		{
+			name:                 "not invited",
+			item:                 graphEvent{ResponseStatus: &graphResponseStatus{Response: "none"}},
+			expectedStatus:       models.EventStatusConfirmed,
+			expectedTransparency: models.TransparencyOpaque,
+			expectedResponse:     "accepted",
+		},
+		{
+			name:                 "not responded",
+			item:                 graphEvent{ResponseStatus: &graphResponseStatus{Response: "notResponded"}},
+			expectedStatus:       models.EventStatusConfirmed,
+			expectedTransparency: models.TransparencyOpaque,
+			expectedResponse:     "needsAction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.item.ID = "status-test"
+			tt.item.Start = start
+			tt.item.End = end
+
+			event, err := provider.convertEvent(&tt.item, "cal-1")
+			if err != nil {
+				t.Fatalf("convertEvent() unexpected error: %v", err)
+			}
+			if event.Status != tt.expectedStatus {
+				t.Errorf("convertEvent() Status = %v, want %v", event.Status, tt.expectedStatus)
+			}
+			if event.Transparency != tt.expectedTransparency {
+				t.Errorf("convertEvent() Transparency = %v, want %v", event.Transparency, tt.expectedTransparency)
+			}
+			if event.ResponseStatus != tt.expectedResponse {
+				t.Errorf("convertEvent() ResponseStatus = %v, want %v", event.ResponseStatus, tt.expectedResponse)
+			}
+			if event.JoinURL != tt.expectedJoinURL {
+				t.Errorf("convertEvent() JoinURL = %v, want %v", event.JoinURL, tt.expectedJoinURL)
+			}
+			if len(event.Alarms) != tt.expectedAlarms {
+				t.Errorf("convertEvent() got %d alarms, want %d", len(event.Alarms), tt.expectedAlarms)
+			}
+		})
+	}
+}
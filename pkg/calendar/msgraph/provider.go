@@ -0,0 +1,426 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+	pkgcalendar "github.com/venkytv/calendar-notifier/pkg/calendar"
+	"github.com/venkytv/calendar-notifier/pkg/retry"
+)
+
+const (
+	providerType = "msgraph"
+	providerName = "Microsoft Graph"
+
+	// pageSize is the number of items asked for per page
+	pageSize = 100
+
+	// eventFields limits calendarView responses to what convertEvent uses
+	eventFields = "id,subject,bodyPreview,start,end,isAllDay,isCancelled,isReminderOn," +
+		"reminderMinutesBeforeStart,responseStatus,showAs,location,createdDateTime," +
+		"lastModifiedDateTime,onlineMeeting,onlineMeetingUrl,webLink,organizer"
+)
+
+// Provider implements the calendar.Provider interface for Outlook and
+// Microsoft 365 calendars through the Microsoft Graph API
+type Provider struct {
+	name        string
+	client      *http.Client
+	graphURL    string
+	tokenFile   string
+	user        string // Mailbox read with application permissions; empty means the signed-in user
+	calendarIDs []string
+	location    *time.Location
+	logger      *slog.Logger
+	retryer     *retry.Retryer
+}
+
+// NewProvider creates a new Microsoft Graph provider
+func NewProvider() *Provider {
+	logger := slog.Default()
+	return &Provider{
+		name:    providerName,
+		logger:  logger,
+		retryer: retry.NewRetryer(retryConfig(), logger),
+	}
+}
+
+// retryConfig returns the retry configuration for Graph requests. Graph
+// throttles with 429 and asks for a wait with Retry-After, which the retryer
+// honours.
+func retryConfig() *retry.Config {
+	return &retry.Config{
+		MaxAttempts:   3,
+		InitialDelay:  2 * time.Second,
+		MaxDelay:      30 * time.Second,
+		BackoffFactor: 2.0,
+		Jitter:        true,
+		RetriableStatuses: []int{
+			http.StatusRequestTimeout,      // 408
+			http.StatusTooManyRequests,     // 429
+			http.StatusInternalServerError, // 500
+			http.StatusBadGateway,          // 502
+			http.StatusServiceUnavailable,  // 503
+			http.StatusGatewayTimeout,      // 504
+		},
+		RetriableErrors: []string{
+			"connection refused",
+			"timeout",
+			"temporary failure",
+			"network unreachable",
+			"no such host",
+			"connection reset",
+		},
+	}
+}
+
+// Name returns the human-readable name of the provider
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Type returns the provider type identifier
+func (p *Provider) Type() string {
+	return providerType
+}
+
+// SetLogger configures the logger for this provider
+func (p *Provider) SetLogger(logger *slog.Logger) {
+	if logger != nil {
+		p.logger = logger
+		p.retryer = retry.NewRetryer(retryConfig(), logger)
+	}
+}
+
+// Initialize sets up the provider from an app registration credentials file.
+// With a client secret the app authenticates as itself and reads the mailbox
+// set with SetUser; otherwise the token saved by the device code flow is used.
+func (p *Provider) Initialize(ctx context.Context, credentialsPath string) error {
+	p.logger.Info("initializing Microsoft Graph provider", "credentials", credentialsPath)
+
+	creds, err := LoadCredentials(credentialsPath)
+	if err != nil {
+		return err
+	}
+	p.graphURL = creds.GraphURL
+
+	if creds.UsesClientCredentials() {
+		if p.user == "" {
+			return fmt.Errorf("application permissions have no signed-in user: set the mailbox to read with impersonate")
+		}
+		client, err := NewClientCredentialsClient(ctx, creds)
+		if err != nil {
+			return err
+		}
+		p.client = client
+
+		p.logger.Info("Microsoft Graph provider initialized with client credentials", "user", p.user)
+		return nil
+	}
+	if p.user != "" {
+		return fmt.Errorf("reading the calendars of %s requires a client secret (application permissions)", p.user)
+	}
+
+	if p.tokenFile == "" {
+		p.tokenFile = DefaultTokenFile(credentialsPath)
+	}
+
+	tm := NewTokenManager(creds, p.tokenFile, p.logger)
+	if !tm.IsTokenValid() {
+		return fmt.Errorf("authentication required: no valid token in %s", p.tokenFile)
+	}
+
+	client, err := tm.GetClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get authenticated client: %w", err)
+	}
+	p.client = client
+
+	p.logger.Info("Microsoft Graph provider initialized successfully")
+	return nil
+}
+
+// graphCalendar is the part of a Graph calendar resource the notifier uses
+type graphCalendar struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	HexColor          string `json:"hexColor"`
+	IsDefaultCalendar bool   `json:"isDefaultCalendar"`
+	CanEdit           bool   `json:"canEdit"`
+}
+
+// GetCalendars returns the configured calendars, or the default calendar if
+// no calendar IDs are configured
+func (p *Provider) GetCalendars(ctx context.Context) ([]*pkgcalendar.Calendar, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	p.logger.Debug("fetching calendar list")
+
+	configuredIDs := make(map[string]bool)
+	for _, id := range p.calendarIDs {
+		configuredIDs[id] = true
+	}
+
+	query := url.Values{"$select": {"id,name,hexColor,isDefaultCalendar,canEdit"}}
+	var calendars []*pkgcalendar.Calendar
+	err := p.list(ctx, p.userURL("/calendars", query), func(data json.RawMessage) error {
+		var item graphCalendar
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+
+		if len(configuredIDs) > 0 && !configuredIDs[item.ID] {
+			return nil
+		}
+		if len(configuredIDs) == 0 && !item.IsDefaultCalendar {
+			return nil
+		}
+
+		accessRole := "reader"
+		if item.CanEdit {
+			accessRole = "writer"
+		}
+		calendars = append(calendars, &pkgcalendar.Calendar{
+			ID:         item.ID,
+			Name:       item.Name,
+			Primary:    item.IsDefaultCalendar,
+			AccessRole: accessRole,
+			Color:      item.HexColor,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar list: %w", err)
+	}
+
+	p.logger.Debug("fetched calendars", "count", len(calendars))
+	return calendars, nil
+}
+
+// GetEvents retrieves events from specified calendars within the time range.
+// calendarView expands recurring events into their occurrences.
+func (p *Provider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("provider not initialized")
+	}
+
+	p.logger.Debug("fetching events",
+		"calendar_count", len(calendarIDs),
+		"from", from.Format(time.RFC3339),
+		"to", to.Format(time.RFC3339))
+
+	var allEvents []*models.Event
+
+	for _, calendarID := range calendarIDs {
+		events, err := p.getEventsFromCalendar(ctx, calendarID, from, to)
+		if err != nil {
+			p.logger.Error("failed to fetch events from calendar",
+				"calendar_id", calendarID,
+				"error", err)
+			return nil, fmt.Errorf("failed to fetch events from calendar %s: %w", calendarID, err)
+		}
+
+		allEvents = append(allEvents, events...)
+	}
+
+	p.logger.Debug("fetched events", "total_count", len(allEvents))
+	return allEvents, nil
+}
+
+// getEventsFromCalendar lists the calendar view of one calendar, page by page
+func (p *Provider) getEventsFromCalendar(ctx context.Context, calendarID string, from, to time.Time) ([]*models.Event, error) {
+	query := url.Values{
+		"startDateTime": {from.UTC().Format(time.RFC3339)},
+		"endDateTime":   {to.UTC().Format(time.RFC3339)},
+		"$select":       {eventFields},
+		"$orderby":      {"start/dateTime"},
+	}
+	path := "/calendars/" + url.PathEscape(calendarID) + "/calendarView"
+
+	var events []*models.Event
+	err := p.list(ctx, p.userURL(path, query), func(data json.RawMessage) error {
+		var item graphEvent
+		if err := json.Unmarshal(data, &item); err != nil {
+			return err
+		}
+
+		event, err := p.convertEvent(&item, calendarID)
+		if err != nil {
+			p.logger.Warn("failed to convert event, skipping",
+				"event_id", item.ID,
+				"title", item.Subject,
+				"error", err)
+			return nil
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// IsHealthy performs a health check on the provider
+func (p *Provider) IsHealthy(ctx context.Context) error {
+	if p.client == nil {
+		return fmt.Errorf("provider not initialized")
+	}
+
+	query := url.Values{"$top": {"1"}, "$select": {"id"}}
+	var page struct {
+		Value []json.RawMessage `json:"value"`
+	}
+	if err := p.get(ctx, p.userURL("/calendars", query), &page); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	return nil
+}
+
+// Close cleans up any resources used by the provider
+func (p *Provider) Close() error {
+	p.logger.Debug("closing Microsoft Graph provider")
+	p.client = nil
+	return nil
+}
+
+// SetTokenFile sets a custom token file path (must be called before Initialize)
+func (p *Provider) SetTokenFile(tokenFile string) {
+	p.tokenFile = tokenFile
+}
+
+// SetUser sets the user, by ID or principal name, whose mailbox is read with
+// application permissions (must be called before Initialize)
+func (p *Provider) SetUser(user string) {
+	p.user = user
+}
+
+// SetTimeZone sets the IANA time zone for all-day events (defaults to the
+// local time zone)
+func (p *Provider) SetTimeZone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	p.location = loc
+	return nil
+}
+
+// SetCalendarIDs sets the calendar IDs to monitor
+func (p *Provider) SetCalendarIDs(calendarIDs []string) {
+	p.calendarIDs = calendarIDs
+}
+
+// userURL returns the URL of a resource of the user's mailbox
+func (p *Provider) userURL(path string, query url.Values) string {
+	user := "/me"
+	if p.user != "" {
+		user = "/users/" + url.PathEscape(p.user)
+	}
+	return p.graphURL + user + path + "?" + query.Encode()
+}
+
+// graphError is an error response of the Graph API
+type graphError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	URL        string
+	RetryAfter time.Duration
+}
+
+func (e *graphError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("Graph API returned %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Graph API returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap exposes the status for retry classification
+func (e *graphError) Unwrap() error {
+	httpErr := retry.NewHTTPError(e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+	httpErr.RetryAfter = e.RetryAfter
+	return httpErr
+}
+
+// list walks a paged collection, passing each item to fn. Graph links the
+// next page with @odata.nextLink, an absolute URL with the query included.
+func (p *Provider) list(ctx context.Context, pageURL string, fn func(json.RawMessage) error) error {
+	for pageURL != "" {
+		var page struct {
+			Value    []json.RawMessage `json:"value"`
+			NextLink string            `json:"@odata.nextLink"`
+		}
+		if err := p.get(ctx, pageURL, &page); err != nil {
+			return err
+		}
+
+		for _, item := range page.Value {
+			if err := fn(item); err != nil {
+				return fmt.Errorf("failed to parse response: %w", err)
+			}
+		}
+		pageURL = page.NextLink
+	}
+	return nil
+}
+
+// get fetches a Graph resource into out, retrying throttled and failed
+// requests. Times are requested in UTC.
+func (p *Provider) get(ctx context.Context, resourceURL string, out any) error {
+	return p.retryer.Do(ctx, func() error {
+		return p.getOnce(ctx, resourceURL, out)
+	})
+}
+
+// getOnce makes a single request for a Graph resource
+func (p *Provider) getOnce(ctx context.Context, resourceURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Add("Prefer", `outlook.timezone="UTC"`)
+	req.Header.Add("Prefer", fmt.Sprintf("odata.maxpagesize=%d", pageSize))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		gerr := &graphError{
+			StatusCode: resp.StatusCode,
+			URL:        resourceURL,
+			RetryAfter: retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024)); err == nil && json.Unmarshal(data, &body) == nil {
+			gerr.Code = body.Error.Code
+			gerr.Message = body.Error.Message
+		}
+		return gerr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package msgraph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/pkg/retry"
+)
+
+// fakeGraph stands in for the calendars and calendarView endpoints of the
+// Graph API, serving items in pages of pageSize like Graph does
+type fakeGraph struct {
+	t        *testing.T
+	server   *httptest.Server
+	user     string // Path prefix, "/me" or "/users/<id>"
+	pageSize int
+
+	calendars []map[string]any
+	events    map[string][]map[string]any // Keyed by calendar ID
+	requests  []*http.Request
+	throttle  int // Number of requests to answer with 429 first
+}
+
+func newFakeGraph(t *testing.T, user string) *fakeGraph {
+	f := &fakeGraph{t: t, user: user, pageSize: 2, events: make(map[string][]map[string]any)}
+	f.server = httptest.NewServer(f)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeGraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r)
+
+	if f.throttle > 0 {
+		f.throttle--
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "TooManyRequests", "Too many requests.")
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		writeError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token is empty.")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, f.user)
+	switch {
+	case path == "/calendars":
+		f.writePage(w, r, f.calendars)
+	case strings.HasPrefix(path, "/calendars/") && strings.HasSuffix(path, "/calendarView"):
+		calendarID := strings.TrimSuffix(strings.TrimPrefix(path, "/calendars/"), "/calendarView")
+		items, ok := f.events[calendarID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+			return
+		}
+		f.writePage(w, r, items)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writePage writes the page selected by the skip parameter of a next link
+func (f *fakeGraph) writePage(w http.ResponseWriter, r *http.Request, items []map[string]any) {
+	skip, _ := strconv.Atoi(r.URL.Query().Get("$skip"))
+	end := min(skip+f.pageSize, len(items))
+
+	page := map[string]any{"value": items[skip:end]}
+	if end < len(items) {
+		query := r.URL.Query()
+		query.Set("$skip", strconv.Itoa(end))
+		page["@odata.nextLink"] = f.server.URL + r.URL.Path + "?" + query.Encode()
+	}
+	writeJSON(f.t, w, page)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("Failed to write response: %v", err)
+	}
+}
+
+// writeError writes an error response the way Graph does
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"code": code, "message": message}})
+}
+
+// bearerTransport adds a fixed access token to each request
+type bearerTransport struct{}
+
+func (bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("Authorization", "Bearer test-token")
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newTestProvider(f *fakeGraph) *Provider {
+	provider := NewProvider()
+	provider.client = &http.Client{Transport: bearerTransport{}}
+	provider.graphURL = f.server.URL
+	return provider
+}
+
+func testEvent(id string, start time.Time) map[string]any {
+	return map[string]any{
+		"id":                         id,
+		"subject":                    id,
+		"start":                      map[string]string{"dateTime": start.UTC().Format(graphDateTimeLayout), "timeZone": "UTC"},
+		"end":                        map[string]string{"dateTime": start.Add(time.Hour).UTC().Format(graphDateTimeLayout), "timeZone": "UTC"},
+		"isReminderOn":               true,
+		"reminderMinutesBeforeStart": 15,
+		"showAs":                     "busy",
+	}
+}
+
+func TestProvider_GetEventsPaginates(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := newFakeGraph(t, "/me")
+	fake.events["cal-1"] = []map[string]any{
+		testEvent("standup", from.Add(9*time.Hour)),
+		testEvent("review", from.Add(14*time.Hour)),
+		testEvent("retro", from.Add(16*time.Hour)),
+	}
+	provider := newTestProvider(fake)
+
+	events, err := provider.GetEvents(context.Background(), []string{"cal-1"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+
+	var ids []string
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	if strings.Join(ids, ",") != "standup,review,retro" {
+		t.Fatalf("Expected events from both pages, got %v", ids)
+	}
+	if events[0].CalendarID != "cal-1" || len(events[0].Alarms) != 1 || events[0].Alarms[0].LeadTimeMinutes != 15 {
+		t.Errorf("Unexpected event %+v", events[0])
+	}
+
+	if len(fake.requests) != 2 {
+		t.Fatalf("Expected 2 page requests, got %d", len(fake.requests))
+	}
+	first := fake.requests[0]
+	query := first.URL.Query()
+	if query.Get("startDateTime") != "2025-10-01T00:00:00Z" || query.Get("endDateTime") != "2025-10-02T00:00:00Z" {
+		t.Errorf("Unexpected calendar view range %v", query)
+	}
+	if !strings.Contains(strings.Join(first.Header.Values("Prefer"), ";"), `outlook.timezone="UTC"`) {
+		t.Errorf("Expected times to be requested in UTC, got Prefer %v", first.Header.Values("Prefer"))
+	}
+	if fake.requests[1].URL.Query().Get("$skip") != "2" {
+		t.Errorf("Expected the second request to follow the next link, got %v", fake.requests[1].URL)
+	}
+}
+
+func TestProvider_GetCalendars(t *testing.T) {
+	fake := newFakeGraph(t, "/me")
+	fake.calendars = []map[string]any{
+		{"id": "cal-1", "name": "Calendar", "isDefaultCalendar": true, "canEdit": true, "hexColor": "#3a96dd"},
+		{"id": "cal-2", "name": "Birthdays"},
+		{"id": "cal-3", "name": "Team"},
+	}
+
+	tests := []struct {
+		name        string
+		calendarIDs []string
+		expected    []string
+	}{
+		{"Default calendar when none configured", nil, []string{"cal-1"}},
+		{"Configured calendars across pages", []string{"cal-3", "cal-2"}, []string{"cal-2", "cal-3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestProvider(fake)
+			provider.SetCalendarIDs(tt.calendarIDs)
+
+			calendars, err := provider.GetCalendars(context.Background())
+			if err != nil {
+				t.Fatalf("GetCalendars() unexpected error: %v", err)
+			}
+
+			var ids []string
+			for _, cal := range calendars {
+				ids = append(ids, cal.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("GetCalendars() = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+
+	provider := newTestProvider(fake)
+	calendars, _ := provider.GetCalendars(context.Background())
+	if cal := calendars[0]; !cal.Primary || cal.Name != "Calendar" || cal.AccessRole != "writer" || cal.Color != "#3a96dd" {
+		t.Errorf("Unexpected default calendar %+v", cal)
+	}
+}
+
+func TestProvider_ReadsUserMailbox(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := newFakeGraph(t, "/users/alice@example.com")
+	fake.events["cal-1"] = []map[string]any{testEvent("standup", from.Add(9*time.Hour))}
+	provider := newTestProvider(fake)
+	provider.SetUser("alice@example.com")
+
+	events, err := provider.GetEvents(context.Background(), []string{"cal-1"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event from the user's mailbox, got %d", len(events))
+	}
+}
+
+func TestProvider_GraphError(t *testing.T) {
+	fake := newFakeGraph(t, "/me")
+	provider := newTestProvider(fake)
+
+	_, err := provider.GetEvents(context.Background(), []string{"missing"}, time.Now(), time.Now().Add(time.Hour))
+	var gerr *graphError
+	if !errors.As(err, &gerr) {
+		t.Fatalf("Expected a Graph error, got %v", err)
+	}
+	if gerr.StatusCode != http.StatusNotFound || gerr.Code != "ErrorItemNotFound" {
+		t.Errorf("Unexpected error %+v", gerr)
+	}
+
+	if err := provider.IsHealthy(context.Background()); err != nil {
+		t.Errorf("IsHealthy() unexpected error: %v", err)
+	}
+	provider.client = http.DefaultClient
+	if err := provider.IsHealthy(context.Background()); err == nil || !strings.Contains(err.Error(), "InvalidAuthenticationToken") {
+		t.Errorf("Expected health check to report the auth error, got %v", err)
+	}
+}
+
+func TestProvider_RetriesThrottledRequests(t *testing.T) {
+	from := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	fake := newFakeGraph(t, "/me")
+	fake.events["cal-1"] = []map[string]any{testEvent("standup", from.Add(9*time.Hour))}
+	fake.throttle = 1
+	provider := newTestProvider(fake)
+
+	// Back off by milliseconds, so any longer wait comes from Retry-After
+	config := retryConfig()
+	config.InitialDelay = time.Millisecond
+	config.MaxDelay = 10 * time.Millisecond
+	provider.retryer = retry.NewRetryer(config, provider.logger)
+
+	start := time.Now()
+	events, err := provider.GetEvents(context.Background(), []string{"cal-1"}, from, from.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("GetEvents() unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event after the retry, got %d", len(events))
+	}
+	if len(fake.requests) != 2 {
+		t.Errorf("Expected the throttled request to be retried once, got %d requests", len(fake.requests))
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the retry to wait for Retry-After, waited %v", elapsed)
+	}
+}
@@ -3,8 +3,10 @@ package providers
 import (
 	"github.com/venkytv/calendar-notifier/pkg/calendar"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/caldav"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/file"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/google"
 	"github.com/venkytv/calendar-notifier/pkg/calendar/ical"
+	"github.com/venkytv/calendar-notifier/pkg/calendar/msgraph"
 )
 
 // InitializeBuiltinProviders registers all built-in calendar providers with the factory
@@ -19,8 +21,18 @@ func InitializeBuiltinProviders(factory *calendar.DefaultProviderFactory) {
 		return ical.NewProvider()
 	})
 
+	// Register file provider (local .ics files and vdir stores)
+	factory.RegisterProvider("file", func() calendar.Provider {
+		return file.NewProvider()
+	})
+
 	// Register Google Calendar provider (OAuth2-based)
 	factory.RegisterProvider("google", func() calendar.Provider {
 		return google.NewProvider()
 	})
+
+	// Register Microsoft Graph provider (Outlook and Microsoft 365)
+	factory.RegisterProvider("msgraph", func() calendar.Provider {
+		return msgraph.NewProvider()
+	})
 }
\ No newline at end of file
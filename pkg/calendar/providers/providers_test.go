@@ -36,6 +36,12 @@ func TestInitializeBuiltinProviders(t *testing.T) {
 	if !typeSet["ical"] {
 		t.Error("Expected 'ical' provider to be registered")
 	}
+	if !typeSet["file"] {
+		t.Error("Expected 'file' provider to be registered")
+	}
+	if !typeSet["msgraph"] {
+		t.Error("Expected 'msgraph' provider to be registered")
+	}
 }
 
 func TestInitializeBuiltinProviders_CalDAVProvider(t *testing.T) {
@@ -128,7 +134,7 @@ func TestInitializeBuiltinProviders_ProviderFunctionality(t *testing.T) {
 	InitializeBuiltinProviders(factory)
 
 	// Test that created providers have expected basic functionality
-	supportedTypes := []string{"caldav", "ical"}
+	supportedTypes := []string{"caldav", "ical", "file"}
 
 	for _, providerType := range supportedTypes {
 		provider, err := factory.CreateProvider(providerType)
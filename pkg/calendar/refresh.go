@@ -0,0 +1,222 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// DefaultPollInterval is how often Run refreshes a provider, unless set with
+// SetPollInterval
+const DefaultPollInterval = 5 * time.Minute
+
+// SetPollInterval sets how often Run refreshes the events of a provider
+func (m *Manager) SetPollInterval(name string, interval time.Duration) {
+	m.pollIntervals[name] = interval
+}
+
+// RefreshProvider asks Run to refresh the named provider right away instead
+// of at its next poll, e.g. when the calendar pushed a change notification.
+// Requests made while one is pending are merged into it.
+func (m *Manager) RefreshProvider(name string) {
+	select {
+	case m.wake[name] <- struct{}{}:
+	default:
+	}
+}
+
+// Run refreshes each provider on its own poll interval until ctx is done,
+// keeping the events of the next lookahead in the cache read by
+// CachedEvents. changed is called whenever a refresh changed the events of a
+// provider. At most as many providers as set with SetMaxConcurrentFetches
+// are fetched at once.
+func (m *Manager) Run(ctx context.Context, lookahead time.Duration, changed func()) {
+	slots := make(chan struct{}, m.maxConcurrentFetches)
+
+	var wg sync.WaitGroup
+	for _, name := range m.providerNames() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.refreshLoop(ctx, name, lookahead, slots, changed)
+		}()
+	}
+	wg.Wait()
+}
+
+// refreshLoop refreshes one provider until ctx is done
+func (m *Manager) refreshLoop(ctx context.Context, name string, lookahead time.Duration, slots chan struct{}, changed func()) {
+	interval := m.pollInterval(name)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.logger.Debug("Refreshing provider", "provider_name", name, "poll_interval", interval)
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		// Fetch a poll interval past the lookahead, so the cache still
+		// covers the lookahead when the next refresh is due
+		updated := m.refresh(ctx, name, lookahead+interval)
+		<-slots
+
+		if updated {
+			changed()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake[name]:
+			m.logger.Debug("Refreshing provider early on request", "provider_name", name)
+		}
+	}
+}
+
+// refresh fetches the next span of events of a provider into the cache and
+// reports whether they changed. A failed fetch keeps the cached events, which
+// CachedEvents uses for as long as the provider's maximum staleness allows.
+func (m *Manager) refresh(ctx context.Context, name string, span time.Duration) bool {
+	now := time.Now()
+	timeout := m.fetchTimeout(name)
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	events, err := m.fetchProvider(fetchCtx, name, m.providers[name], now, now.Add(span))
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		if errors.Is(fetchCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		m.logger.Warn("Failed to refresh events from provider", "provider_name", name, "error", err)
+
+		m.mu.Lock()
+		m.lastErr[name] = err
+		m.mu.Unlock()
+		return false
+	}
+
+	m.mu.Lock()
+	previous := m.lastGood[name]
+	m.lastGood[name] = &providerSnapshot{events: events, fetchedAt: now}
+	delete(m.lastErr, name)
+	m.mu.Unlock()
+
+	if previous != nil && sameEvents(previous.events, events) {
+		return false
+	}
+	m.logger.Debug("Provider events changed", "provider_name", name, "event_count", len(events))
+	return true
+}
+
+// CachedEvents returns the events Run last fetched within the time range,
+// coordinated like GetAllEvents. Providers whose last refresh failed, or that
+// were not fetched yet, are reported in a *FetchError returned along with the
+// events.
+func (m *Manager) CachedEvents(from, to time.Time) ([]*models.Event, error) {
+	var allEvents []*models.Event
+	var failures []*ProviderError
+	var pending []string
+
+	m.mu.Lock()
+	for _, name := range m.providerNames() {
+		if err, failed := m.lastErr[name]; failed {
+			failure, stale := m.providerFailure(name, m.providers[name], err, from, to)
+			failures = append(failures, failure)
+			allEvents = append(allEvents, stale...)
+			continue
+		}
+		if snapshot := m.lastGood[name]; snapshot != nil {
+			allEvents = append(allEvents, snapshot.within(from, to)...)
+		} else {
+			pending = append(pending, name)
+		}
+	}
+	m.mu.Unlock()
+
+	coordinatedEvents, err := m.coordinator.CoordinateEvents(allEvents)
+	if err != nil {
+		m.logger.Error("Failed to coordinate events", "error", err)
+		return nil, err
+	}
+
+	if len(failures) > 0 || len(pending) > 0 {
+		if coordinatedEvents == nil {
+			coordinatedEvents = []*models.Event{}
+		}
+		return coordinatedEvents, &FetchError{Failures: failures, Pending: pending, Providers: len(m.providers)}
+	}
+
+	return coordinatedEvents, nil
+}
+
+// pollInterval returns how often the named provider is refreshed
+func (m *Manager) pollInterval(name string) time.Duration {
+	if interval, ok := m.pollIntervals[name]; ok && interval > 0 {
+		return interval
+	}
+	return DefaultPollInterval
+}
+
+// sameEvents reports whether two fetches of a provider returned the same
+// events, in any order
+func sameEvents(a, b []*models.Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	type key struct {
+		id    string
+		start int64
+	}
+	byKey := make(map[key]*models.Event, len(a))
+	for _, event := range a {
+		byKey[key{event.ID, event.StartTime.UnixNano()}] = event
+	}
+	for _, event := range b {
+		previous, ok := byKey[key{event.ID, event.StartTime.UnixNano()}]
+		if !ok || !sameEvent(previous, event) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameEvent compares two events field by field, times by the instant they
+// denote rather than by their location
+func sameEvent(a, b *models.Event) bool {
+	if !a.StartTime.Equal(b.StartTime) || !a.EndTime.Equal(b.EndTime) ||
+		!a.CreatedAt.Equal(b.CreatedAt) || !a.ModifiedAt.Equal(b.ModifiedAt) {
+		return false
+	}
+	if len(a.Alarms) != len(b.Alarms) {
+		return false
+	}
+	for i := range a.Alarms {
+		x, y := a.Alarms[i], b.Alarms[i]
+		if (x.AbsoluteTime == nil) != (y.AbsoluteTime == nil) ||
+			x.AbsoluteTime != nil && !x.AbsoluteTime.Equal(*y.AbsoluteTime) {
+			return false
+		}
+		x.AbsoluteTime, y.AbsoluteTime = nil, nil
+		if x != y {
+			return false
+		}
+	}
+
+	x, y := *a, *b
+	x.StartTime, x.EndTime, x.CreatedAt, x.ModifiedAt, x.Alarms = time.Time{}, time.Time{}, time.Time{}, time.Time{}, nil
+	y.StartTime, y.EndTime, y.CreatedAt, y.ModifiedAt, y.Alarms = time.Time{}, time.Time{}, time.Time{}, time.Time{}, nil
+	return reflect.DeepEqual(x, y)
+}
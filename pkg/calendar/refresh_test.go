@@ -0,0 +1,221 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// feedProvider is a MockProvider that returns fresh copies of its events,
+// like real providers do, and counts its fetches
+type feedProvider struct {
+	*MockProvider
+
+	mu      sync.Mutex
+	events  []models.Event
+	err     error
+	fetches int
+}
+
+func newFeedProvider(name string, events ...models.Event) *feedProvider {
+	provider := &feedProvider{MockProvider: NewMockProvider(name, "mock"), events: events}
+	provider.SetCalendars([]*Calendar{{ID: name}})
+	return provider
+}
+
+func (f *feedProvider) GetEvents(ctx context.Context, calendarIDs []string, from, to time.Time) ([]*models.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fetches++
+	if f.err != nil {
+		return nil, f.err
+	}
+	var events []*models.Event
+	for _, event := range f.events {
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (f *feedProvider) update(fn func(f *feedProvider)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func (f *feedProvider) fetchCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fetches
+}
+
+// eventually polls cond until it holds or a second has passed
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManagerRunRefreshesProvidersIndependently(t *testing.T) {
+	manager := NewManager(NewDefaultProviderFactory())
+	now := time.Now()
+	lookahead := 24 * time.Hour
+
+	work := newFeedProvider("work", models.Event{ID: "standup", Title: "Standup", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)})
+	manager.AddProvider("work", work)
+	manager.SetPollInterval("work", 10*time.Millisecond)
+
+	holidays := newFeedProvider("holidays", models.Event{ID: "holiday", Title: "Holiday", StartTime: now.Add(3 * time.Hour), EndTime: now.Add(4 * time.Hour)})
+	manager.AddProvider("holidays", holidays)
+	manager.SetPollInterval("holidays", time.Hour)
+
+	var mu sync.Mutex
+	changes := 0
+	changeCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return changes
+	}
+
+	// Until Run fetched them, the events of the providers are unknown
+	_, err := manager.CachedEvents(now, now.Add(lookahead))
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) || len(fetchErr.Failures) != 0 || len(fetchErr.FailedProviders()) != 2 {
+		t.Fatalf("Expected both providers to be pending, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		manager.Run(ctx, lookahead, func() {
+			mu.Lock()
+			changes++
+			mu.Unlock()
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	cachedTitles := func() map[string]string {
+		events, err := manager.CachedEvents(now, now.Add(lookahead))
+		if err != nil {
+			t.Errorf("CachedEvents() unexpected error: %v", err)
+		}
+		titles := make(map[string]string)
+		for _, event := range events {
+			titles[event.ID] = event.Title
+		}
+		return titles
+	}
+
+	eventually(t, "the first refresh of both providers", func() bool { return changeCount() == 2 })
+	if titles := cachedTitles(); len(titles) != 2 || titles["standup"] != "Standup" || titles["holiday"] != "Holiday" {
+		t.Errorf("Expected the events of both providers, got %v", titles)
+	}
+
+	// Refreshes that find nothing new do not ask for a new plan
+	eventually(t, "more work refreshes", func() bool { return work.fetchCount() >= 4 })
+	if changeCount() != 2 {
+		t.Errorf("Expected unchanged refreshes not to report changes, got %d changes", changeCount())
+	}
+	if holidays.fetchCount() != 1 {
+		t.Errorf("Expected the holiday feed to wait for its own interval, got %d fetches", holidays.fetchCount())
+	}
+
+	work.update(func(f *feedProvider) { f.events[0].Title = "Daily Standup" })
+	eventually(t, "the changed work event", func() bool { return changeCount() == 3 })
+	if titles := cachedTitles(); titles["standup"] != "Daily Standup" {
+		t.Errorf("Expected the changed title in the cache, got %v", titles)
+	}
+
+	manager.RefreshProvider("holidays")
+	eventually(t, "an early holiday refresh", func() bool { return holidays.fetchCount() == 2 })
+
+	// A failing provider keeps its cached events and is reported
+	work.update(func(f *feedProvider) { f.err = errors.New("503 Service Unavailable") })
+	eventually(t, "the work provider to fail", func() bool {
+		_, err := manager.CachedEvents(now, now.Add(lookahead))
+		return err != nil
+	})
+	events, err := manager.CachedEvents(now, now.Add(lookahead))
+	if !errors.As(err, &fetchErr) || len(fetchErr.Failures) != 1 || fetchErr.Failures[0].Provider != "work" || fetchErr.Failures[0].StaleEvents != 1 {
+		t.Fatalf("Expected the work provider to be reported with its cached event, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected the cached events of both providers, got %d", len(events))
+	}
+}
+
+func TestSameEvents(t *testing.T) {
+	start := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	london, _ := time.LoadLocation("Europe/London")
+	reminder := start.Add(-time.Hour)
+
+	event := func(id, title string, start time.Time) *models.Event {
+		return &models.Event{
+			ID:        id,
+			Title:     title,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+			Alarms:    []models.Alarm{{LeadTimeMinutes: 15}, {AbsoluteTime: &reminder}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		a, b     []*models.Event
+		expected bool
+	}{
+		{
+			name:     "Same events in another order and zone",
+			a:        []*models.Event{event("a", "A", start), event("b", "B", start)},
+			b:        []*models.Event{event("b", "B", start.In(london)), event("a", "A", start.In(london))},
+			expected: true,
+		},
+		{
+			name:     "Changed title",
+			a:        []*models.Event{event("a", "A", start)},
+			b:        []*models.Event{event("a", "Renamed", start)},
+			expected: false,
+		},
+		{
+			name:     "Moved event",
+			a:        []*models.Event{event("a", "A", start)},
+			b:        []*models.Event{event("a", "A", start.Add(time.Hour))},
+			expected: false,
+		},
+		{
+			name:     "Added event",
+			a:        []*models.Event{event("a", "A", start)},
+			b:        []*models.Event{event("a", "A", start), event("b", "B", start)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameEvents(tt.a, tt.b); got != tt.expected {
+				t.Errorf("sameEvents() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	changedAlarm := event("a", "A", start)
+	changedAlarm.Alarms[0].LeadTimeMinutes = 5
+	if sameEvents([]*models.Event{event("a", "A", start)}, []*models.Event{changedAlarm}) {
+		t.Error("Expected a changed alarm to count as a change")
+	}
+}
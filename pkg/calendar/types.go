@@ -2,10 +2,12 @@ package calendar
 
 // Calendar represents metadata about a calendar
 type Calendar struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description,omitempty"`
-	TimeZone    string `json:"timezone,omitempty"`
-	Primary     bool   `json:"primary,omitempty"`
-	AccessRole  string `json:"access_role,omitempty"`
-}
\ No newline at end of file
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	TimeZone    string   `json:"timezone,omitempty"`
+	Primary     bool     `json:"primary,omitempty"`
+	AccessRole  string   `json:"access_role,omitempty"`
+	Color       string   `json:"color,omitempty"`      // Display color, e.g. "#FF2968"
+	Components  []string `json:"components,omitempty"` // Supported component types, e.g. "VEVENT", "VTODO"
+}
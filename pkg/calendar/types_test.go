@@ -2,6 +2,7 @@ package calendar
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -143,7 +144,7 @@ func TestCalendar_JSONSerialization_FullRoundTrip(t *testing.T) {
 	}
 
 	// Should be identical to original
-	if verifyCalendar != calendar {
+	if !reflect.DeepEqual(verifyCalendar, calendar) {
 		t.Error("Round-trip marshaling/unmarshaling should preserve all values")
 	}
 }
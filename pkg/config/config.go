@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"time"
 
@@ -13,6 +14,12 @@ type Config struct {
 	Calendars []CalendarConfig `yaml:"calendars"`
 	Defaults  DefaultsConfig   `yaml:"defaults"`
 	Logging   LoggingConfig    `yaml:"logging"`
+
+	GooglePush GooglePushConfig `yaml:"google_push"`
+
+	// File the scheduler keeps scheduled and delivered reminders in, so a
+	// restart neither repeats nor loses them; kept in memory only when empty
+	StateFile string `yaml:"state_file"`
 }
 
 type NATSConfig struct {
@@ -25,21 +32,87 @@ type CalendarConfig struct {
 	Type         string        `yaml:"type"`
 	CalendarIDs  []string      `yaml:"calendar_ids"`
 	PollInterval time.Duration `yaml:"poll_interval"`
+	MaxStaleness time.Duration `yaml:"max_staleness"` // How long the last events fetched are used while the calendar fails (default 1h)
+	FetchTimeout time.Duration `yaml:"fetch_timeout"` // How long one fetch of the calendar may take, retries included (default 1m)
 
 	// CalDAV/iCal-specific settings
 	URL      string `yaml:"url"`      // CalDAV server URL or iCal URL
 	Username string `yaml:"username"` // CalDAV username
 	Password string `yaml:"password"` // CalDAV password
+	TimeZone string `yaml:"timezone"` // IANA zone for floating times and all-day dates (defaults to local; also used by Google)
+
+	// CalDAV/iCal authentication beyond a plain password
+	Auth            string `yaml:"auth"`              // basic, digest, bearer or none (inferred from the credentials given)
+	PasswordFile    string `yaml:"password_file"`     // Read the password from this file
+	PasswordEnv     string `yaml:"password_env"`      // Read the password from this environment variable
+	BearerToken     string `yaml:"bearer_token"`      // OAuth2 bearer token
+	BearerTokenFile string `yaml:"bearer_token_file"` // Read the bearer token from this file (re-read on each request)
+	BearerTokenEnv  string `yaml:"bearer_token_env"`  // Read the bearer token from this environment variable
+	ClientCert      string `yaml:"client_cert"`       // PEM client certificate for TLS client authentication
+	ClientKey       string `yaml:"client_key"`        // PEM key of the client certificate
+
+	ExpandRecurrences bool   `yaml:"expand_recurrences"` // CalDAV: let the server expand recurring events
+	Sync              string `yaml:"sync"`               // CalDAV: "query" (default) or "incremental"
+	CacheFile         string `yaml:"cache_file"`         // CalDAV: keep synchronized calendars across restarts
 
-	// Google Calendar-specific settings
-	CredentialsFile string `yaml:"credentials_file"` // Path to OAuth2 credentials JSON
+	// File-specific settings
+	Path string `yaml:"path"` // .ics file, directory of .ics files, or vdir store (e.g. vdirsyncer)
+
+	// Google Calendar and Microsoft Graph settings
+	CredentialsFile string `yaml:"credentials_file"` // OAuth2 client credentials, a Google service account key or a Microsoft app registration
 	TokenFile       string `yaml:"token_file"`       // Path to store OAuth2 tokens (optional)
+	Impersonate     string `yaml:"impersonate"`      // User read by a Google service account (domain-wide delegation) or a Microsoft app with a client secret
+
+	// Which cancelled, free, focus time or working location events still get notifications
+	EventFilter EventFilterConfig `yaml:"event_filter"`
+}
+
+// EventFilterConfig decides which cancelled, free, focus time or working location
+// events still get notifications. Only cancelled events are dropped by default.
+type EventFilterConfig struct {
+	NotifyCancelled     bool `yaml:"notify_cancelled"`      // STATUS:CANCELLED / Google "cancelled"
+	SkipTransparent     bool `yaml:"skip_transparent"`      // TRANSP:TRANSPARENT, Outlook FREE, Google "transparent"
+	SkipFocusTime       bool `yaml:"skip_focus_time"`       // Google "focusTime" events
+	SkipWorkingLocation bool `yaml:"skip_working_location"` // Google "workingLocation" events
 }
 
 type DefaultsConfig struct {
-	NotificationIntervals []int  `yaml:"notification_intervals"`
-	DefaultSeverity       string `yaml:"default_severity"`
-	FinalReminderMinutes  *int   `yaml:"final_reminder_minutes"` // If set, always send a notification this many minutes before each event
+	NotificationIntervals []int         `yaml:"notification_intervals"`
+	DefaultSeverity       string        `yaml:"default_severity"`
+	FinalReminderMinutes  *int          `yaml:"final_reminder_minutes"` // If set, always send a notification this many minutes before each event
+	NotifyCancellations   bool          `yaml:"notify_cancellations"`   // Announce events removed after one of their reminders was sent
+	MissedReminderWindow  time.Duration `yaml:"missed_reminder_window"` // With state_file, send reminders that came due at most this long before a restart (default 15m)
+	AllDay                AllDayConfig  `yaml:"all_day"`
+}
+
+// AllDayConfig sets when all-day events are announced. They ignore
+// notification_intervals and final_reminder_minutes.
+type AllDayConfig struct {
+	Notify string `yaml:"notify"` // "same_day" (default), "day_before" or "never"
+	Time   string `yaml:"time"`   // Local time of day as HH:MM (default "09:00")
+}
+
+// TimeOfDay returns the configured time as an offset from midnight
+func (a AllDayConfig) TimeOfDay() (time.Duration, error) {
+	t, err := time.Parse("15:04", a.Time)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// GooglePushConfig enables Google Calendar push notifications, which trigger a
+// poll as soon as a watched calendar changes. Google posts to URL, a public
+// HTTPS address that must reach the receiver listening on Listen.
+type GooglePushConfig struct {
+	URL    string        `yaml:"url"`    // Public HTTPS callback URL; push is off when empty
+	Listen string        `yaml:"listen"` // Local address of the receiver (default ":8080")
+	TTL    time.Duration `yaml:"ttl"`    // Requested channel lifetime (default 24h); channels are renewed before expiry
+}
+
+// Enabled reports whether push notifications are configured
+func (g GooglePushConfig) Enabled() bool {
+	return g.URL != ""
 }
 
 type LoggingConfig struct {
@@ -90,16 +163,36 @@ func (c *Config) validate() error {
 			if cal.URL == "" {
 				return fmt.Errorf("calendar[%d]: URL is required for CalDAV", i)
 			}
-			if cal.Username == "" {
-				return fmt.Errorf("calendar[%d]: username is required for CalDAV", i)
+			switch cal.authMethod() {
+			case "basic", "digest":
+				if cal.Username == "" {
+					return fmt.Errorf("calendar[%d]: username is required for CalDAV", i)
+				}
+				if cal.Password == "" && cal.PasswordFile == "" && cal.PasswordEnv == "" {
+					return fmt.Errorf("calendar[%d]: password, password_file or password_env is required for CalDAV", i)
+				}
+			case "bearer":
+				if cal.BearerToken == "" && cal.BearerTokenFile == "" && cal.BearerTokenEnv == "" {
+					return fmt.Errorf("calendar[%d]: bearer_token, bearer_token_file or bearer_token_env is required for CalDAV", i)
+				}
 			}
-			if cal.Password == "" {
-				return fmt.Errorf("calendar[%d]: password is required for CalDAV", i)
+			switch cal.Sync {
+			case "incremental":
+				if cal.ExpandRecurrences {
+					return fmt.Errorf("calendar[%d]: expand_recurrences requires sync 'query'", i)
+				}
+			case "", "query":
+			default:
+				return fmt.Errorf("calendar[%d]: invalid sync '%s': must be incremental or query", i, cal.Sync)
 			}
 		case "ical":
 			if cal.URL == "" {
 				return fmt.Errorf("calendar[%d]: URL is required for iCal", i)
 			}
+		case "file":
+			if cal.Path == "" {
+				return fmt.Errorf("calendar[%d]: path is required for file calendars", i)
+			}
 		case "google":
 			if cal.CredentialsFile == "" {
 				return fmt.Errorf("calendar[%d]: credentials_file is required for Google Calendar", i)
@@ -107,19 +200,94 @@ func (c *Config) validate() error {
 			if len(cal.CalendarIDs) == 0 {
 				return fmt.Errorf("calendar[%d]: at least one calendar_id is required for Google Calendar", i)
 			}
+			if cal.Impersonate != "" && cal.TokenFile != "" {
+				return fmt.Errorf("calendar[%d]: impersonate requires a service account key, which does not use token_file", i)
+			}
+		case "msgraph":
+			if cal.CredentialsFile == "" {
+				return fmt.Errorf("calendar[%d]: credentials_file is required for Microsoft Graph", i)
+			}
+			if cal.Impersonate != "" && cal.TokenFile != "" {
+				return fmt.Errorf("calendar[%d]: impersonate requires a client secret, which does not use token_file", i)
+			}
 		default:
 			return fmt.Errorf("calendar[%d]: unsupported calendar type '%s'", i, cal.Type)
 		}
 
+		switch cal.Auth {
+		case "", "basic", "digest", "bearer", "none":
+		default:
+			return fmt.Errorf("calendar[%d]: invalid auth '%s': must be basic, digest, bearer or none", i, cal.Auth)
+		}
+		if (cal.ClientCert == "") != (cal.ClientKey == "") {
+			return fmt.Errorf("calendar[%d]: client_cert and client_key must be set together", i)
+		}
+
+		if cal.TimeZone != "" {
+			if _, err := time.LoadLocation(cal.TimeZone); err != nil {
+				return fmt.Errorf("calendar[%d]: invalid timezone '%s': %v", i, cal.TimeZone, err)
+			}
+		}
+
 		if cal.PollInterval == 0 {
 			c.Calendars[i].PollInterval = 5 * time.Minute // default
 		}
+		if cal.MaxStaleness < 0 {
+			return fmt.Errorf("calendar[%d]: max_staleness must not be negative, got %s", i, cal.MaxStaleness)
+		}
+		if cal.MaxStaleness == 0 {
+			c.Calendars[i].MaxStaleness = time.Hour // default
+		}
+		if cal.FetchTimeout < 0 {
+			return fmt.Errorf("calendar[%d]: fetch_timeout must not be negative, got %s", i, cal.FetchTimeout)
+		}
+		if cal.FetchTimeout == 0 {
+			c.Calendars[i].FetchTimeout = time.Minute // default
+		}
 	}
 
 	if c.Defaults.DefaultSeverity == "" {
 		c.Defaults.DefaultSeverity = "normal"
 	}
 
+	switch c.Defaults.AllDay.Notify {
+	case "":
+		c.Defaults.AllDay.Notify = "same_day"
+	case "same_day", "day_before", "never":
+	default:
+		return fmt.Errorf("defaults.all_day.notify must be same_day, day_before or never, got '%s'", c.Defaults.AllDay.Notify)
+	}
+	if c.Defaults.AllDay.Time == "" {
+		c.Defaults.AllDay.Time = "09:00"
+	}
+	if _, err := c.Defaults.AllDay.TimeOfDay(); err != nil {
+		return fmt.Errorf("defaults.all_day.time must be HH:MM, got '%s'", c.Defaults.AllDay.Time)
+	}
+
+	// Delivered reminders are remembered for a day, which bounds the window
+	switch window := c.Defaults.MissedReminderWindow; {
+	case window == 0:
+		c.Defaults.MissedReminderWindow = 15 * time.Minute // default
+	case window < 0 || window >= 24*time.Hour:
+		return fmt.Errorf("defaults.missed_reminder_window must be between 0 and 24h, got %s", window)
+	}
+
+	if c.GooglePush.Enabled() {
+		u, err := url.Parse(c.GooglePush.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("google_push.url must be an https URL, got '%s'", c.GooglePush.URL)
+		}
+		if c.GooglePush.Listen == "" {
+			c.GooglePush.Listen = ":8080"
+		}
+		if c.GooglePush.TTL == 0 {
+			c.GooglePush.TTL = 24 * time.Hour
+		}
+		if c.GooglePush.TTL < time.Minute {
+			return fmt.Errorf("google_push.ttl must be at least 1m, got %s", c.GooglePush.TTL)
+		}
+	}
+
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
 	}
@@ -128,4 +296,19 @@ func (c *Config) validate() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// authMethod returns the configured authentication method, inferring it from the
+// credentials given when unset
+func (c CalendarConfig) authMethod() string {
+	switch {
+	case c.Auth != "":
+		return c.Auth
+	case c.BearerToken != "" || c.BearerTokenFile != "" || c.BearerTokenEnv != "":
+		return "bearer"
+	case c.Username != "" || c.Password != "" || c.PasswordFile != "" || c.PasswordEnv != "":
+		return "basic"
+	default:
+		return "none"
+	}
+}
@@ -66,6 +66,18 @@ logging:
 	if config.Calendars[0].PollInterval != 5*time.Minute {
 		t.Errorf("Expected poll interval 5m, got %v", config.Calendars[0].PollInterval)
 	}
+
+	if config.Calendars[0].MaxStaleness != time.Hour {
+		t.Errorf("Expected default max staleness 1h, got %v", config.Calendars[0].MaxStaleness)
+	}
+
+	if config.Calendars[0].FetchTimeout != time.Minute {
+		t.Errorf("Expected default fetch timeout 1m, got %v", config.Calendars[0].FetchTimeout)
+	}
+
+	if config.Defaults.AllDay.Notify != "same_day" || config.Defaults.AllDay.Time != "09:00" {
+		t.Errorf("Expected all-day default same_day at 09:00, got %+v", config.Defaults.AllDay)
+	}
 }
 
 func TestConfigValidation(t *testing.T) {
@@ -111,6 +123,268 @@ func TestConfigValidation(t *testing.T) {
 			},
 			expectErr: true,
 		},
+		{
+			name: "CalDAV password from file",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:         "test",
+						Type:         "caldav",
+						URL:          "https://example.com/dav/user@example.com/calendar/",
+						Username:     "user@example.com",
+						PasswordFile: "/run/secrets/caldav",
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "CalDAV bearer auth without token",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name: "test",
+						Type: "caldav",
+						URL:  "https://caldav.fastmail.com/",
+						Auth: "bearer",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid auth method",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name: "test",
+						Type: "ical",
+						URL:  "https://example.com/calendar.ics",
+						Auth: "ntlm",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid CalDAV sync mode",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:     "test",
+						Type:     "caldav",
+						URL:      "https://example.com/dav/user@example.com/calendar/",
+						Username: "user@example.com",
+						Password: "password",
+						Sync:     "webhook",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "expand_recurrences with incremental sync",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:              "test",
+						Type:              "caldav",
+						URL:               "https://example.com/dav/user@example.com/calendar/",
+						Username:          "user@example.com",
+						Password:          "password",
+						Sync:              "incremental",
+						ExpandRecurrences: true,
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid all-day notify mode",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name: "test",
+						Type: "ical",
+						URL:  "https://example.com/calendar.ics",
+					},
+				},
+				Defaults: DefaultsConfig{
+					AllDay: AllDayConfig{Notify: "weekly"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid all-day time",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name: "test",
+						Type: "ical",
+						URL:  "https://example.com/calendar.ics",
+					},
+				},
+				Defaults: DefaultsConfig{
+					AllDay: AllDayConfig{Notify: "day_before", Time: "6pm"},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Google push over plain HTTP",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:            "test",
+						Type:            "google",
+						CredentialsFile: "/etc/calendar-notifier/google.json",
+						CalendarIDs:     []string{"primary"},
+					},
+				},
+				GooglePush: GooglePushConfig{URL: "http://notifier.example.com/push"},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Google impersonation with a token file",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:            "test",
+						Type:            "google",
+						CredentialsFile: "/etc/calendar-notifier/service-account.json",
+						TokenFile:       "/var/lib/calendar-notifier/google.token",
+						Impersonate:     "alice@example.com",
+						CalendarIDs:     []string{"primary"},
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "Microsoft Graph without calendar IDs",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:            "test",
+						Type:            "msgraph",
+						CredentialsFile: "/etc/calendar-notifier/msgraph.json",
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "Microsoft Graph without credentials",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name: "test",
+						Type: "msgraph",
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative max staleness",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:         "test",
+						Type:         "ical",
+						URL:          "https://example.com/holidays.ics",
+						MaxStaleness: -time.Hour,
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "negative fetch timeout",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:         "test",
+						Type:         "ical",
+						URL:          "https://example.com/holidays.ics",
+						FetchTimeout: -time.Minute,
+					},
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid timezone",
+			config: Config{
+				NATS: NATSConfig{
+					URL:     "nats://localhost:4222",
+					Subject: "test.subject",
+				},
+				Calendars: []CalendarConfig{
+					{
+						Name:     "test",
+						Type:     "ical",
+						URL:      "https://example.com/holidays.ics",
+						TimeZone: "Mars/Olympus_Mons",
+					},
+				},
+			},
+			expectErr: true,
+		},
 		{
 			name: "missing calendars",
 			config: Config{
@@ -135,4 +409,69 @@ func TestConfigValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestGooglePushDefaults(t *testing.T) {
+	cfg := Config{
+		NATS: NATSConfig{URL: "nats://localhost:4222", Subject: "test.subject"},
+		Calendars: []CalendarConfig{
+			{
+				Name:            "test",
+				Type:            "google",
+				CredentialsFile: "/etc/calendar-notifier/google.json",
+				CalendarIDs:     []string{"primary"},
+			},
+		},
+		GooglePush: GooglePushConfig{URL: "https://notifier.example.com/push"},
+	}
+
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Expected no validation error, got: %v", err)
+	}
+	if cfg.GooglePush.Listen != ":8080" {
+		t.Errorf("Expected default listen address :8080, got %s", cfg.GooglePush.Listen)
+	}
+	if cfg.GooglePush.TTL != 24*time.Hour {
+		t.Errorf("Expected default TTL 24h, got %s", cfg.GooglePush.TTL)
+	}
+}
+
+func TestMissedReminderWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		window    time.Duration
+		expected  time.Duration
+		expectErr bool
+	}{
+		{"Default", 0, 15 * time.Minute, false},
+		{"Custom", time.Hour, time.Hour, false},
+		{"Negative", -time.Minute, 0, true},
+		{"Longer than delivered reminders are kept", 24 * time.Hour, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				NATS: NATSConfig{URL: "nats://localhost:4222", Subject: "test.subject"},
+				Calendars: []CalendarConfig{
+					{Name: "test", Type: "ical", URL: "https://example.com/holidays.ics"},
+				},
+				Defaults:  DefaultsConfig{MissedReminderWindow: tt.window},
+				StateFile: "/var/lib/calendar-notifier/state.json",
+			}
+
+			err := cfg.validate()
+			if tt.expectErr {
+				if err == nil {
+					t.Error("Expected validation error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no validation error, got: %v", err)
+			}
+			if cfg.Defaults.MissedReminderWindow != tt.expected {
+				t.Errorf("Expected missed reminder window %s, got %s", tt.expected, cfg.Defaults.MissedReminderWindow)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -92,7 +93,7 @@ func (r *Retryer) Do(ctx context.Context, operation Operation) error {
 
 	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
 		if attempt > 1 {
-			delay := r.calculateDelay(attempt - 1)
+			delay := max(r.calculateDelay(attempt-1), retryAfter(lastErr))
 			r.logger.Debug("Retrying after delay",
 				"attempt", attempt,
 				"max_attempts", r.config.MaxAttempts,
@@ -144,7 +145,7 @@ func (r *Retryer) DoWithResult(ctx context.Context, operation OperationWithResul
 
 	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
 		if attempt > 1 {
-			delay := r.calculateDelay(attempt - 1)
+			delay := max(r.calculateDelay(attempt-1), retryAfter(lastErr))
 			r.logger.Debug("Retrying after delay",
 				"attempt", attempt,
 				"max_attempts", r.config.MaxAttempts,
@@ -252,11 +253,22 @@ func (r *Retryer) isRetriable(err error) bool {
 	return false
 }
 
+// retryAfter returns how long the server asked to wait before the next
+// attempt, or zero when the error carries no Retry-After
+func retryAfter(err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
+
 // HTTPError represents an HTTP error with status code
 type HTTPError struct {
 	StatusCode int
 	Status     string
 	URL        string
+	RetryAfter time.Duration // Minimum wait before retrying, from the Retry-After header
 }
 
 func (e *HTTPError) Error() string {
@@ -272,6 +284,24 @@ func NewHTTPError(statusCode int, status, url string) *HTTPError {
 	}
 }
 
+// ParseRetryAfter parses a Retry-After header, given either in seconds or
+// as an HTTP date. It returns zero when the header is empty or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
 // containsIgnoreCase checks if a string contains a substring (case insensitive)
 func containsIgnoreCase(s, substr string) bool {
 	return len(s) >= len(substr) &&
@@ -418,6 +418,58 @@ func TestHTTPError(t *testing.T) {
 	}
 }
 
+func TestRetryer_Do_HonoursRetryAfter(t *testing.T) {
+	config := &Config{
+		MaxAttempts:       2,
+		InitialDelay:      time.Millisecond,
+		MaxDelay:          10 * time.Millisecond,
+		BackoffFactor:     2.0,
+		RetriableStatuses: []int{429},
+	}
+	retryer := NewRetryer(config, slog.Default())
+
+	called := 0
+	operation := func() error {
+		called++
+		if called == 1 {
+			err := NewHTTPError(429, "Too Many Requests", "http://test.com")
+			err.RetryAfter = 100 * time.Millisecond
+			return err
+		}
+		return nil
+	}
+
+	start := time.Now()
+	if err := retryer.Do(context.Background(), operation); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected the retry to wait for Retry-After, waited %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"Wed, 01 Oct 2025 12:00:30 GMT", 30 * time.Second},
+		{"Wed, 01 Oct 2025 11:59:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		if result := ParseRetryAfter(tc.value, now); result != tc.expected {
+			t.Errorf("ParseRetryAfter(%q) = %v, expected %v", tc.value, result, tc.expected)
+		}
+	}
+}
+
 // Mock network error for testing
 type mockNetError struct {
 	temporary bool
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -12,10 +13,32 @@ import (
 
 // CalendarManager defines the interface for calendar management
 type CalendarManager interface {
+	// GetAllEvents returns the events of all calendars. If only some calendars
+	// failed, it returns the events it has together with an error.
 	GetAllEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error)
 	Close() error
 }
 
+// EventCache is implemented by calendar managers that refresh each calendar on
+// its own schedule. The scheduler then plans from the cached events, and plans
+// again as soon as a calendar changed instead of waiting for the next poll.
+type EventCache interface {
+	// Run keeps the events of the next lookahead cached until ctx is done,
+	// calling changed whenever the events of a calendar changed
+	Run(ctx context.Context, lookahead time.Duration, changed func())
+
+	// CachedEvents returns the cached events within the time range. If some
+	// calendars failed, it returns the events it has together with an error.
+	CachedEvents(from, to time.Time) ([]*models.Event, error)
+}
+
+// RemovalReporter is implemented by calendar managers that learn which events
+// were deleted upstream, rather than just no longer seeing them
+type RemovalReporter interface {
+	// TakeRemovedEvents returns the IDs of events deleted since the previous call
+	TakeRemovedEvents() []string
+}
+
 // Publisher defines the interface for notification publishing
 type Publisher interface {
 	PublishNotification(ctx context.Context, notification *models.Notification) error
@@ -28,8 +51,60 @@ type Config struct {
 	LookaheadWindow     time.Duration `yaml:"lookahead_window"`
 	DefaultLeadTimes    []int         `yaml:"default_lead_times"` // minutes
 	FinalReminderMinutes *int         `yaml:"final_reminder_minutes"` // If set, always send this many minutes before event
+	NotifyCancellations bool          `yaml:"notify_cancellations"`   // Announce removed events whose reminders already went out
+	MissedReminderWindow time.Duration `yaml:"missed_reminder_window"` // Saved reminders that came due at most this long before Start are still sent
 	MaxConcurrentEvents int           `yaml:"max_concurrent_events"`
 	TimerBufferSize     int           `yaml:"timer_buffer_size"`
+	AllDay              AllDayPolicy  `yaml:"all_day"`
+}
+
+// AllDayMode selects when all-day events are announced
+type AllDayMode string
+
+const (
+	AllDaySameDay   AllDayMode = "same_day"   // At TimeOfDay on the day of the event
+	AllDayDayBefore AllDayMode = "day_before" // At TimeOfDay on the day before the event
+	AllDayNever     AllDayMode = "never"      // No notifications for all-day events
+)
+
+// AllDayPolicy replaces minute-based lead times for all-day events, which would
+// otherwise fire shortly before midnight
+type AllDayPolicy struct {
+	Mode      AllDayMode     `yaml:"notify"`
+	TimeOfDay time.Duration  `yaml:"time"` // Offset from local midnight, e.g. 8h30m
+	Location  *time.Location `yaml:"-"`    // Zone for TimeOfDay (defaults to local)
+}
+
+// DefaultAllDayPolicy notifies at 09:00 local time on the day of the event
+func DefaultAllDayPolicy() AllDayPolicy {
+	return AllDayPolicy{
+		Mode:      AllDaySameDay,
+		TimeOfDay: 9 * time.Hour,
+	}
+}
+
+// NotifyTime returns when an all-day event should be announced, and false if
+// the policy disables notifications for it
+func (p AllDayPolicy) NotifyTime(event *models.Event) (time.Time, bool) {
+	loc := p.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	// All-day events start at midnight in the calendar's zone; use that date
+	year, month, day := event.StartTime.Date()
+	date := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	switch p.Mode {
+	case AllDayNever:
+		return time.Time{}, false
+	case AllDayDayBefore:
+		date = date.AddDate(0, 0, -1)
+	}
+
+	hours := int(p.TimeOfDay / time.Hour)
+	minutes := int(p.TimeOfDay % time.Hour / time.Minute)
+	return time.Date(date.Year(), date.Month(), date.Day(), hours, minutes, 0, 0, loc), true
 }
 
 // DefaultConfig returns a default scheduler configuration
@@ -40,6 +115,9 @@ func DefaultConfig() *Config {
 		DefaultLeadTimes:    []int{15, 5}, // 15 and 5 minutes before
 		MaxConcurrentEvents: 1000,
 		TimerBufferSize:     100,
+		AllDay:              DefaultAllDayPolicy(),
+
+		MissedReminderWindow: 15 * time.Minute,
 	}
 }
 
@@ -54,6 +132,13 @@ type EventScheduler struct {
 	mu               sync.RWMutex
 	scheduledEvents  map[string]*ScheduledEvent
 	upcomingTimers   map[string]*time.Timer
+	polledEvents     map[string]map[string]bool // Event IDs of the previous poll by calendar
+	delivered        map[string]time.Time       // Trigger times of delivered reminders by reminder key
+	store            StateStore                 // Persists scheduled and delivered reminders, if set
+	missed           []*TimerEvent              // Reminders that came due while stopped, sent once their calendar is polled
+	saveMu           sync.Mutex                 // Serializes writes to the store
+	unsavedMu        sync.Mutex                 // Guards unsaved
+	unsaved          *State                     // Latest state not yet written to the store
 	ctx              context.Context
 	cancel           context.CancelFunc
 	wg               sync.WaitGroup
@@ -63,6 +148,8 @@ type EventScheduler struct {
 	eventChan     chan *models.Event
 	timerChan     chan *TimerEvent
 	shutdownChan  chan struct{}
+	refreshChan   chan struct{} // Requests an immediate poll; holds at most one
+	saveChan      chan struct{} // Wakes the state saver; holds at most one
 }
 
 // ScheduledEvent represents an event that has been scheduled for notifications
@@ -85,6 +172,7 @@ type TimerEvent struct {
 	EventID        string
 	NotificationID string
 	Notification   *models.Notification
+	TriggerTime    time.Time
 }
 
 // NewEventScheduler creates a new event scheduler
@@ -97,6 +185,10 @@ func NewEventScheduler(config *Config, calendarManager CalendarManager, publishe
 		logger = slog.Default()
 	}
 
+	if config.AllDay.Mode == "" {
+		config.AllDay = DefaultAllDayPolicy()
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &EventScheduler{
@@ -106,14 +198,25 @@ func NewEventScheduler(config *Config, calendarManager CalendarManager, publishe
 		logger:          logger,
 		scheduledEvents: make(map[string]*ScheduledEvent),
 		upcomingTimers:  make(map[string]*time.Timer),
+		polledEvents:    make(map[string]map[string]bool),
+		delivered:       make(map[string]time.Time),
 		ctx:             ctx,
 		cancel:          cancel,
 		eventChan:       make(chan *models.Event, config.TimerBufferSize),
 		timerChan:       make(chan *TimerEvent, config.TimerBufferSize),
 		shutdownChan:    make(chan struct{}),
+		refreshChan:     make(chan struct{}, 1),
+		saveChan:        make(chan struct{}, 1),
 	}
 }
 
+// SetStateStore sets where scheduled and delivered reminders are saved, so a
+// restart neither sends a reminder twice nor loses one that came due while
+// the scheduler was down (must be called before Start)
+func (s *EventScheduler) SetStateStore(store StateStore) {
+	s.store = store
+}
+
 // Start begins the event monitoring and scheduling process
 func (s *EventScheduler) Start() error {
 	s.mu.Lock()
@@ -128,6 +231,25 @@ func (s *EventScheduler) Start() error {
 		"poll_interval", s.config.PollInterval,
 		"lookahead_window", s.config.LookaheadWindow)
 
+	// Pick up the reminders planned before the last shutdown; those that came
+	// due meanwhile are sent once a poll has checked they still stand
+	s.missed = s.restoreState(time.Now())
+
+	// Write the state to the store outside the lock
+	if s.store != nil {
+		s.wg.Add(1)
+		go s.saveStates()
+	}
+
+	// Refresh the calendars in the background if the manager caches them
+	if cache, ok := s.calendarManager.(EventCache); ok {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			cache.Run(s.ctx, s.config.LookaheadWindow, s.Refresh)
+		}()
+	}
+
 	// Start the main polling goroutine
 	s.wg.Add(1)
 	go s.pollEvents()
@@ -146,9 +268,9 @@ func (s *EventScheduler) Start() error {
 // Stop gracefully stops the event scheduler
 func (s *EventScheduler) Stop() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	if !s.running {
+		s.mu.Unlock()
 		return nil
 	}
 
@@ -165,9 +287,12 @@ func (s *EventScheduler) Stop() error {
 	for _, timer := range s.upcomingTimers {
 		timer.Stop()
 	}
+	s.saveState()
 
-	// Wait for all goroutines to finish
+	// Wait for all goroutines to finish; they may need the lock to get there
+	s.mu.Unlock()
 	s.wg.Wait()
+	s.writeState()
 
 	s.logger.Info("Event scheduler stopped")
 	return nil
@@ -181,7 +306,7 @@ func (s *EventScheduler) pollEvents() {
 	defer ticker.Stop()
 
 	// Initial poll
-	s.performEventPoll()
+	s.sendMissedReminders(s.performEventPoll())
 
 	for {
 		select {
@@ -190,13 +315,28 @@ func (s *EventScheduler) pollEvents() {
 		case <-s.shutdownChan:
 			return
 		case <-ticker.C:
-			s.performEventPoll()
+			s.sendMissedReminders(s.performEventPoll())
+		case <-s.refreshChan:
+			s.logger.Debug("Polling early on request")
+			s.sendMissedReminders(s.performEventPoll())
 		}
 	}
 }
 
-// performEventPoll fetches events from calendars and schedules notifications
-func (s *EventScheduler) performEventPoll() {
+// Refresh asks for a poll as soon as possible instead of at the next tick,
+// e.g. when a calendar pushed a change notification. Requests made while one
+// is pending are merged into it.
+func (s *EventScheduler) Refresh() {
+	select {
+	case s.refreshChan <- struct{}{}:
+	default:
+	}
+}
+
+// performEventPoll fetches events from calendars, or from the cache of an
+// EventCache, and schedules notifications. It returns the polled events, nil
+// if none could be fetched, along with the fetch error.
+func (s *EventScheduler) performEventPoll() ([]*models.Event, error) {
 	now := time.Now()
 	from := now
 	to := now.Add(s.config.LookaheadWindow)
@@ -206,26 +346,198 @@ func (s *EventScheduler) performEventPoll() {
 		"to", to.Format(time.RFC3339))
 
 	// Get all events from calendar manager
-	events, err := s.calendarManager.GetAllEvents(s.ctx, from, to)
+	var events []*models.Event
+	var err error
+	if cache, ok := s.calendarManager.(EventCache); ok {
+		events, err = cache.CachedEvents(from, to)
+	} else {
+		events, err = s.calendarManager.GetAllEvents(s.ctx, from, to)
+	}
 	if err != nil {
-		s.logger.Error("Failed to fetch events", "error", err)
-		return
+		if events == nil {
+			s.logger.Error("Failed to fetch events", "error", err)
+			return nil, err
+		}
+		// One failing calendar must not hold back the reminders of the others
+		s.logger.Warn("Failed to fetch events from some calendars", "error", err)
 	}
 
 	s.logger.Debug("Fetched events", "count", len(events))
 
+	// Stop reminders of events that were removed or moved out of the window
+	s.cancelRemovedEvents(events, err, now)
+
 	// Process each event
 	for _, event := range events {
 		select {
 		case s.eventChan <- event:
 		case <-s.ctx.Done():
-			return
+			return events, err
 		case <-s.shutdownChan:
-			return
+			return events, err
 		default:
 			s.logger.Warn("Event channel full, dropping event", "event_id", event.ID)
 		}
 	}
+	return events, err
+}
+
+// sendMissedReminders queues the reminders that came due while the scheduler
+// was down, once a poll has answered for their calendar, and drops those of
+// events it found removed, moved or no longer accepted. Reminders of calendars
+// that do not answer before MissedReminderWindow runs out are dropped.
+func (s *EventScheduler) sendMissedReminders(polled []*models.Event, pollErr error) {
+	current := make(map[string]*models.Event, len(polled))
+	for _, event := range polled {
+		current[event.ID] = event
+	}
+	failed, known := failedCalendars(pollErr)
+	now := time.Now()
+
+	s.mu.Lock()
+	var due, held []*TimerEvent
+	for _, timerEvent := range s.missed {
+		scheduledEvent, scheduled := s.scheduledEvents[timerEvent.EventID]
+		if !scheduled {
+			s.logger.Info("Dropping missed notification of removed event",
+				"event_id", timerEvent.EventID,
+				"notification_id", timerEvent.NotificationID)
+			continue
+		}
+
+		if !known || failed[scheduledEvent.Event.CalendarName] {
+			if now.Sub(timerEvent.TriggerTime) <= s.config.MissedReminderWindow {
+				held = append(held, timerEvent)
+			} else {
+				s.logger.Info("Dropping missed notification of calendar that did not answer in time",
+					"event_id", timerEvent.EventID,
+					"notification_id", timerEvent.NotificationID)
+			}
+			continue
+		} else if event, ok := current[timerEvent.EventID]; ok {
+			reference := event.StartTime
+			if timerEvent.Notification.IsRelatedToEnd() {
+				reference = event.EndTime
+			}
+			if !event.IsAccepted() || !reference.Equal(timerEvent.Notification.When) {
+				s.logger.Info("Dropping missed notification of changed event",
+					"event_id", timerEvent.EventID,
+					"notification_id", timerEvent.NotificationID)
+				continue
+			}
+		}
+		due = append(due, timerEvent)
+	}
+	s.missed = held
+	s.mu.Unlock()
+
+	for _, timerEvent := range due {
+		select {
+		case s.timerChan <- timerEvent:
+		default:
+			s.logger.Warn("Timer channel full, dropping missed notification",
+				"event_id", timerEvent.EventID,
+				"notification_id", timerEvent.NotificationID)
+		}
+	}
+}
+
+// failedCalendars returns the calendars a poll error reports as failed. It
+// returns false if the error does not tell which calendars answered.
+func failedCalendars(pollErr error) (map[string]bool, bool) {
+	failed := make(map[string]bool)
+	if pollErr == nil {
+		return failed, true
+	}
+	var fetchErr interface{ FailedProviders() []string }
+	if !errors.As(pollErr, &fetchErr) {
+		return nil, false
+	}
+	for _, name := range fetchErr.FailedProviders() {
+		failed[name] = true
+	}
+	return failed, true
+}
+
+// cancelRemovedEvents compares the events of a poll with those of the previous
+// one, calendar by calendar, and cancels the notifications of events that are
+// gone, along with events the calendar manager reports as deleted. Events of
+// calendars that failed to fetch are left alone, as are events that ended.
+func (s *EventScheduler) cancelRemovedEvents(events []*models.Event, pollErr error, now time.Time) {
+	failed, known := failedCalendars(pollErr)
+	if !known {
+		// Without knowing which calendars answered, nothing is known to be gone
+		s.logger.Debug("Not checking for removed events after a failed poll", "error", pollErr)
+		return
+	}
+
+	polled := make(map[string]map[string]bool)
+	present := make(map[string]bool)
+	for _, event := range events {
+		if polled[event.CalendarName] == nil {
+			polled[event.CalendarName] = make(map[string]bool)
+		}
+		polled[event.CalendarName][event.ID] = true
+		present[event.ID] = true
+	}
+
+	var removed []string
+	if reporter, ok := s.calendarManager.(RemovalReporter); ok {
+		removed = reporter.TakeRemovedEvents()
+	}
+
+	s.mu.Lock()
+	for name, ids := range s.polledEvents {
+		if failed[name] {
+			// Compare with what was last seen once the calendar is back
+			polled[name] = ids
+			continue
+		}
+		for id := range ids {
+			if !polled[name][id] {
+				removed = append(removed, id)
+			}
+		}
+	}
+	s.polledEvents = polled
+
+	var cancellations []*models.Notification
+	for _, id := range removed {
+		scheduledEvent, exists := s.scheduledEvents[id]
+		if !exists || present[id] || scheduledEvent.Event.HasEnded(now) {
+			continue
+		}
+
+		delivered := false
+		for _, pending := range scheduledEvent.Notifications {
+			if pending.Timer != nil {
+				pending.Timer.Stop()
+			}
+			delivered = delivered || pending.Sent
+		}
+		delete(s.scheduledEvents, id)
+
+		s.logger.Info("Cancelled notifications of removed event",
+			"event_id", id,
+			"title", scheduledEvent.Event.Title,
+			"reminder_delivered", delivered)
+
+		if delivered && s.config.NotifyCancellations {
+			cancellations = append(cancellations, models.NewCancellationNotification(scheduledEvent.Event, now))
+		}
+	}
+	s.saveState()
+	s.mu.Unlock()
+
+	for _, notification := range cancellations {
+		if err := s.publisher.PublishNotification(s.ctx, notification); err != nil {
+			s.logger.Error("Failed to publish cancellation",
+				"error", err,
+				"title", notification.Title)
+			continue
+		}
+		s.logger.Info("Cancellation published", "title", notification.Title)
+	}
 }
 
 // processEvents handles incoming events and schedules notifications
@@ -240,6 +552,13 @@ func (s *EventScheduler) processEvents() {
 			return
 		case event := <-s.eventChan:
 			s.scheduleEventNotifications(event)
+
+			// Save the plan once a poll's events are all scheduled
+			if len(s.eventChan) == 0 {
+				s.mu.Lock()
+				s.saveState()
+				s.mu.Unlock()
+			}
 		}
 	}
 }
@@ -251,8 +570,8 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 
 	now := time.Now()
 
-	// Skip past events
-	if !event.IsUpcoming(now) {
+	// Skip past events; events in progress are kept for end-related alarms
+	if event.HasEnded(now) {
 		s.logger.Debug("Skipping past event", "event_id", event.ID, "title", event.Title)
 		return
 	}
@@ -263,6 +582,14 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 			"event_id", event.ID,
 			"title", event.Title,
 			"response_status", event.ResponseStatus)
+		s.unscheduleEvent(event.ID)
+		return
+	}
+
+	// Skip all-day events if the policy disables them
+	if event.AllDay && s.config.AllDay.Mode == AllDayNever {
+		s.logger.Debug("Skipping all-day event", "event_id", event.ID, "title", event.Title)
+		s.unscheduleEvent(event.ID)
 		return
 	}
 
@@ -283,7 +610,15 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 
 	// Determine which alarms to use
 	alarms := event.Alarms
-	if len(alarms) == 0 && len(s.config.DefaultLeadTimes) > 0 {
+	if event.AllDay {
+		// All-day events follow the policy instead of minute-based lead times
+		notifyAt, _ := s.config.AllDay.NotifyTime(event)
+		alarms = []models.Alarm{{
+			Method:       "popup",
+			Severity:     "normal",
+			AbsoluteTime: &notifyAt,
+		}}
+	} else if len(alarms) == 0 && len(s.config.DefaultLeadTimes) > 0 {
 		// Create default alarms
 		for _, leadTime := range s.config.DefaultLeadTimes {
 			alarm := models.Alarm{
@@ -296,11 +631,11 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 	}
 
 	// Add final reminder if configured and not already present
-	if s.config.FinalReminderMinutes != nil {
+	if s.config.FinalReminderMinutes != nil && !event.AllDay {
 		finalMinutes := *s.config.FinalReminderMinutes
 		hasFinalReminder := false
 		for _, alarm := range alarms {
-			if alarm.LeadTimeMinutes == finalMinutes {
+			if !alarm.IsAbsolute() && !alarm.IsRelatedToEnd() && alarm.LeadTimeMinutes == finalMinutes {
 				hasFinalReminder = true
 				break
 			}
@@ -322,6 +657,7 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 	// Skip events with no alarms
 	if len(alarms) == 0 {
 		s.logger.Debug("Skipping event with no alarms", "event_id", event.ID, "title", event.Title)
+		s.unscheduleEvent(event.ID)
 		return
 	}
 
@@ -336,38 +672,35 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 	// Schedule new notifications
 	for i, alarm := range alarms {
 		notification := models.NewNotification(event, &alarm)
-		triggerTime := event.StartTime.Add(-time.Duration(alarm.LeadTimeMinutes) * time.Minute)
+		triggerTime := alarm.TriggerTime(event)
+
+		// Keep track of reminders already delivered
+		if _, sent := s.delivered[reminderKey(event.ID, triggerTime)]; sent {
+			scheduledEvent.Notifications = append(scheduledEvent.Notifications, &PendingNotification{
+				Notification: notification,
+				TriggerTime:  triggerTime,
+				Sent:         true,
+			})
+			continue
+		}
 
 		// Skip notifications that should have already been sent
 		if triggerTime.Before(now) || triggerTime.Equal(now) {
 			s.logger.Debug("Skipping past notification",
 				"event_id", event.ID,
 				"trigger_time", triggerTime.Format(time.RFC3339),
-				"lead_time", alarm.LeadTimeMinutes)
+				"lead_time", notification.Lead)
 			continue
 		}
 
 		// Create timer for notification
-		duration := triggerTime.Sub(now)
 		notificationID := fmt.Sprintf("%s-%d", event.ID, i)
-
-		timer := time.AfterFunc(duration, func() {
-			timerEvent := &TimerEvent{
-				EventID:        event.ID,
-				NotificationID: notificationID,
-				Notification:   notification,
-			}
-
-			select {
-			case s.timerChan <- timerEvent:
-			case <-s.ctx.Done():
-			case <-s.shutdownChan:
-			default:
-				s.logger.Warn("Timer channel full, dropping notification",
-					"event_id", event.ID,
-					"notification_id", notificationID)
-			}
-		})
+		timer := s.startTimer(&TimerEvent{
+			EventID:        event.ID,
+			NotificationID: notificationID,
+			Notification:   notification,
+			TriggerTime:    triggerTime,
+		}, triggerTime.Sub(now))
 
 		pending := &PendingNotification{
 			Notification: notification,
@@ -383,8 +716,46 @@ func (s *EventScheduler) scheduleEventNotifications(event *models.Event) {
 			"notification_id", notificationID,
 			"title", event.Title,
 			"trigger_time", triggerTime.Format(time.RFC3339),
-			"lead_time", alarm.LeadTimeMinutes)
+			"lead_time", notification.Lead,
+			"related", notification.Related)
+	}
+}
+
+// unscheduleEvent stops the reminders of an event planned by an earlier poll
+// that no longer gets any, e.g. after the user declined it. The caller must
+// hold s.mu.
+func (s *EventScheduler) unscheduleEvent(eventID string) {
+	scheduledEvent, exists := s.scheduledEvents[eventID]
+	if !exists {
+		return
+	}
+
+	for _, pending := range scheduledEvent.Notifications {
+		if pending.Timer != nil {
+			pending.Timer.Stop()
+		}
 	}
+	delete(s.scheduledEvents, eventID)
+	s.saveState()
+
+	s.logger.Info("Cancelled notifications of event no longer notified",
+		"event_id", eventID,
+		"title", scheduledEvent.Event.Title)
+}
+
+// startTimer queues timerEvent for processTimers once duration has passed
+func (s *EventScheduler) startTimer(timerEvent *TimerEvent, duration time.Duration) *time.Timer {
+	return time.AfterFunc(duration, func() {
+		select {
+		case s.timerChan <- timerEvent:
+		case <-s.ctx.Done():
+		case <-s.shutdownChan:
+		default:
+			s.logger.Warn("Timer channel full, dropping notification",
+				"event_id", timerEvent.EventID,
+				"notification_id", timerEvent.NotificationID)
+		}
+	})
 }
 
 // processTimers handles timer events and publishes notifications
@@ -410,6 +781,23 @@ func (s *EventScheduler) handleTimerEvent(timerEvent *TimerEvent) {
 		"notification_id", timerEvent.NotificationID,
 		"title", timerEvent.Notification.Title)
 
+	// Record the reminder as delivered before publishing it: after a crash in
+	// between it is lost rather than sent twice
+	key := reminderKey(timerEvent.EventID, timerEvent.TriggerTime)
+	s.mu.Lock()
+	if _, sent := s.delivered[key]; sent {
+		s.mu.Unlock()
+		s.logger.Info("Skipping notification already delivered",
+			"event_id", timerEvent.EventID,
+			"notification_id", timerEvent.NotificationID)
+		return
+	}
+	s.delivered[key] = timerEvent.TriggerTime
+	s.markSent(timerEvent, true)
+	s.saveState()
+	s.mu.Unlock()
+	s.writeState()
+
 	// Publish notification
 	err := s.publisher.PublishNotification(s.ctx, timerEvent.Notification)
 	if err != nil {
@@ -417,20 +805,15 @@ func (s *EventScheduler) handleTimerEvent(timerEvent *TimerEvent) {
 			"error", err,
 			"event_id", timerEvent.EventID,
 			"title", timerEvent.Notification.Title)
-		return
-	}
 
-	// Mark notification as sent
-	s.mu.Lock()
-	if scheduledEvent, exists := s.scheduledEvents[timerEvent.EventID]; exists {
-		for _, pending := range scheduledEvent.Notifications {
-			if pending.Notification == timerEvent.Notification {
-				pending.Sent = true
-				break
-			}
-		}
+		s.mu.Lock()
+		delete(s.delivered, key)
+		s.markSent(timerEvent, false)
+		s.saveState()
+		s.mu.Unlock()
+		s.writeState()
+		return
 	}
-	s.mu.Unlock()
 
 	s.logger.Info("Notification published successfully",
 		"event_id", timerEvent.EventID,
@@ -438,6 +821,20 @@ func (s *EventScheduler) handleTimerEvent(timerEvent *TimerEvent) {
 		"lead_time", timerEvent.Notification.Lead)
 }
 
+// markSent sets whether the notification of timerEvent was sent. The caller
+// must hold s.mu.
+func (s *EventScheduler) markSent(timerEvent *TimerEvent, sent bool) {
+	scheduledEvent, exists := s.scheduledEvents[timerEvent.EventID]
+	if !exists {
+		return
+	}
+	for _, pending := range scheduledEvent.Notifications {
+		if pending.TriggerTime.Equal(timerEvent.TriggerTime) {
+			pending.Sent = sent
+		}
+	}
+}
+
 // GetScheduledEvents returns a copy of currently scheduled events
 func (s *EventScheduler) GetScheduledEvents() map[string]*ScheduledEvent {
 	s.mu.RLock()
@@ -519,6 +916,14 @@ func (s *EventScheduler) CleanupOldEvents() {
 		s.logger.Debug("Cleaned up old event", "event_id", eventID)
 	}
 
+	// Forget delivered reminders once they can no longer come due again
+	for key, triggerTime := range s.delivered {
+		if triggerTime.Before(cutoff) {
+			delete(s.delivered, key)
+		}
+	}
+	s.saveState()
+
 	if len(toDelete) > 0 {
 		s.logger.Info("Cleaned up old events", "count", len(toDelete))
 	}
@@ -2,8 +2,10 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,15 +14,19 @@ import (
 
 // MockCalendarManager is a mock implementation for testing
 type MockCalendarManager struct {
-	events []*models.Event
-	err    error
+	events  []*models.Event
+	err     error
+	removed []string
 }
 
 func (m *MockCalendarManager) GetAllEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
-	if m.err != nil {
-		return nil, m.err
-	}
-	return m.events, nil
+	return m.events, m.err
+}
+
+func (m *MockCalendarManager) TakeRemovedEvents() []string {
+	removed := m.removed
+	m.removed = nil
+	return removed
 }
 
 func (m *MockCalendarManager) Close() error {
@@ -141,6 +147,135 @@ func TestScheduleEventNotifications(t *testing.T) {
 	}
 }
 
+func TestScheduleEndRelatedAlarmForEventInProgress(t *testing.T) {
+	scheduler := NewEventScheduler(nil, &MockCalendarManager{}, &MockPublisher{}, slog.Default())
+
+	// Event started 30 minutes ago and ends in 30 minutes
+	now := time.Now()
+	absolute := now.Add(10 * time.Minute)
+	event := &models.Event{
+		ID:        "in-progress",
+		Title:     "Workshop",
+		StartTime: now.Add(-30 * time.Minute),
+		EndTime:   now.Add(30 * time.Minute),
+		Alarms: []models.Alarm{
+			{LeadTimeMinutes: 15, Method: "popup", Severity: "normal"},                                   // already past
+			{LeadTimeMinutes: 5, RelatedTo: models.AlarmRelatedEnd, Method: "popup", Severity: "normal"}, // ends in 5 minutes
+			{AbsoluteTime: &absolute, Method: "popup", Severity: "normal"},
+		},
+	}
+
+	scheduler.scheduleEventNotifications(event)
+
+	scheduledEvent := scheduler.GetScheduledEvents()["in-progress"]
+	if scheduledEvent == nil {
+		t.Fatal("Expected in-progress event to be scheduled")
+	}
+	if len(scheduledEvent.Notifications) != 2 {
+		t.Fatalf("Expected 2 notifications, got %d", len(scheduledEvent.Notifications))
+	}
+
+	endAlarm := scheduledEvent.Notifications[0]
+	if !endAlarm.TriggerTime.Equal(event.EndTime.Add(-5 * time.Minute)) {
+		t.Errorf("Expected end alarm 5 minutes before the end, got %v", endAlarm.TriggerTime)
+	}
+	if endAlarm.Notification.Related != "end" || !endAlarm.Notification.When.Equal(event.EndTime) {
+		t.Errorf("Expected end-related notification, got %+v", endAlarm.Notification)
+	}
+
+	if !scheduledEvent.Notifications[1].TriggerTime.Equal(absolute) {
+		t.Errorf("Expected absolute trigger at %v, got %v", absolute, scheduledEvent.Notifications[1].TriggerTime)
+	}
+}
+
+func TestAllDayPolicyNotifyTime(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	event := &models.Event{
+		AllDay:    true,
+		StartTime: time.Date(2024, 3, 1, 0, 0, 0, 0, loc),
+		EndTime:   time.Date(2024, 3, 2, 0, 0, 0, 0, loc),
+	}
+
+	tests := []struct {
+		name     string
+		policy   AllDayPolicy
+		expected time.Time
+		ok       bool
+	}{
+		{
+			name:     "same day",
+			policy:   AllDayPolicy{Mode: AllDaySameDay, TimeOfDay: 8*time.Hour + 30*time.Minute, Location: loc},
+			expected: time.Date(2024, 3, 1, 8, 30, 0, 0, loc),
+			ok:       true,
+		},
+		{
+			name:     "evening before",
+			policy:   AllDayPolicy{Mode: AllDayDayBefore, TimeOfDay: 18 * time.Hour, Location: loc},
+			expected: time.Date(2024, 2, 29, 18, 0, 0, 0, loc),
+			ok:       true,
+		},
+		{
+			name:   "never",
+			policy: AllDayPolicy{Mode: AllDayNever, TimeOfDay: 9 * time.Hour, Location: loc},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := tt.policy.NotifyTime(event)
+			if ok != tt.ok {
+				t.Fatalf("NotifyTime() ok = %v, expected %v", ok, tt.ok)
+			}
+			if ok && !result.Equal(tt.expected) {
+				t.Errorf("NotifyTime() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScheduleAllDayEventUsesPolicy(t *testing.T) {
+	config := DefaultConfig()
+	config.AllDay = AllDayPolicy{Mode: AllDaySameDay, TimeOfDay: 9 * time.Hour, Location: time.UTC}
+	scheduler := NewEventScheduler(config, &MockCalendarManager{}, &MockPublisher{}, slog.Default())
+
+	// Two days ahead so the 09:00 reminder is always in the future
+	year, month, day := time.Now().UTC().AddDate(0, 0, 2).Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	event := &models.Event{
+		ID:        "holiday",
+		Title:     "Public Holiday",
+		AllDay:    true,
+		StartTime: start,
+		EndTime:   start.AddDate(0, 0, 1),
+		Alarms:    []models.Alarm{{LeadTimeMinutes: 15, Method: "popup", Severity: "normal"}},
+	}
+
+	scheduler.scheduleEventNotifications(event)
+
+	scheduledEvent := scheduler.GetScheduledEvents()["holiday"]
+	if scheduledEvent == nil {
+		t.Fatal("Expected all-day event to be scheduled")
+	}
+	if len(scheduledEvent.Notifications) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(scheduledEvent.Notifications))
+	}
+	expected := start.Add(9 * time.Hour)
+	if !scheduledEvent.Notifications[0].TriggerTime.Equal(expected) {
+		t.Errorf("Expected trigger at %v, got %v", expected, scheduledEvent.Notifications[0].TriggerTime)
+	}
+	if !scheduledEvent.Notifications[0].Notification.AllDay {
+		t.Error("Expected notification to be marked all-day")
+	}
+
+	// With the policy disabled the event is not scheduled at all
+	config.AllDay.Mode = AllDayNever
+	never := NewEventScheduler(config, &MockCalendarManager{}, &MockPublisher{}, slog.Default())
+	never.scheduleEventNotifications(event)
+	if len(never.GetScheduledEvents()) != 0 {
+		t.Error("Expected all-day event to be skipped when the policy is never")
+	}
+}
+
 func TestScheduleEventWithoutAlarms(t *testing.T) {
 	config := &Config{
 		PollInterval:        1 * time.Minute,
@@ -399,4 +534,276 @@ func TestSchedulerStartStop(t *testing.T) {
 	if stats.IsRunning {
 		t.Error("Expected scheduler to be stopped after stop")
 	}
-}
\ No newline at end of file
+}
+// countingCalendarManager counts polls
+type countingCalendarManager struct {
+	polls chan struct{}
+}
+
+func (m *countingCalendarManager) GetAllEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
+	m.polls <- struct{}{}
+	return nil, nil
+}
+
+func (m *countingCalendarManager) Close() error {
+	return nil
+}
+
+func TestSchedulerRefresh(t *testing.T) {
+	manager := &countingCalendarManager{polls: make(chan struct{}, 10)}
+	config := &Config{
+		PollInterval:        time.Hour,
+		LookaheadWindow:     time.Hour,
+		DefaultLeadTimes:    []int{5},
+		MaxConcurrentEvents: 10,
+		TimerBufferSize:     5,
+	}
+
+	scheduler := NewEventScheduler(config, manager, &MockPublisher{}, slog.Default())
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Stop()
+
+	waitForPoll := func(what string) {
+		t.Helper()
+		select {
+		case <-manager.polls:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected %s", what)
+		}
+	}
+
+	waitForPoll("the initial poll")
+
+	// Requests are merged while one is pending
+	scheduler.Refresh()
+	scheduler.Refresh()
+	scheduler.Refresh()
+	waitForPoll("a poll on refresh")
+
+	select {
+	case <-manager.polls:
+		select {
+		case <-manager.polls:
+			t.Error("Expected pending refresh requests to be merged")
+		case <-time.After(100 * time.Millisecond):
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPerformEventPollPartialFailure(t *testing.T) {
+	now := time.Now()
+	event := &models.Event{ID: "work-standup", Title: "Standup", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}
+
+	tests := []struct {
+		name     string
+		manager  *MockCalendarManager
+		expected int
+	}{
+		{"Partial failure keeps the fetched events", &MockCalendarManager{events: []*models.Event{event}, err: errors.New("holidays: feed unavailable")}, 1},
+		{"Total failure schedules nothing", &MockCalendarManager{err: errors.New("coordination failed")}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewEventScheduler(nil, tt.manager, &MockPublisher{}, slog.Default())
+			scheduler.performEventPoll()
+
+			if queued := len(scheduler.eventChan); queued != tt.expected {
+				t.Errorf("Expected %d queued events, got %d", tt.expected, queued)
+			}
+		})
+	}
+}
+
+// cachingCalendarManager is an EventCache whose calendars change when events
+// are sent on updates
+type cachingCalendarManager struct {
+	mu      sync.Mutex
+	events  []*models.Event
+	updates chan []*models.Event
+}
+
+func (m *cachingCalendarManager) GetAllEvents(ctx context.Context, from, to time.Time) ([]*models.Event, error) {
+	return nil, errors.New("events should come from the cache")
+}
+
+func (m *cachingCalendarManager) Run(ctx context.Context, lookahead time.Duration, changed func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case events := <-m.updates:
+			m.mu.Lock()
+			m.events = events
+			m.mu.Unlock()
+			changed()
+		}
+	}
+}
+
+func (m *cachingCalendarManager) CachedEvents(from, to time.Time) ([]*models.Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.events, nil
+}
+
+func (m *cachingCalendarManager) Close() error {
+	return nil
+}
+
+func TestSchedulerPlansFromEventCache(t *testing.T) {
+	manager := &cachingCalendarManager{updates: make(chan []*models.Event)}
+	config := &Config{
+		PollInterval:        time.Hour,
+		LookaheadWindow:     24 * time.Hour,
+		DefaultLeadTimes:    []int{5},
+		MaxConcurrentEvents: 10,
+		TimerBufferSize:     5,
+	}
+
+	scheduler := NewEventScheduler(config, manager, &MockPublisher{}, slog.Default())
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Failed to start scheduler: %v", err)
+	}
+	defer scheduler.Stop()
+
+	// A calendar refreshed long before the next poll is planned right away
+	now := time.Now()
+	manager.updates <- []*models.Event{{ID: "standup", Title: "Standup", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}}
+
+	deadline := time.After(2 * time.Second)
+	for len(scheduler.GetScheduledEvents()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("Expected the changed calendar to be planned")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if _, ok := scheduler.GetScheduledEvents()["standup"]; !ok {
+		t.Errorf("Expected the cached event to be scheduled, got %v", scheduler.GetScheduledEvents())
+	}
+}
+
+// partialFetchError reports failed calendars like calendar.FetchError does
+type partialFetchError struct {
+	failed []string
+}
+
+func (e *partialFetchError) Error() string {
+	return fmt.Sprintf("calendars failed: %v", e.failed)
+}
+
+func (e *partialFetchError) FailedProviders() []string {
+	return e.failed
+}
+
+// pollAndSchedule polls once and schedules the queued events
+func pollAndSchedule(s *EventScheduler) {
+	s.performEventPoll()
+	for len(s.eventChan) > 0 {
+		s.scheduleEventNotifications(<-s.eventChan)
+	}
+}
+
+func TestPollCancelsRemovedEvents(t *testing.T) {
+	now := time.Now()
+	event := func(id, calendar string, start time.Duration) *models.Event {
+		return &models.Event{ID: id, Title: id, CalendarName: calendar, StartTime: now.Add(start), EndTime: now.Add(start + time.Hour)}
+	}
+	review := event("review", "work", 30*time.Minute)
+	standup := event("standup", "work", time.Hour)
+	planning := event("planning", "work", 2*time.Hour)
+	holiday := event("holiday", "holidays", 3*time.Hour)
+
+	manager := &MockCalendarManager{events: []*models.Event{review, standup, planning, holiday}}
+	publisher := &MockPublisher{}
+	config := DefaultConfig()
+	config.DefaultLeadTimes = []int{15, 1}
+	config.NotifyCancellations = true
+
+	scheduler := NewEventScheduler(config, manager, publisher, slog.Default())
+	pollAndSchedule(scheduler)
+	if len(scheduler.GetScheduledEvents()) != 4 {
+		t.Fatalf("Expected 4 scheduled events, got %d", len(scheduler.GetScheduledEvents()))
+	}
+
+	// The first reminder of the review already went out
+	reviewNotifications := scheduler.GetScheduledEvents()["review"].Notifications
+	reviewNotifications[0].Sent = true
+
+	// The review is deleted and the holiday feed fails, taking its event along
+	manager.events = []*models.Event{standup, planning}
+	manager.err = &partialFetchError{failed: []string{"holidays"}}
+	pollAndSchedule(scheduler)
+
+	scheduled := scheduler.GetScheduledEvents()
+	if _, ok := scheduled["review"]; ok {
+		t.Error("Expected the deleted review to be unscheduled")
+	}
+	if _, ok := scheduled["holiday"]; !ok {
+		t.Error("Expected the event of the failed calendar to stay scheduled")
+	}
+	if reviewNotifications[1].Timer.Stop() {
+		t.Error("Expected the pending review reminder to be stopped")
+	}
+	if len(publisher.published) != 1 || !publisher.published[0].Cancelled || publisher.published[0].Title != "review" {
+		t.Fatalf("Expected one cancellation for the review, got %+v", publisher.published)
+	}
+
+	// Deletions reported by the calendars are cancelled too, without a
+	// notification when no reminder went out
+	manager.err = nil
+	manager.removed = []string{"planning", "standup"}
+	manager.events = []*models.Event{standup, holiday}
+	pollAndSchedule(scheduler)
+
+	scheduled = scheduler.GetScheduledEvents()
+	if _, ok := scheduled["planning"]; ok {
+		t.Error("Expected the reported deletion to be unscheduled")
+	}
+	if _, ok := scheduled["standup"]; !ok {
+		t.Error("Expected an event still returned by the calendar to stay scheduled")
+	}
+	if len(publisher.published) != 1 {
+		t.Errorf("Expected no cancellation without a delivered reminder, got %+v", publisher.published)
+	}
+
+	// After a failure that does not say which calendars answered, nothing is cancelled
+	manager.events = []*models.Event{}
+	manager.err = errors.New("coordination trouble")
+	pollAndSchedule(scheduler)
+	if len(scheduler.GetScheduledEvents()) != 2 {
+		t.Errorf("Expected no events to be cancelled, got %v", scheduler.GetScheduledEvents())
+	}
+}
+
+func TestPollCancelsDeclinedEvents(t *testing.T) {
+	now := time.Now()
+	review := &models.Event{ID: "review", Title: "review", CalendarName: "work", StartTime: now.Add(time.Minute + 100*time.Millisecond), EndTime: now.Add(time.Hour), ResponseStatus: "accepted"}
+	manager := &MockCalendarManager{events: []*models.Event{review}}
+	config := DefaultConfig()
+	config.DefaultLeadTimes = []int{1}
+
+	scheduler := NewEventScheduler(config, manager, &MockPublisher{}, slog.Default())
+	pollAndSchedule(scheduler)
+	if _, ok := scheduler.GetScheduledEvents()["review"]; !ok {
+		t.Fatal("Expected the accepted review to be scheduled")
+	}
+
+	// The user declines the review before its reminder is due
+	declined := *review
+	declined.ResponseStatus = "declined"
+	manager.events = []*models.Event{&declined}
+	pollAndSchedule(scheduler)
+
+	if _, ok := scheduler.GetScheduledEvents()["review"]; ok {
+		t.Error("Expected the declined review to be unscheduled")
+	}
+	time.Sleep(300 * time.Millisecond)
+	if len(scheduler.timerChan) != 0 {
+		t.Errorf("Expected no reminder for the declined review, got %d", len(scheduler.timerChan))
+	}
+}
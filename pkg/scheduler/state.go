@@ -0,0 +1,250 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// StateStore persists the scheduler state across restarts
+type StateStore interface {
+	// Load returns the saved state, or an empty state if none was saved
+	Load() (*State, error)
+
+	// Save replaces the saved state
+	Save(state *State) error
+}
+
+// State is the part of the scheduler state that survives restarts
+type State struct {
+	Events    []*EventState        `json:"events"`    // Scheduled events with their reminders
+	Delivered map[string]time.Time `json:"delivered"` // Trigger times of delivered reminders by reminder key
+}
+
+// EventState is a scheduled event with its reminders
+type EventState struct {
+	Event         *models.Event       `json:"event"`
+	Notifications []NotificationState `json:"notifications"`
+}
+
+// NotificationState is a reminder of a scheduled event
+type NotificationState struct {
+	Notification *models.Notification `json:"notification"`
+	TriggerTime  time.Time            `json:"trigger_time"`
+}
+
+// reminderKey identifies a reminder across plans and restarts
+func reminderKey(eventID string, triggerTime time.Time) string {
+	return eventID + "@" + triggerTime.UTC().Format(time.RFC3339Nano)
+}
+
+// saveState takes a snapshot of the scheduled events and delivered reminders
+// for the saver to write, if a store is set. Snapshots taken before the saver
+// gets to them are merged into the latest. The caller must hold s.mu.
+func (s *EventScheduler) saveState() {
+	if s.store == nil {
+		return
+	}
+
+	state := &State{Delivered: maps.Clone(s.delivered)}
+	for _, id := range slices.Sorted(maps.Keys(s.scheduledEvents)) {
+		scheduledEvent := s.scheduledEvents[id]
+		eventState := &EventState{Event: scheduledEvent.Event}
+		for _, pending := range scheduledEvent.Notifications {
+			eventState.Notifications = append(eventState.Notifications, NotificationState{
+				Notification: pending.Notification,
+				TriggerTime:  pending.TriggerTime,
+			})
+		}
+		state.Events = append(state.Events, eventState)
+	}
+
+	s.unsavedMu.Lock()
+	s.unsaved = state
+	s.unsavedMu.Unlock()
+
+	select {
+	case s.saveChan <- struct{}{}:
+	default:
+	}
+}
+
+// writeState writes the latest snapshot to the store, if one is unsaved. On
+// return every snapshot taken before the call is on disk. The caller must not
+// hold s.mu.
+func (s *EventScheduler) writeState() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.unsavedMu.Lock()
+	state := s.unsaved
+	s.unsaved = nil
+	s.unsavedMu.Unlock()
+
+	if state == nil {
+		return
+	}
+	if err := s.store.Save(state); err != nil {
+		s.logger.Error("Failed to save scheduler state", "error", err)
+	}
+}
+
+// saveStates writes the snapshots taken by saveState until the scheduler stops
+func (s *EventScheduler) saveStates() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.shutdownChan:
+			return
+		case <-s.saveChan:
+			s.writeState()
+		}
+	}
+}
+
+// restoreState loads the saved state, if a store is set, and schedules its
+// reminders again. It returns the reminders that came due while the scheduler
+// was down, within MissedReminderWindow, to be sent right away. The caller
+// must hold s.mu.
+func (s *EventScheduler) restoreState(now time.Time) []*TimerEvent {
+	if s.store == nil {
+		return nil
+	}
+
+	state, err := s.store.Load()
+	if err != nil {
+		s.logger.Error("Failed to load scheduler state, starting afresh", "error", err)
+		return nil
+	}
+
+	for key, triggerTime := range state.Delivered {
+		s.delivered[key] = triggerTime
+	}
+
+	var missed []*TimerEvent
+	for _, eventState := range state.Events {
+		event := eventState.Event
+		if event == nil || event.HasEnded(now) {
+			continue
+		}
+
+		scheduledEvent := &ScheduledEvent{Event: event, LastUpdated: now}
+		for i, notificationState := range eventState.Notifications {
+			timerEvent := &TimerEvent{
+				EventID:        event.ID,
+				NotificationID: fmt.Sprintf("%s-%d", event.ID, i),
+				Notification:   notificationState.Notification,
+				TriggerTime:    notificationState.TriggerTime,
+			}
+			pending := &PendingNotification{
+				Notification: notificationState.Notification,
+				TriggerTime:  notificationState.TriggerTime,
+			}
+
+			_, pending.Sent = s.delivered[reminderKey(event.ID, pending.TriggerTime)]
+			switch {
+			case pending.Sent:
+			case pending.TriggerTime.After(now):
+				pending.Timer = s.startTimer(timerEvent, pending.TriggerTime.Sub(now))
+			case now.Sub(pending.TriggerTime) <= s.config.MissedReminderWindow:
+				missed = append(missed, timerEvent)
+			default:
+				s.logger.Info("Dropping notification missed while stopped",
+					"event_id", event.ID,
+					"title", event.Title,
+					"trigger_time", pending.TriggerTime.Format(time.RFC3339))
+				continue
+			}
+			scheduledEvent.Notifications = append(scheduledEvent.Notifications, pending)
+		}
+		s.scheduledEvents[event.ID] = scheduledEvent
+
+		// Events gone by the first poll were removed while the scheduler was down
+		if s.polledEvents[event.CalendarName] == nil {
+			s.polledEvents[event.CalendarName] = make(map[string]bool)
+		}
+		s.polledEvents[event.CalendarName][event.ID] = true
+	}
+
+	s.logger.Info("Restored scheduler state",
+		"events", len(s.scheduledEvents),
+		"delivered", len(s.delivered),
+		"missed", len(missed))
+	return missed
+}
+
+// FileStateStore keeps the scheduler state in a JSON file
+type FileStateStore struct {
+	path string
+}
+
+// NewFileStateStore creates a state store writing to path
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// Load reads the state file. A missing file is an empty state.
+func (f *FileStateStore) Load() (*State, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", f.path, err)
+	}
+	return &state, nil
+}
+
+// Save writes the state file atomically, readable by the owner only
+func (f *FileStateStore) Save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	// The state must be on disk before a reminder it records is sent
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	return nil
+}
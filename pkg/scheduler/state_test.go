@@ -0,0 +1,344 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/venkytv/calendar-notifier/internal/models"
+)
+
+// memoryStateStore keeps the saved state in memory and counts the saves
+type memoryStateStore struct {
+	mu    sync.Mutex
+	state *State
+	saves int
+}
+
+func (m *memoryStateStore) Load() (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == nil {
+		return &State{}, nil
+	}
+	return m.state, nil
+}
+
+func (m *memoryStateStore) Save(state *State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	m.saves++
+	return nil
+}
+
+func (m *memoryStateStore) saved() *State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// channelPublisher hands published notifications to the test
+type channelPublisher struct {
+	published chan *models.Notification
+}
+
+func (c *channelPublisher) PublishNotification(ctx context.Context, notification *models.Notification) error {
+	c.published <- notification
+	return nil
+}
+
+func (c *channelPublisher) Close() error {
+	return nil
+}
+
+func TestFileStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "scheduler.json")
+	store := NewFileStateStore(path)
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(state.Events) != 0 || len(state.Delivered) != 0 {
+		t.Errorf("Expected an empty state without a state file, got %+v", state)
+	}
+
+	start := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	event := &models.Event{ID: "standup", Title: "Standup", StartTime: start, EndTime: start.Add(time.Hour)}
+	trigger := start.Add(-15 * time.Minute)
+	saved := &State{
+		Events: []*EventState{{
+			Event: event,
+			Notifications: []NotificationState{{
+				Notification: models.NewNotification(event, &models.Alarm{LeadTimeMinutes: 15}),
+				TriggerTime:  trigger,
+			}},
+		}},
+		Delivered: map[string]time.Time{reminderKey("standup", trigger): trigger},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Expected state file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected state file mode 0600, got %v", info.Mode().Perm())
+	}
+
+	state, err = store.Load()
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(state.Events) != 1 || state.Events[0].Event.Title != "Standup" || !state.Events[0].Notifications[0].TriggerTime.Equal(trigger) {
+		t.Errorf("Unexpected events after reload %+v", state.Events)
+	}
+	if _, ok := state.Delivered[reminderKey("standup", trigger)]; !ok {
+		t.Errorf("Expected the delivered reminder after reload, got %v", state.Delivered)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load(); err == nil {
+		t.Error("Expected error for a corrupt state file")
+	}
+}
+
+func TestRestoreState(t *testing.T) {
+	now := time.Now()
+	event := &models.Event{ID: "review", Title: "Review", CalendarName: "work", StartTime: now.Add(10 * time.Minute), EndTime: now.Add(time.Hour)}
+	ended := &models.Event{ID: "lunch", Title: "Lunch", CalendarName: "work", StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour)}
+
+	reminder := func(event *models.Event, trigger time.Time) NotificationState {
+		return NotificationState{
+			Notification: models.NewNotification(event, &models.Alarm{LeadTimeMinutes: int(event.StartTime.Sub(trigger).Minutes())}),
+			TriggerTime:  trigger,
+		}
+	}
+	delivered := now.Add(-time.Hour)
+	tooOld := now.Add(-30 * time.Minute)
+	missed := now.Add(-5 * time.Minute)
+	future := now.Add(5 * time.Minute)
+
+	store := &memoryStateStore{state: &State{
+		Events: []*EventState{
+			{Event: event, Notifications: []NotificationState{
+				reminder(event, delivered), reminder(event, tooOld), reminder(event, missed), reminder(event, future),
+			}},
+			{Event: ended, Notifications: []NotificationState{reminder(ended, now.Add(-time.Minute))}},
+		},
+		Delivered: map[string]time.Time{reminderKey("review", delivered): delivered},
+	}}
+
+	scheduler := NewEventScheduler(nil, &MockCalendarManager{}, &MockPublisher{}, slog.Default())
+	scheduler.SetStateStore(store)
+	due := scheduler.restoreState(now)
+
+	if len(due) != 1 || !due[0].TriggerTime.Equal(missed) {
+		t.Fatalf("Expected only the recently missed reminder to be due, got %+v", due)
+	}
+
+	scheduled := scheduler.GetScheduledEvents()
+	if _, ok := scheduled["lunch"]; ok {
+		t.Error("Expected the ended event not to be restored")
+	}
+	notifications := scheduled["review"].Notifications
+	if len(notifications) != 3 {
+		t.Fatalf("Expected the too old reminder to be dropped, got %d reminders", len(notifications))
+	}
+	if !notifications[0].Sent || notifications[0].Timer != nil {
+		t.Errorf("Expected the delivered reminder to stay sent, got %+v", notifications[0])
+	}
+	if notifications[2].Timer == nil || !notifications[2].Timer.Stop() {
+		t.Errorf("Expected a timer for the future reminder, got %+v", notifications[2])
+	}
+	if !scheduler.polledEvents["work"]["review"] {
+		t.Error("Expected the restored event to count as polled")
+	}
+}
+
+func TestSchedulerSendsMissedRemindersOnStart(t *testing.T) {
+	now := time.Now()
+	event := &models.Event{ID: "review", Title: "Review", CalendarName: "work", StartTime: now.Add(10 * time.Minute), EndTime: now.Add(time.Hour)}
+	missed := now.Add(-5 * time.Minute)
+	store := &memoryStateStore{state: &State{Events: []*EventState{{
+		Event: event,
+		Notifications: []NotificationState{{
+			Notification: models.NewNotification(event, &models.Alarm{LeadTimeMinutes: 15}),
+			TriggerTime:  missed,
+		}},
+	}}}}
+
+	config := DefaultConfig()
+	config.DefaultLeadTimes = []int{15}
+	publisher := &channelPublisher{published: make(chan *models.Notification, 10)}
+	scheduler := NewEventScheduler(config, &MockCalendarManager{events: []*models.Event{event}}, publisher, slog.Default())
+	scheduler.SetStateStore(store)
+
+	if err := scheduler.Start(); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	select {
+	case notification := <-publisher.published:
+		if notification.Title != "Review" {
+			t.Errorf("Unexpected notification %+v", notification)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the missed reminder to be sent on start")
+	}
+	scheduler.Stop()
+
+	// The reminder is recorded as delivered and not sent again after the
+	// next restart
+	if _, ok := store.saved().Delivered[reminderKey("review", missed)]; !ok {
+		t.Fatalf("Expected the sent reminder to be saved as delivered, got %v", store.saved().Delivered)
+	}
+	restarted := NewEventScheduler(config, &MockCalendarManager{events: []*models.Event{event}}, publisher, slog.Default())
+	restarted.SetStateStore(store)
+	if err := restarted.Start(); err != nil {
+		t.Fatalf("Start() unexpected error: %v", err)
+	}
+	select {
+	case notification := <-publisher.published:
+		t.Errorf("Expected no reminder after the restart, got %+v", notification)
+	case <-time.After(100 * time.Millisecond):
+	}
+	restarted.Stop()
+}
+
+func TestSchedulerDropsMissedRemindersOfChangedEvents(t *testing.T) {
+	now := time.Now()
+	event := &models.Event{ID: "review", Title: "Review", CalendarName: "work", StartTime: now.Add(10 * time.Minute), EndTime: now.Add(time.Hour)}
+	moved := *event
+	moved.StartTime = now.Add(2 * time.Hour)
+	moved.EndTime = now.Add(3 * time.Hour)
+	declined := *event
+	declined.ResponseStatus = "declined"
+
+	tests := []struct {
+		name     string
+		polled   []*models.Event
+		err      error
+		expected int // Reminders sent
+		held     int // Reminders waiting for their calendar to answer
+		window   time.Duration
+	}{
+		{name: "unchanged", polled: []*models.Event{event}, expected: 1},
+		{name: "removed", polled: nil, expected: 0},
+		{name: "moved", polled: []*models.Event{&moved}, expected: 0},
+		{name: "declined", polled: []*models.Event{&declined}, expected: 0},
+		{name: "poll failed", err: errors.New("connection refused"), expected: 0, held: 1},
+		{name: "calendar not fetched yet", polled: []*models.Event{}, err: &partialFetchError{failed: []string{"work"}}, expected: 0, held: 1},
+		{name: "calendar failing past the window", polled: []*models.Event{}, err: &partialFetchError{failed: []string{"work"}}, expected: 0, held: 0, window: time.Minute},
+		{name: "poll failed past the window", err: errors.New("connection refused"), expected: 0, held: 0, window: time.Minute},
+		{name: "other calendar failed", polled: []*models.Event{event}, err: &partialFetchError{failed: []string{"holidays"}}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStateStore{state: &State{Events: []*EventState{{
+				Event: event,
+				Notifications: []NotificationState{{
+					Notification: models.NewNotification(event, &models.Alarm{LeadTimeMinutes: 15}),
+					TriggerTime:  now.Add(-5 * time.Minute),
+				}},
+			}}}}
+			scheduler := NewEventScheduler(nil, &MockCalendarManager{events: tt.polled, err: tt.err}, &MockPublisher{}, slog.Default())
+			scheduler.SetStateStore(store)
+
+			scheduler.missed = scheduler.restoreState(now)
+			if tt.window != 0 {
+				scheduler.config.MissedReminderWindow = tt.window
+			}
+			scheduler.sendMissedReminders(scheduler.performEventPoll())
+
+			if len(scheduler.timerChan) != tt.expected {
+				t.Errorf("Expected %d missed reminders to be sent, got %d", tt.expected, len(scheduler.timerChan))
+			}
+			if len(scheduler.missed) != tt.held {
+				t.Errorf("Expected %d missed reminders to be held, got %d", tt.held, len(scheduler.missed))
+			}
+		})
+	}
+}
+
+func TestSaveStateWritesLatestSnapshot(t *testing.T) {
+	now := time.Now()
+	store := &memoryStateStore{}
+	scheduler := NewEventScheduler(nil, &MockCalendarManager{}, &MockPublisher{}, slog.Default())
+	scheduler.SetStateStore(store)
+
+	// Snapshots are taken under the lock but written outside it
+	scheduler.mu.Lock()
+	for i := range 3 {
+		scheduler.delivered[reminderKey("standup", now.Add(time.Duration(i)*time.Minute))] = now
+		scheduler.saveState()
+	}
+	scheduler.mu.Unlock()
+	if store.saved() != nil {
+		t.Fatal("Expected no write while holding the lock")
+	}
+
+	scheduler.writeState()
+	scheduler.writeState()
+	if store.saves != 1 {
+		t.Errorf("Expected the snapshots to be written once, got %d saves", store.saves)
+	}
+	if len(store.saved().Delivered) != 3 {
+		t.Errorf("Expected the latest snapshot to be written, got %v", store.saved().Delivered)
+	}
+}
+
+func TestHandleTimerEventDeliversOnce(t *testing.T) {
+	now := time.Now()
+	event := &models.Event{ID: "standup", Title: "Standup", StartTime: now.Add(time.Hour), EndTime: now.Add(2 * time.Hour)}
+	config := DefaultConfig()
+	config.DefaultLeadTimes = []int{15}
+
+	publisher := &MockPublisher{err: errors.New("nats: no servers available")}
+	store := &memoryStateStore{}
+	scheduler := NewEventScheduler(config, &MockCalendarManager{}, publisher, slog.Default())
+	scheduler.SetStateStore(store)
+	scheduler.scheduleEventNotifications(event)
+
+	pending := scheduler.GetScheduledEvents()["standup"].Notifications[0]
+	pending.Timer.Stop()
+	timerEvent := &TimerEvent{
+		EventID:        "standup",
+		NotificationID: "standup-0",
+		Notification:   pending.Notification,
+		TriggerTime:    pending.TriggerTime,
+	}
+
+	// A reminder that failed to publish may be sent again
+	scheduler.handleTimerEvent(timerEvent)
+	if pending.Sent || len(store.saved().Delivered) != 0 {
+		t.Errorf("Expected the failed reminder not to count as delivered, got %v", store.saved().Delivered)
+	}
+
+	publisher.err = nil
+	scheduler.handleTimerEvent(timerEvent)
+	scheduler.handleTimerEvent(timerEvent)
+	if len(publisher.published) != 1 {
+		t.Errorf("Expected the reminder to be published once, got %d", len(publisher.published))
+	}
+	if !pending.Sent || len(store.saved().Delivered) != 1 {
+		t.Errorf("Expected the reminder to be saved as delivered, got %v", store.saved().Delivered)
+	}
+
+	// Planning the event again keeps the delivered reminder without a timer
+	scheduler.scheduleEventNotifications(event)
+	replanned := scheduler.GetScheduledEvents()["standup"].Notifications[0]
+	if !replanned.Sent || replanned.Timer != nil {
+		t.Errorf("Expected the delivered reminder to stay sent, got %+v", replanned)
+	}
+}